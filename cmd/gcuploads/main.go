@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/UreshiiPanda/kanji_go/internal/cleanup"
+	"github.com/UreshiiPanda/kanji_go/internal/config"
+	"github.com/UreshiiPanda/kanji_go/internal/db"
+	"github.com/UreshiiPanda/kanji_go/internal/storage"
+)
+
+// gcuploads deletes uploaded images that no creation or draft references,
+// the same pass the server runs daily. Use -dry-run to see what would go.
+func main() {
+	grace := flag.Duration("grace", cleanup.OrphanGracePeriod, "only collect uploads older than this")
+	batch := flag.Int("batch", 100, "uploads to check and delete at a time")
+	dryRun := flag.Bool("dry-run", false, "list orphaned uploads without deleting them")
+	flag.Parse()
+
+	if *grace <= 0 || *batch <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load configuration (and .env) for the storage backend
+	cfg := config.Load()
+
+	// Get database connection
+	dbConn, err := db.GetDBConnection()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dbConn.Close()
+
+	store, err := storage.New(context.Background(), storage.Config{
+		Backend:    cfg.StorageBackend,
+		BucketName: cfg.BucketName,
+		Dir:        cfg.StorageDir,
+		URLPrefix:  "/files/",
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+
+	report, err := cleanup.CollectOrphanedUploads(context.Background(), dbConn, store, cleanup.OrphanOptions{
+		GracePeriod: *grace,
+		BatchSize:   *batch,
+		DryRun:      *dryRun,
+	})
+	if *dryRun {
+		for _, o := range report.Orphans {
+			fmt.Printf("%s  %8d bytes  %v\n", o.Created.Format("2006-01-02 15:04"), o.Bytes, o.Keys)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Garbage collection failed after %d orphans: %v", len(report.Orphans), err)
+	}
+
+	if *dryRun {
+		fmt.Printf("Found %d orphaned uploads (%d bytes) among %d objects; nothing deleted\n",
+			len(report.Orphans), report.Bytes(), report.Scanned)
+		return
+	}
+	fmt.Printf("✅ Deleted %d orphaned uploads (%d objects, %d bytes) among %d objects\n",
+		len(report.Orphans)-report.Failed, report.Deleted, report.Bytes(), report.Scanned)
+	if report.Failed > 0 {
+		log.Fatalf("%d orphaned uploads could not be deleted", report.Failed)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jmdictEntry is one <entry> of JMdict
+type jmdictEntry struct {
+	Seq   string `xml:"ent_seq"`
+	Kanji []struct {
+		Text     string   `xml:"keb"`
+		Priority []string `xml:"ke_pri"`
+	} `xml:"k_ele"`
+	Readings []struct {
+		Text     string   `xml:"reb"`
+		Priority []string `xml:"re_pri"`
+	} `xml:"r_ele"`
+	Senses []struct {
+		Glosses []struct {
+			Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"gloss"`
+	} `xml:"sense"`
+}
+
+// vocabRecord is the data imported for a single vocabulary word
+type vocabRecord struct {
+	Seq      int
+	Written  string
+	Reading  string
+	Meanings string
+	IsCommon bool
+}
+
+// maxVocabSenses limits how many senses are kept per word
+const maxVocabSenses = 3
+
+// commonPriorities are the JMdict priority tags that mark a common word
+var commonPriorities = map[string]bool{
+	"news1": true, "ichi1": true, "spec1": true, "spec2": true, "gai1": true,
+}
+
+// entityPattern matches the entity declarations in JMdict's internal DTD
+var entityPattern = regexp.MustCompile(`<!ENTITY\s+(\S+)\s+"([^"]*)">`)
+
+// parseJMdict streams JMdict XML and calls fn for every entry written with kanji
+func parseJMdict(r io.Reader, fn func(vocabRecord) error) error {
+	decoder := xml.NewDecoder(r)
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading JMdict: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.Directive:
+			// JMdict uses custom entities (&n; &v5r; ...) declared in the DTD
+			for _, m := range entityPattern.FindAllStringSubmatch(string(t), -1) {
+				if decoder.Entity == nil {
+					decoder.Entity = map[string]string{}
+				}
+				decoder.Entity[m[1]] = m[2]
+			}
+
+		case xml.StartElement:
+			if t.Name.Local != "entry" {
+				continue
+			}
+
+			var e jmdictEntry
+			if err := decoder.DecodeElement(&e, &t); err != nil {
+				return fmt.Errorf("error decoding entry: %w", err)
+			}
+
+			rec, ok := e.record()
+			if !ok {
+				continue
+			}
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// record converts the raw XML entry, reporting false for kana-only words
+func (e jmdictEntry) record() (vocabRecord, bool) {
+	seq, err := strconv.Atoi(strings.TrimSpace(e.Seq))
+	if err != nil || len(e.Kanji) == 0 || len(e.Readings) == 0 {
+		return vocabRecord{}, false
+	}
+
+	rec := vocabRecord{
+		Seq:     seq,
+		Written: e.Kanji[0].Text,
+		Reading: e.Readings[0].Text,
+	}
+
+	for _, p := range append(e.Kanji[0].Priority, e.Readings[0].Priority...) {
+		if commonPriorities[p] {
+			rec.IsCommon = true
+		}
+	}
+
+	var senses []string
+	for _, sense := range e.Senses {
+		var glosses []string
+		for _, g := range sense.Glosses {
+			if g.Lang == "" || g.Lang == "eng" {
+				glosses = append(glosses, g.Value)
+			}
+		}
+		if len(glosses) > 0 {
+			senses = append(senses, strings.Join(glosses, ", "))
+		}
+		if len(senses) == maxVocabSenses {
+			break
+		}
+	}
+	rec.Meanings = strings.Join(senses, "; ")
+
+	return rec, rec.Meanings != ""
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
+)
+
+// kanjidicCharacter is one <character> entry of KANJIDIC2
+type kanjidicCharacter struct {
+	Literal string `xml:"literal"`
+	Radical []struct {
+		Type  string `xml:"rad_type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"radical>rad_value"`
+	Misc struct {
+		Grade       string   `xml:"grade"`
+		StrokeCount []string `xml:"stroke_count"`
+		Freq        string   `xml:"freq"`
+		JLPT        string   `xml:"jlpt"`
+	} `xml:"misc"`
+	ReadingMeaning struct {
+		Groups []struct {
+			Readings []struct {
+				Type  string `xml:"r_type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"reading"`
+			Meanings []struct {
+				Lang  string `xml:"m_lang,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"meaning"`
+		} `xml:"rmgroup"`
+		Nanori []string `xml:"nanori"`
+	} `xml:"reading_meaning"`
+}
+
+// kanjiRecord is the data imported for a single kanji
+type kanjiRecord struct {
+	Char        string
+	Onyomi      []string // hiragana
+	Kunyomi     []string // hiragana, "." marks the okurigana boundary
+	Nanori      []string
+	Meanings    []string // English only
+	StrokeCount *int
+	Grade       *int
+	Frequency   *int
+	Radical     *int
+	JLPTLevel   *string
+}
+
+// oldJLPTLevels maps the pre-2010 JLPT levels in KANJIDIC2 to the current
+// ones. Old level 2 was split into N2 and N3; it is mapped to N2.
+var oldJLPTLevels = map[string]string{
+	"4": "n5",
+	"3": "n4",
+	"2": "n2",
+	"1": "n1",
+}
+
+// parseKanjidic streams KANJIDIC2 XML and calls fn for every character
+func parseKanjidic(r io.Reader, fn func(kanjiRecord) error) error {
+	decoder := xml.NewDecoder(r)
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading KANJIDIC2: %w", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "character" {
+			continue
+		}
+
+		var c kanjidicCharacter
+		if err := decoder.DecodeElement(&c, &start); err != nil {
+			return fmt.Errorf("error decoding character: %w", err)
+		}
+
+		if err := fn(c.record()); err != nil {
+			return err
+		}
+	}
+}
+
+// record converts the raw XML entry into a kanjiRecord
+func (c kanjidicCharacter) record() kanjiRecord {
+	rec := kanjiRecord{
+		Char:      strings.TrimSpace(c.Literal),
+		Grade:     atoiPtr(c.Misc.Grade),
+		Frequency: atoiPtr(c.Misc.Freq),
+		Nanori:    c.ReadingMeaning.Nanori,
+	}
+
+	// The first stroke count is the accepted one; others are common miscounts
+	if len(c.Misc.StrokeCount) > 0 {
+		rec.StrokeCount = atoiPtr(c.Misc.StrokeCount[0])
+	}
+
+	for _, rad := range c.Radical {
+		if rad.Type == "classical" {
+			rec.Radical = atoiPtr(rad.Value)
+		}
+	}
+
+	if level, ok := oldJLPTLevels[c.Misc.JLPT]; ok {
+		rec.JLPTLevel = &level
+	}
+
+	for _, group := range c.ReadingMeaning.Groups {
+		for _, reading := range group.Readings {
+			switch reading.Type {
+			case "ja_on":
+				rec.Onyomi = append(rec.Onyomi, kana.ToHiragana(reading.Value))
+			case "ja_kun":
+				rec.Kunyomi = append(rec.Kunyomi, reading.Value)
+			}
+		}
+		for _, meaning := range group.Meanings {
+			// Meanings without m_lang are English
+			if meaning.Lang == "" || meaning.Lang == "en" {
+				rec.Meanings = append(rec.Meanings, meaning.Value)
+			}
+		}
+	}
+
+	return rec
+}
+
+// atoiPtr parses an optional integer field
+func atoiPtr(s string) *int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
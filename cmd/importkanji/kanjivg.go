@@ -0,0 +1,190 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// strokeRecord is one stroke of a KanjiVG diagram
+type strokeRecord struct {
+	Number int
+	Type   string // kvg:type, e.g. ㇐
+	Path   string // SVG path data
+	LabelX *float64
+	LabelY *float64
+}
+
+// kanjivgChar returns the kanji a KanjiVG file is for, from its name: the
+// code point in hex, e.g. 05b57.svg for 字. Variant drawings
+// (05b57-Kaisho.svg) are skipped.
+func kanjivgChar(name string) (string, bool) {
+	base := path.Base(name)
+	hex, ok := strings.CutSuffix(base, ".svg")
+	if !ok || strings.Contains(hex, "-") {
+		return "", false
+	}
+	cp, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return "", false
+	}
+	return string(rune(cp)), true
+}
+
+// walkKanjiVG calls fn for every SVG in a KanjiVG archive: either the
+// released zip or a directory it was unpacked into
+func walkKanjiVG(archive string, fn func(name string, r io.Reader) error) error {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return fs.WalkDir(os.DirFS(archive), ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(name, ".svg") {
+				return err
+			}
+			f, err := os.Open(path.Join(archive, name))
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return fn(name, f)
+		})
+	}
+
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("%s is neither a directory nor a zip file: %w", archive, err)
+	}
+	defer zr.Close()
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() || !strings.HasSuffix(zf.Name, ".svg") {
+			continue
+		}
+		f, err := zf.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", zf.Name, err)
+		}
+		err = fn(zf.Name, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseKanjiVG reads the strokes of one KanjiVG SVG. Strokes are the
+// <path id="kvg:XXXXX-sN"> elements; their numbers are the <text> elements
+// in the StrokeNumbers group, positioned by a translate matrix.
+func parseKanjiVG(r io.Reader) ([]strokeRecord, error) {
+	byNumber := make(map[int]*strokeRecord)
+	stroke := func(n int) *strokeRecord {
+		s, ok := byNumber[n]
+		if !ok {
+			s = &strokeRecord{Number: n}
+			byNumber[n] = s
+		}
+		return s
+	}
+
+	dec := xml.NewDecoder(r)
+	dec.Strict = false // the DOCTYPE declares kvg: attributes without a namespace
+	var label *[2]float64
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "path":
+				id := attr(t, "id")
+				i := strings.LastIndex(id, "-s")
+				if i < 0 {
+					continue
+				}
+				n, err := strconv.Atoi(id[i+2:])
+				if err != nil || n <= 0 {
+					return nil, fmt.Errorf("bad stroke id %q", id)
+				}
+				s := stroke(n)
+				s.Path = attr(t, "d")
+				s.Type = attr(t, "type")
+			case "text":
+				label = nil
+				if x, y, ok := translate(attr(t, "transform")); ok {
+					label = &[2]float64{x, y}
+				}
+			}
+		case xml.CharData:
+			if label == nil {
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(string(t)))
+			if err != nil || n <= 0 {
+				continue
+			}
+			s := stroke(n)
+			s.LabelX, s.LabelY = &label[0], &label[1]
+			label = nil
+		case xml.EndElement:
+			if t.Name.Local == "text" {
+				label = nil
+			}
+		}
+	}
+
+	strokes := make([]strokeRecord, 0, len(byNumber))
+	for _, s := range byNumber {
+		if s.Path == "" {
+			return nil, fmt.Errorf("stroke %d has a number but no path", s.Number)
+		}
+		strokes = append(strokes, *s)
+	}
+	sort.Slice(strokes, func(i, j int) bool { return strokes[i].Number < strokes[j].Number })
+	for i, s := range strokes {
+		if s.Number != i+1 {
+			return nil, fmt.Errorf("stroke %d is missing", i+1)
+		}
+	}
+	return strokes, nil
+}
+
+// attr returns the value of an attribute by local name, ignoring its prefix
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// translate reads the x and y offsets of a "matrix(1 0 0 1 x y)" transform
+func translate(transform string) (x, y float64, ok bool) {
+	inner, ok := strings.CutPrefix(strings.TrimSpace(transform), "matrix(")
+	if !ok {
+		return 0, 0, false
+	}
+	fields := strings.Fields(strings.ReplaceAll(strings.TrimSuffix(inner, ")"), ",", " "))
+	if len(fields) != 6 {
+		return 0, 0, false
+	}
+	x, errX := strconv.ParseFloat(fields[4], 64)
+	y, errY := strconv.ParseFloat(fields[5], 64)
+	return x, y, errX == nil && errY == nil
+}
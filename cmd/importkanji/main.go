@@ -0,0 +1,541 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/UreshiiPanda/kanji_go/internal/db"
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/joho/godotenv"
+)
+
+// importStats counts what happened to each imported record
+type importStats struct {
+	Inserted int
+	Updated  int
+	Skipped  int
+}
+
+func (s importStats) String() string {
+	return fmt.Sprintf("%d inserted, %d updated, %d skipped", s.Inserted, s.Updated, s.Skipped)
+}
+
+func main() {
+	kanjidicPath := flag.String("kanjidic", "", "path to KANJIDIC2 XML file (required)")
+	jmdictPath := flag.String("jmdict", "", "path to JMdict XML file (optional)")
+	jlptOnly := flag.Bool("jlpt-only", false, "only import kanji that have a JLPT level")
+	allVocab := flag.Bool("all-vocab", false, "import all JMdict words, not just common ones")
+	radkPath := flag.String("radkfile", "", "path to RADKFILE (optional; UTF-8 or EUC-JP)")
+	kradPath := flag.String("kradfile", "", "path to KRADFILE (optional; UTF-8 or EUC-JP)")
+	kanjivgPath := flag.String("kanjivg", "", "path to the KanjiVG SVG zip or an unpacked directory (optional)")
+	flag.Parse()
+
+	if *kanjidicPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	// Get database connection
+	dbConn, err := db.GetDBConnection()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dbConn.Close()
+
+	kanjiStats, err := importKanjidic(dbConn, *kanjidicPath, *jlptOnly)
+	if err != nil {
+		log.Fatalf("KANJIDIC2 import failed: %v", err)
+	}
+	fmt.Printf("✅ Kanji: %s\n", kanjiStats)
+
+	if *jmdictPath != "" {
+		vocabStats, err := importJMdict(dbConn, *jmdictPath, !*allVocab)
+		if err != nil {
+			log.Fatalf("JMdict import failed: %v", err)
+		}
+		fmt.Printf("✅ Vocabulary: %s\n", vocabStats)
+	}
+
+	if *radkPath != "" || *kradPath != "" {
+		componentStats, err := importComponents(dbConn, *radkPath, *kradPath)
+		if err != nil {
+			log.Fatalf("Component import failed: %v", err)
+		}
+		fmt.Printf("✅ Component links: %s\n", componentStats)
+	}
+
+	if *kanjivgPath != "" {
+		strokeStats, err := importKanjiVG(dbConn, *kanjivgPath)
+		if err != nil {
+			log.Fatalf("KanjiVG import failed: %v", err)
+		}
+		fmt.Printf("✅ Stroke diagrams: %s\n", strokeStats)
+	}
+
+	// Commonness depends on both the readings and the vocabulary
+	if err := models.UpdateReadingCommonness(dbConn); err != nil {
+		log.Fatalf("Updating reading commonness failed: %v", err)
+	}
+}
+
+// importKanjidic upserts every kanji in the KANJIDIC2 file in one transaction
+func importKanjidic(dbConn *sql.DB, path string, jlptOnly bool) (importStats, error) {
+	var stats importStats
+
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = parseKanjidic(f, func(rec kanjiRecord) error {
+		if rec.Char == "" || (jlptOnly && rec.JLPTLevel == nil) {
+			stats.Skipped++
+			return nil
+		}
+
+		inserted, changed, err := upsertKanji(tx, rec)
+		if err != nil {
+			return fmt.Errorf("kanji %s: %w", rec.Char, err)
+		}
+
+		switch {
+		case inserted:
+			stats.Inserted++
+		case changed:
+			stats.Updated++
+		default:
+			stats.Skipped++
+		}
+
+		if total := stats.Inserted + stats.Updated + stats.Skipped; total%1000 == 0 {
+			log.Printf("Processed %d kanji", total)
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return stats, nil
+}
+
+// upsertKanji inserts or updates one kanji and its meanings. Romaji is
+// derived from the kana readings; existing JLPT levels are kept, since those
+// may have been curated by hand.
+func upsertKanji(tx *sql.Tx, rec kanjiRecord) (inserted, changed bool, err error) {
+	query := `
+		INSERT INTO kanji_go.kanji
+		(kanji_char, romaji_onyomi, romaji_kunyomi, hiragana_onyomi, hiragana_kunyomi,
+		 jlpt_level, stroke_count, grade, frequency, radical, nanori)
+		VALUES ($1, $10, $11, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (kanji_char) DO UPDATE SET
+			romaji_onyomi = EXCLUDED.romaji_onyomi,
+			romaji_kunyomi = EXCLUDED.romaji_kunyomi,
+			hiragana_onyomi = EXCLUDED.hiragana_onyomi,
+			hiragana_kunyomi = EXCLUDED.hiragana_kunyomi,
+			jlpt_level = COALESCE(kanji.jlpt_level, EXCLUDED.jlpt_level),
+			stroke_count = EXCLUDED.stroke_count,
+			grade = EXCLUDED.grade,
+			frequency = EXCLUDED.frequency,
+			radical = EXCLUDED.radical,
+			nanori = EXCLUDED.nanori,
+			updated_at = NOW()
+		WHERE (kanji.romaji_onyomi, kanji.romaji_kunyomi, kanji.hiragana_onyomi, kanji.hiragana_kunyomi,
+		       kanji.stroke_count, kanji.grade, kanji.frequency, kanji.radical, kanji.nanori)
+		      IS DISTINCT FROM
+		      (EXCLUDED.romaji_onyomi, EXCLUDED.romaji_kunyomi, EXCLUDED.hiragana_onyomi, EXCLUDED.hiragana_kunyomi,
+		       EXCLUDED.stroke_count, EXCLUDED.grade, EXCLUDED.frequency, EXCLUDED.radical, EXCLUDED.nanori)
+		   OR (kanji.jlpt_level IS NULL AND EXCLUDED.jlpt_level IS NOT NULL)
+		RETURNING kanji_char_id, (xmax = 0)
+	`
+
+	onyomi := strings.Join(rec.Onyomi, ", ")
+	kunyomi := strings.Join(rec.Kunyomi, ", ")
+
+	var id int
+	err = tx.QueryRow(query, rec.Char, onyomi, kunyomi,
+		rec.JLPTLevel, rec.StrokeCount, rec.Grade, rec.Frequency, rec.Radical,
+		strings.Join(rec.Nanori, ", "),
+		kana.ToRomaji(onyomi, kana.Hepburn),
+		kana.ToRomaji(kunyomi, kana.Hepburn),
+	).Scan(&id, &inserted)
+
+	// No row means the kanji exists and nothing changed
+	if err == sql.ErrNoRows {
+		if err := tx.QueryRow(`SELECT kanji_char_id FROM kanji_go.kanji WHERE kanji_char = $1`, rec.Char).Scan(&id); err != nil {
+			return false, false, fmt.Errorf("failed to look up kanji: %w", err)
+		}
+	} else if err != nil {
+		return false, false, fmt.Errorf("failed to upsert kanji: %w", err)
+	} else {
+		changed = true
+	}
+
+	// Structured readings follow the reading columns. They are compared even
+	// when the columns didn't change, so rows split differently by an older
+	// backfill are brought in line.
+	readings := models.ParseReadings(onyomi, models.ReadingOn)
+	readings = append(readings, models.ParseReadings(kunyomi, models.ReadingKun)...)
+	readings = append(readings, models.ParseReadings(strings.Join(rec.Nanori, ", "), models.ReadingNanori)...)
+	readingsChanged, err := models.ReplaceReadings(tx, id, readings)
+	if err != nil {
+		return false, false, err
+	}
+
+	meaningsChanged, err := replaceMeanings(tx, id, rec.Meanings)
+	if err != nil {
+		return false, false, err
+	}
+
+	return inserted, changed || readingsChanged || meaningsChanged, nil
+}
+
+// replaceMeanings rewrites a kanji's meanings if they differ from the stored ones
+func replaceMeanings(tx *sql.Tx, kanjiID int, meanings []string) (bool, error) {
+	// "\x1f" (unit separator) can't appear in a meaning
+	var current string
+	err := tx.QueryRow(`
+		SELECT COALESCE(string_agg(meaning, E'\x1f' ORDER BY position), '')
+		FROM kanji_go.kanji_meanings WHERE kanji_char_id = $1
+	`, kanjiID).Scan(&current)
+	if err != nil {
+		return false, fmt.Errorf("failed to read meanings: %w", err)
+	}
+	if current == strings.Join(meanings, "\x1f") {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM kanji_go.kanji_meanings WHERE kanji_char_id = $1`, kanjiID); err != nil {
+		return false, fmt.Errorf("failed to clear meanings: %w", err)
+	}
+	for i, m := range meanings {
+		_, err := tx.Exec(`INSERT INTO kanji_go.kanji_meanings (kanji_char_id, position, meaning) VALUES ($1, $2, $3)`,
+			kanjiID, i, m)
+		if err != nil {
+			return false, fmt.Errorf("failed to insert meaning: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// importJMdict upserts vocabulary and links each word to the kanji it contains
+func importJMdict(dbConn *sql.DB, path string, commonOnly bool) (importStats, error) {
+	var stats importStats
+
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = parseJMdict(f, func(rec vocabRecord) error {
+		if commonOnly && !rec.IsCommon {
+			stats.Skipped++
+			return nil
+		}
+
+		var vocabID int
+		var inserted bool
+		err := tx.QueryRow(`
+			INSERT INTO kanji_go.vocabulary (jmdict_seq, written, reading, meanings, is_common)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (jmdict_seq) DO UPDATE SET
+				written = EXCLUDED.written,
+				reading = EXCLUDED.reading,
+				meanings = EXCLUDED.meanings,
+				is_common = EXCLUDED.is_common,
+				updated_at = NOW()
+			WHERE (vocabulary.written, vocabulary.reading, vocabulary.meanings, vocabulary.is_common)
+			      IS DISTINCT FROM
+			      (EXCLUDED.written, EXCLUDED.reading, EXCLUDED.meanings, EXCLUDED.is_common)
+			RETURNING vocab_id, (xmax = 0)
+		`, rec.Seq, rec.Written, rec.Reading, rec.Meanings, rec.IsCommon).Scan(&vocabID, &inserted)
+		if err == sql.ErrNoRows {
+			stats.Skipped++
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("word %d: failed to upsert: %w", rec.Seq, err)
+		}
+
+		if inserted {
+			stats.Inserted++
+		} else {
+			stats.Updated++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	// Rebuild the kanji <-> word links, so words imported before their kanji
+	// (or kanji added since) are linked too
+	if _, err := tx.Exec(`DELETE FROM kanji_go.kanji_vocabulary`); err != nil {
+		return stats, fmt.Errorf("failed to clear kanji links: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO kanji_go.kanji_vocabulary (kanji_char_id, vocab_id)
+		SELECT DISTINCT k.kanji_char_id, v.vocab_id
+		FROM kanji_go.vocabulary v
+		CROSS JOIN LATERAL regexp_split_to_table(v.written, '') AS c(ch)
+		JOIN kanji_go.kanji k ON k.kanji_char = c.ch
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to link kanji: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return stats, nil
+}
+
+// importComponents replaces the kanji <-> component links with the ones in
+// RADKFILE and/or KRADFILE. Kanji that aren't in kanji_go.kanji are
+// skipped, so import KANJIDIC2 first.
+func importComponents(dbConn *sql.DB, radkPath, kradPath string) (importStats, error) {
+	var stats importStats
+
+	comps := make(map[string]*componentRecord)
+	if radkPath != "" {
+		data, err := readDictFile(radkPath)
+		if err != nil {
+			return stats, err
+		}
+		if err := parseRadkfile(data, comps); err != nil {
+			return stats, err
+		}
+	}
+	if kradPath != "" {
+		data, err := readDictFile(kradPath)
+		if err != nil {
+			return stats, err
+		}
+		if err := parseKradfile(data, comps); err != nil {
+			return stats, err
+		}
+	}
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var linkKanji, linkComponents []string
+	for _, c := range comps {
+		_, err := tx.Exec(`
+			INSERT INTO kanji_go.components (component, display, stroke_count, radical_number)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (component) DO UPDATE SET
+				display = EXCLUDED.display,
+				stroke_count = COALESCE(EXCLUDED.stroke_count, components.stroke_count),
+				radical_number = COALESCE(EXCLUDED.radical_number, components.radical_number)
+		`, c.Component, c.Display, c.StrokeCount, c.Radical)
+		if err != nil {
+			return stats, fmt.Errorf("component %s: failed to upsert: %w", c.Component, err)
+		}
+		seen := make(map[string]bool)
+		for _, k := range c.Kanji {
+			// Both files list most links, so skip the second copy
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			linkKanji = append(linkKanji, k)
+			linkComponents = append(linkComponents, c.Component)
+		}
+	}
+
+	// Components written as the radical itself, or as a kanji, get their
+	// radical number and stroke count from those tables
+	_, err = tx.Exec(`
+		UPDATE kanji_go.components c SET radical_number = r.radical_number
+		FROM kanji_go.radicals r
+		WHERE c.radical_number IS NULL AND r.radical_char = c.display
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to match radicals: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE kanji_go.components c SET stroke_count = k.stroke_count
+		FROM kanji_go.kanji k
+		WHERE c.stroke_count IS NULL AND k.kanji_char = c.component
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to fill in stroke counts: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM kanji_go.kanji_components`); err != nil {
+		return stats, fmt.Errorf("failed to clear component links: %w", err)
+	}
+	result, err := tx.Exec(`
+		INSERT INTO kanji_go.kanji_components (kanji_char_id, component)
+		SELECT k.kanji_char_id, l.component
+		FROM unnest($1::TEXT[], $2::TEXT[]) AS l(kanji_char, component)
+		JOIN kanji_go.kanji k ON k.kanji_char = l.kanji_char
+		ON CONFLICT DO NOTHING
+	`, linkKanji, linkComponents)
+	if err != nil {
+		return stats, fmt.Errorf("failed to link components: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return stats, fmt.Errorf("failed to link components: %w", err)
+	}
+	stats.Inserted = int(n)
+	stats.Skipped = len(linkKanji) - stats.Inserted
+
+	// Drop components left over from an older file
+	_, err = tx.Exec(`
+		DELETE FROM kanji_go.components c
+		WHERE NOT EXISTS (SELECT 1 FROM kanji_go.kanji_components kc WHERE kc.component = c.component)
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to remove unused components: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return stats, nil
+}
+
+// importKanjiVG replaces the stroke order of every kanji with a diagram in
+// the KanjiVG archive. Kanji that aren't in kanji_go.kanji are skipped.
+// Kanji without a KANJIDIC2 stroke count get the diagram's.
+func importKanjiVG(dbConn *sql.DB, archive string) (importStats, error) {
+	var stats importStats
+
+	ids := make(map[string]int)
+	rows, err := dbConn.Query(`SELECT kanji_char, kanji_char_id FROM kanji_go.kanji`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to load kanji: %w", err)
+	}
+	for rows.Next() {
+		var char string
+		var id int
+		if err := rows.Scan(&char, &id); err != nil {
+			rows.Close()
+			return stats, fmt.Errorf("failed to scan kanji: %w", err)
+		}
+		ids[char] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, fmt.Errorf("failed to load kanji: %w", err)
+	}
+
+	tx, err := dbConn.Begin()
+	if err != nil {
+		return stats, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = walkKanjiVG(archive, func(name string, r io.Reader) error {
+		char, ok := kanjivgChar(name)
+		if !ok {
+			return nil
+		}
+		id, ok := ids[char]
+		if !ok {
+			stats.Skipped++
+			return nil
+		}
+
+		strokes, err := parseKanjiVG(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if len(strokes) == 0 {
+			stats.Skipped++
+			return nil
+		}
+
+		result, err := tx.Exec(`DELETE FROM kanji_go.kanji_strokes WHERE kanji_char_id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("kanji %s: failed to clear strokes: %w", char, err)
+		}
+		replaced, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("kanji %s: failed to clear strokes: %w", char, err)
+		}
+
+		var numbers []int
+		var types, paths []string
+		var labelX, labelY []*float64
+		for _, s := range strokes {
+			numbers = append(numbers, s.Number)
+			types = append(types, s.Type)
+			paths = append(paths, s.Path)
+			labelX = append(labelX, s.LabelX)
+			labelY = append(labelY, s.LabelY)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO kanji_go.kanji_strokes (kanji_char_id, stroke_number, stroke_type, path, label_x, label_y)
+			SELECT $1, s.*
+			FROM unnest($2::INT[], $3::TEXT[], $4::TEXT[], $5::REAL[], $6::REAL[]) AS s
+		`, id, numbers, types, paths, labelX, labelY)
+		if err != nil {
+			return fmt.Errorf("kanji %s: failed to insert strokes: %w", char, err)
+		}
+
+		if replaced > 0 {
+			stats.Updated++
+		} else {
+			stats.Inserted++
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE kanji_go.kanji k SET stroke_count = s.n, updated_at = NOW()
+		FROM (SELECT kanji_char_id, COUNT(*) AS n FROM kanji_go.kanji_strokes GROUP BY kanji_char_id) s
+		WHERE k.kanji_char_id = s.kanji_char_id AND k.stroke_count IS NULL
+	`)
+	if err != nil {
+		return stats, fmt.Errorf("failed to fill in stroke counts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// componentRecord is one search component and the kanji that contain it
+type componentRecord struct {
+	Component   string
+	Display     string
+	StrokeCount *int
+	Radical     *int
+	Kanji       []string
+}
+
+// radkSubstitute is the real form of a component RADKFILE writes with a
+// stand-in kanji, and its Kangxi radical number (0 if it isn't one)
+type radkSubstitute struct {
+	Display string
+	Radical int
+}
+
+// radkSubstitutes are RADKFILE's stand-ins for radical forms that had no
+// JIS X 0208 code point
+var radkSubstitutes = map[string]radkSubstitute{
+	"化": {"亻", 9},
+	"个": {"𠆢", 9},
+	"并": {"丷", 12},
+	"刈": {"刂", 18},
+	"乞": {"𠂉", 0},
+	"込": {"⻌", 162},
+	"尚": {"⺌", 42},
+	"忙": {"忄", 61},
+	"扎": {"扌", 64},
+	"汁": {"氵", 85},
+	"犯": {"犭", 94},
+	"艾": {"⺾", 140},
+	"邦": {"⻏", 163},
+	"阡": {"⻖", 170},
+	"老": {"⺹", 125},
+	"杰": {"灬", 86},
+	"礼": {"礻", 113},
+	"疔": {"疒", 104},
+	"禹": {"禸", 114},
+	"初": {"衤", 145},
+	"買": {"罒", 122},
+}
+
+// readDictFile reads a RADKFILE/KRADFILE. The EDRDG originals are EUC-JP;
+// anything that isn't valid UTF-8 is decoded as that.
+func readDictFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.Valid(data) {
+		return data, nil
+	}
+	decoded, err := japanese.EUCJP.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither UTF-8 nor EUC-JP: %w", path, err)
+	}
+	return decoded, nil
+}
+
+// component returns the record for c in comps, creating it if needed
+func component(comps map[string]*componentRecord, c string) *componentRecord {
+	rec, ok := comps[c]
+	if !ok {
+		rec = &componentRecord{Component: c, Display: c}
+		if sub, ok := radkSubstitutes[c]; ok {
+			rec.Display = sub.Display
+			if sub.Radical != 0 {
+				n := sub.Radical
+				rec.Radical = &n
+			}
+		}
+		comps[c] = rec
+	}
+	return rec
+}
+
+// parseRadkfile reads RADKFILE: a "$ <component> <strokes> [<image>]" line
+// per component, followed by lines listing the kanji that contain it
+func parseRadkfile(data []byte, comps map[string]*componentRecord) error {
+	var cur *componentRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "$") {
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				return fmt.Errorf("RADKFILE line %d: malformed component line %q", line, text)
+			}
+			strokes, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return fmt.Errorf("RADKFILE line %d: bad stroke count %q", line, fields[2])
+			}
+			cur = component(comps, fields[1])
+			cur.StrokeCount = &strokes
+			continue
+		}
+
+		if cur == nil {
+			return fmt.Errorf("RADKFILE line %d: kanji before the first component", line)
+		}
+		for _, r := range text {
+			if r != ' ' && r != '　' {
+				cur.Kanji = append(cur.Kanji, string(r))
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// parseKradfile reads KRADFILE: "<kanji> : <component> <component> ..."
+// lines. Components have no stroke counts here.
+func parseKradfile(data []byte, comps map[string]*componentRecord) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		kanji, parts, ok := strings.Cut(text, ":")
+		kanji = strings.TrimSpace(kanji)
+		if !ok || utf8.RuneCountInString(kanji) != 1 {
+			return fmt.Errorf("KRADFILE line %d: malformed line %q", line, text)
+		}
+		for _, c := range strings.Fields(parts) {
+			rec := component(comps, c)
+			rec.Kanji = append(rec.Kanji, kanji)
+		}
+	}
+	return scanner.Err()
+}
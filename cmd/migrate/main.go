@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/UreshiiPanda/kanji_go/internal/db"
+	"github.com/joho/godotenv"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up          apply all pending migrations
+  down N      revert the N most recent migrations
+  status      list migrations and whether they are applied
+  force V     mark migrations up to V as applied without running them`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	// Get database connection
+	dbConn, err := db.GetDBConnection()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dbConn.Close()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := db.MigrateUp(dbConn)
+		for _, v := range applied {
+			fmt.Printf("✅ Applied migration %d\n", v)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+
+	case "down":
+		n := argInt()
+		reverted, err := db.MigrateDown(dbConn, n)
+		for _, v := range reverted {
+			fmt.Printf("✅ Reverted migration %d\n", v)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+
+	case "status":
+		statuses, err := db.MigrationStatuses(dbConn)
+		if err != nil {
+			log.Fatalf("Failed to get migration status: %v", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%06d  %-40s %s\n", s.Version, s.Name, applied)
+		}
+
+	case "force":
+		v := argInt()
+		if err := db.ForceVersion(dbConn, v); err != nil {
+			log.Fatalf("Failed to force version: %v", err)
+		}
+		fmt.Printf("✅ Forced schema version to %d\n", v)
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// argInt parses the command's numeric argument
+func argInt() int {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	n, err := strconv.Atoi(os.Args[2])
+	if err != nil || n < 0 {
+		log.Fatalf("Invalid number %q", os.Args[2])
+	}
+	return n
+}
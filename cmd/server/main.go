@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"html/template"
 	"io/fs"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/UreshiiPanda/kanji_go/internal/cleanup"
 	"github.com/UreshiiPanda/kanji_go/internal/config"
 	"github.com/UreshiiPanda/kanji_go/internal/db"
 	"github.com/UreshiiPanda/kanji_go/internal/handlers"
 	"github.com/UreshiiPanda/kanji_go/internal/middleware"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/search"
+	"github.com/UreshiiPanda/kanji_go/internal/session"
+	"github.com/UreshiiPanda/kanji_go/internal/storage"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 )
@@ -32,6 +39,67 @@ func main() {
 	}
 	defer dbConn.Close()
 
+	// Make sure the schema is current before serving anything
+	unmanaged, err := db.UnmanagedSchema(dbConn)
+	if err != nil {
+		log.Fatalf("Failed to check migrations: %v", err)
+	}
+	if unmanaged {
+		log.Fatalf("Database schema exists but no migrations are recorded (applied by hand?). " +
+			"Run `go run ./cmd/migrate force 1` to adopt it, then `go run ./cmd/migrate up`")
+	}
+	if cfg.MigrateOnStart {
+		applied, err := db.MigrateUp(dbConn)
+		if err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
+		}
+		log.Printf("Applied %d migrations", len(applied))
+	} else {
+		pending, err := db.PendingMigrations(dbConn)
+		if err != nil {
+			log.Fatalf("Failed to check migrations: %v", err)
+		}
+		if len(pending) > 0 {
+			log.Fatalf("Database schema is behind by %d migrations (next: %d_%s). "+
+				"Run `go run ./cmd/migrate up` or set MIGRATE_ON_START=true",
+				len(pending), pending[0].Version, pending[0].Name)
+		}
+	}
+
+	// Object storage for uploads
+	store, err := storage.New(context.Background(), storage.Config{
+		Backend:    cfg.StorageBackend,
+		BucketName: cfg.BucketName,
+		Dir:        cfg.StorageDir,
+		URLPrefix:  "/files/",
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer store.Close()
+	log.Printf("Using %s storage backend", cfg.StorageBackend)
+
+	// Server-side sessions, with expired rows cleaned up hourly
+	sessions := session.NewManager(dbConn, cfg.IsProd())
+	sessions.StartGC(context.Background(), time.Hour)
+
+	// Abandoned drafts (and their images) are purged hourly
+	cleanup.StartDraftJanitor(context.Background(), dbConn, store, time.Hour)
+
+	// Deleted uploads are purged hourly once their retention window is over
+	cleanup.StartUploadPurger(context.Background(), dbConn, store, time.Hour)
+
+	// Uploads nothing references are collected daily; see cmd/gcuploads
+	cleanup.StartOrphanCollector(context.Background(), dbConn, store, 24*time.Hour)
+
+	// In-memory kanji search index, refreshed to pick up imports
+	searchIndex := search.NewIndex()
+	if err := searchIndex.Reload(dbConn); err != nil {
+		log.Printf("Error loading search index: %v", err)
+	}
+	searchIndex.StartRefresh(context.Background(), dbConn, 15*time.Minute)
+	log.Printf("Search index loaded with %d kanji", searchIndex.Len())
+
 	// Create template
 	templatesSubFS, err := fs.Sub(templatesFS, "templates")
 	if err != nil {
@@ -93,18 +161,92 @@ func main() {
 	r.Use(chimiddleware.Recoverer)
 	r.Use(middleware.Cors())
 	r.Use(middleware.GetCSRFMiddleware())
+	r.Use(sessions.Middleware)
 
 	// Static files - using standard file server
 	r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.FS(staticSubFS))))
 
 	// Routes
 	r.Get("/", handlers.HomeHandler(tmpl))
-	r.Get("/api/kanji", handlers.GetKanjiHandler(dbConn, tmpl))
+	r.Get("/api/kanji", handlers.BrowseKanjiHandler(dbConn, tmpl))
+	r.Get("/search", handlers.SearchHandler(searchIndex, tmpl))
+	r.Get("/api/search", handlers.SearchAPIHandler(searchIndex))
 	r.Get("/dialog", handlers.GetDialogHandler())
 	r.Get("/empty", handlers.EmptyHandler())
-	r.Get("/list-files", handlers.ListFilesHandler(tmpl))
-	r.Post("/upload", handlers.UploadHandler())
-	r.Post("/delete-file", handlers.DeleteFileHandler())
+	r.Get("/list-files", handlers.ListFilesHandler(dbConn, store, tmpl))
+	r.Post("/upload", handlers.UploadHandler(dbConn, store, tmpl))
+	r.Post("/delete-file", handlers.DeleteFileHandler(dbConn, store, tmpl))
+	r.Get("/files/*", handlers.ServeFileHandler(dbConn, store))
+
+	// Auth routes
+	r.Get("/login", handlers.LoginDialogHandler(tmpl))
+	r.Post("/login", handlers.LoginHandler(dbConn, sessions, tmpl))
+	r.Post("/signup", handlers.SignupHandler(dbConn, sessions, tmpl))
+	r.Post("/logout", handlers.LogoutHandler(sessions, tmpl))
+	r.Get("/auth/status", handlers.AuthStatusHandler(tmpl))
+
+	// Review routes
+	r.Get("/review", handlers.ReviewHandler(dbConn, tmpl))
+	r.Get("/review/{kanjiID}/answer", handlers.ReviewAnswerHandler(dbConn, tmpl))
+	r.Post("/review/{kanjiID}", handlers.GradeReviewHandler(dbConn, tmpl))
+
+	// Kanji detail page, by character (or ID, which redirects)
+	r.Get("/kanji/{char}", handlers.KanjiDetailHandler(dbConn, tmpl))
+
+	// Stroke order (KanjiVG): step-by-step player, JSON and numbered SVG
+	r.Get("/kanji/{char}/strokes", handlers.KanjiStrokesHandler(dbConn, tmpl))
+	r.Get("/kanji/{char}/strokes.svg", handlers.KanjiStrokesSVGHandler(dbConn, tmpl))
+
+	// Starred / saved kanji toggles
+	r.Post("/kanji/{kanjiID}/star", handlers.ToggleKanjiListHandler(dbConn, tmpl, models.ListStarred))
+	r.Post("/kanji/{kanjiID}/save", handlers.ToggleKanjiListHandler(dbConn, tmpl, models.ListSaved))
+
+	// Kanji creation (mnemonic) routes
+	r.Get("/kanji/{kanjiID}/creations", handlers.ListCreationsHandler(dbConn, tmpl))
+	r.Post("/kanji/{kanjiID}/creations", handlers.CreateCreationHandler(dbConn, tmpl))
+	r.Get("/creations/{creationID}", handlers.GetCreationHandler(dbConn, tmpl))
+	r.Get("/creations/{creationID}/edit", handlers.EditCreationHandler(dbConn, tmpl))
+	r.Put("/creations/{creationID}", handlers.UpdateCreationHandler(dbConn, tmpl))
+	r.Post("/creations/{creationID}/visibility", handlers.ToggleCreationVisibilityHandler(dbConn, tmpl))
+	r.Delete("/creations/{creationID}", handlers.DeleteCreationHandler(dbConn, tmpl))
+
+	// Draft routes (one draft per user and kanji)
+	r.Get("/kanji/{kanjiID}/draft", handlers.DraftEditorHandler(dbConn, tmpl))
+	r.Put("/kanji/{kanjiID}/draft", handlers.SaveDraftHandler(dbConn, tmpl))
+	r.Delete("/kanji/{kanjiID}/draft", handlers.DiscardDraftHandler(dbConn, store, tmpl))
+	r.Post("/kanji/{kanjiID}/draft/image", handlers.DraftImageHandler(dbConn, store, tmpl))
+	r.Get("/kanji/{kanjiID}/draft/preview", handlers.PreviewDraftHandler(dbConn, tmpl))
+	r.Post("/kanji/{kanjiID}/draft/publish", handlers.PublishDraftHandler(dbConn, tmpl))
+
+	// Pack routes (built-in JLPT packs and custom decks)
+	r.Get("/packs", handlers.ListPacksHandler(dbConn, tmpl))
+	r.Post("/packs", handlers.CreatePackHandler(dbConn, tmpl))
+	r.Get("/packs/{packID}", handlers.GetPackHandler(dbConn, tmpl))
+	r.Get("/packs/{packID}/edit", handlers.EditPackHandler(dbConn, tmpl))
+	r.Put("/packs/{packID}", handlers.UpdatePackHandler(dbConn, tmpl))
+	r.Delete("/packs/{packID}", handlers.DeletePackHandler(dbConn, tmpl))
+	r.Post("/packs/{packID}/subscribe", handlers.TogglePackSubscriptionHandler(dbConn, tmpl))
+	r.Post("/packs/{packID}/kanji", handlers.AddPackKanjiHandler(dbConn, tmpl))
+	r.Delete("/packs/{packID}/kanji/{kanjiID}", handlers.RemovePackKanjiHandler(dbConn, tmpl))
+	r.Post("/packs/{packID}/kanji/{kanjiID}/move", handlers.MovePackKanjiHandler(dbConn, tmpl))
+
+	// Deck import / export (CSV, TSV, JSON, Anki)
+	r.Post("/packs/import", handlers.ImportPackHandler(dbConn, tmpl))
+	r.Post("/packs/{packID}/import", handlers.ImportPackHandler(dbConn, tmpl))
+	r.Get("/packs/{packID}/export", handlers.ExportPackHandler(dbConn, store))
+
+	// Quiz routes (typed answers, results recorded per user)
+	r.Get("/quiz", handlers.QuizHandler(dbConn, tmpl))
+	r.Get("/quiz/question", handlers.QuizQuestionHandler(dbConn, tmpl))
+	r.Post("/quiz/{kanjiID}", handlers.QuizAnswerHandler(dbConn, tmpl))
+
+	// Radical / component search routes
+	r.Get("/radicals", handlers.RadicalPickerHandler(dbConn, tmpl))
+	r.Get("/radicals/search", handlers.RadicalSearchHandler(dbConn, tmpl))
+
+	// Session state routes
+	r.Post("/session/preferences", handlers.PreferencesHandler())
+	r.Post("/dialog/close", handlers.CloseDialogHandler())
 
 	// Start server
 	log.Printf("Server starting on port %s", cfg.Port)
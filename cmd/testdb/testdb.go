@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/UreshiiPanda/kanji_go/internal/db"
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
 	"github.com/joho/godotenv"
 )
 
@@ -53,16 +54,19 @@ func main() {
 	// Generate a unique test kanji
 	testKanji := "試験__試験"
 	
-	// Insert the test kanji
+	// Insert the test kanji, with romaji derived from its readings
+	onyomi, kunyomi := "しけん", "ため.す"
 	insertSQL := `
 		INSERT INTO kanji_go.kanji 
 		(kanji_char, romaji_onyomi, romaji_kunyomi, hiragana_onyomi, hiragana_kunyomi, jlpt_level) 
-		VALUES ($1, 'shiken', 'tesuto', 'テスト', 'しけん', 'n5')
+		VALUES ($1, $2, $3, $4, $5, 'n5')
 		RETURNING kanji_char_id
 	`
 	
 	var testID int
-	err = dbConn.QueryRow(insertSQL, testKanji).Scan(&testID)
+	err = dbConn.QueryRow(insertSQL, testKanji,
+		kana.ToRomaji(onyomi, kana.Hepburn), kana.ToRomaji(kunyomi, kana.Hepburn),
+		onyomi, kunyomi).Scan(&testID)
 	if err != nil {
 		log.Fatalf("Failed to insert test kanji: %v", err)
 	}
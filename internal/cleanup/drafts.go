@@ -0,0 +1,86 @@
+// Package cleanup holds background jobs that remove stale rows and the
+// storage objects that belong to them.
+package cleanup
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/imaging"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/storage"
+)
+
+// StartDraftJanitor deletes expired drafts, and releases their images if
+// nothing else uses them, every interval until ctx is cancelled
+func StartDraftJanitor(ctx context.Context, db *sql.DB, store storage.Store, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				PurgeExpiredDrafts(ctx, db, store)
+			}
+		}
+	}()
+}
+
+// PurgeExpiredDrafts runs one pass of the draft janitor
+func PurgeExpiredDrafts(ctx context.Context, db *sql.DB, store storage.Store) {
+	drafts, err := models.DeleteExpiredDrafts(db, time.Now())
+	if err != nil {
+		log.Printf("Error deleting expired drafts: %v", err)
+		return
+	}
+	if len(drafts) == 0 {
+		return
+	}
+
+	for _, d := range drafts {
+		for _, url := range []*string{d.ImageURL, d.MappingURL} {
+			if url != nil {
+				ReleaseImageIfUnused(db, store, d.Owner, *url)
+			}
+		}
+	}
+
+	log.Printf("Deleted %d expired drafts", len(drafts))
+}
+
+// ReleaseImageIfUnused soft-deletes owner's upload of the image behind
+// url, unless a creation or draft still references it. The upload purger
+// deletes it after the retention window, with its objects once no other
+// upload shares them. URLs outside the store or its uploads/ prefix, and
+// images owner never uploaded, are left alone: the URL comes from user
+// input.
+func ReleaseImageIfUnused(db *sql.DB, store storage.Store, owner, url string) {
+	key, ok := storage.KeyFromURL(store, url)
+	if !ok || !strings.HasPrefix(key, uploadsPrefix) || owner == "" {
+		return
+	}
+
+	inUse, err := models.ImageURLInUse(db, url)
+	if err != nil {
+		log.Printf("Error checking references to %s: %v", key, err)
+		return
+	}
+	if inUse {
+		return
+	}
+
+	n, err := models.ReleaseOwnerUploads(db, owner, imaging.VariantKeys(key))
+	if err != nil {
+		log.Printf("Error releasing upload %s: %v", key, err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Released unused upload %s", key)
+	}
+}
@@ -0,0 +1,263 @@
+package cleanup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/imaging"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/storage"
+)
+
+// uploadsPrefix is where the upload handlers store images
+const uploadsPrefix = "uploads/"
+
+// OrphanGracePeriod is how old an unreferenced upload must be before it is
+// collected, so images uploaded for a creation still being written survive
+const OrphanGracePeriod = 7 * 24 * time.Hour
+
+// defaultOrphanBatchSize is how many uploads are checked and deleted at once
+const defaultOrphanBatchSize = 100
+
+// OrphanOptions controls a garbage collection pass
+type OrphanOptions struct {
+	GracePeriod time.Duration // defaults to OrphanGracePeriod
+	BatchSize   int           // uploads per batch; defaults to 100
+	DryRun      bool          // report orphans without deleting anything
+
+	now func() time.Time // defaults to time.Now; set by tests
+}
+
+// uploadIndex is what the collector needs to know from the database about
+// the uploads it finds in the bucket
+type uploadIndex interface {
+	// ImageURLsInUse returns which URLs a creation or draft references
+	ImageURLsInUse(urls []string) (map[string]bool, error)
+	// DeletedUploadKeys returns which keys belong to a soft-deleted upload
+	DeletedUploadKeys(keys []string) (map[string]bool, error)
+	// LatestUploadTimes returns when each key was last uploaded
+	LatestUploadTimes(keys []string) (map[string]time.Time, error)
+	// DeleteOrphanedUpload removes the upload rows and blob of keys,
+	// unless they were uploaded after cutoff
+	DeleteOrphanedUpload(keys []string, cutoff time.Time) (bool, error)
+}
+
+// dbUploadIndex is the uploadIndex backed by kanji_go.uploads
+type dbUploadIndex struct {
+	db *sql.DB
+}
+
+func (x dbUploadIndex) ImageURLsInUse(urls []string) (map[string]bool, error) {
+	return models.ImageURLsInUse(x.db, urls)
+}
+
+func (x dbUploadIndex) DeletedUploadKeys(keys []string) (map[string]bool, error) {
+	return models.DeletedUploadKeys(x.db, keys)
+}
+
+func (x dbUploadIndex) LatestUploadTimes(keys []string) (map[string]time.Time, error) {
+	return models.LatestUploadTimes(x.db, keys)
+}
+
+func (x dbUploadIndex) DeleteOrphanedUpload(keys []string, cutoff time.Time) (bool, error) {
+	return models.DeleteOrphanedUpload(x.db, keys, cutoff)
+}
+
+// Orphan is an upload no creation or draft references: every size of it
+// found in the bucket
+type Orphan struct {
+	Keys    []string
+	Bytes   int64
+	Created time.Time // of its newest object, or newest upload if later
+}
+
+// OrphanReport summarises a garbage collection pass
+type OrphanReport struct {
+	Scanned int      // objects listed under uploads/
+	Orphans []Orphan // found, and deleted unless it was a dry run
+	Deleted int      // objects deleted
+	Failed  int      // orphans left in place after an error
+}
+
+// Bytes is the total size of the orphans found
+func (r *OrphanReport) Bytes() int64 {
+	var n int64
+	for _, o := range r.Orphans {
+		n += o.Bytes
+	}
+	return n
+}
+
+// StartOrphanCollector deletes orphaned uploads every interval until ctx is
+// cancelled
+func StartOrphanCollector(ctx context.Context, db *sql.DB, store storage.Store, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := CollectOrphanedUploads(ctx, db, store, OrphanOptions{})
+				if err != nil {
+					log.Printf("Error collecting orphaned uploads: %v", err)
+					continue
+				}
+				if len(report.Orphans) > 0 {
+					log.Printf("Deleted %d orphaned uploads (%d objects, %d bytes, %d failed)",
+						len(report.Orphans)-report.Failed, report.Deleted, report.Bytes(), report.Failed)
+				}
+			}
+		}
+	}()
+}
+
+// CollectOrphanedUploads lists every object under uploads/ and deletes the
+// uploads that are older than the grace period and that no creation or
+// draft references by the URL of any of their sizes. An upload's age is
+// that of its newest object or its newest upload row, since reusing stored
+// content adds a row without touching the objects. Uploads waiting out
+// their soft-delete retention are left to the purger. References are
+// checked batch by batch just before deleting, and the upload rows again
+// as each one is deleted, so an image attached or reused during a long
+// pass is kept.
+func CollectOrphanedUploads(ctx context.Context, db *sql.DB, store storage.Store, opts OrphanOptions) (*OrphanReport, error) {
+	return collectOrphans(ctx, dbUploadIndex{db}, store, opts)
+}
+
+// collectOrphans is CollectOrphanedUploads against any uploadIndex
+func collectOrphans(ctx context.Context, index uploadIndex, store storage.Store, opts OrphanOptions) (*OrphanReport, error) {
+	if opts.GracePeriod <= 0 {
+		opts.GracePeriod = OrphanGracePeriod
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultOrphanBatchSize
+	}
+	if opts.now == nil {
+		opts.now = time.Now
+	}
+	cutoff := opts.now().Add(-opts.GracePeriod)
+
+	// Group the listed objects by upload; the sizes of one upload share a
+	// directory, and legacy uploads are a single file
+	report := &OrphanReport{}
+	var candidates []*Orphan
+	byUpload := make(map[string]*Orphan)
+	token := ""
+	for {
+		page, err := store.List(ctx, storage.ListOptions{Prefix: uploadsPrefix, PageToken: token})
+		if err != nil {
+			return report, fmt.Errorf("failed to list uploads: %w", err)
+		}
+		for _, obj := range page.Objects {
+			report.Scanned++
+			id := imaging.VariantKeys(obj.Key)[0]
+			o, ok := byUpload[id]
+			if !ok {
+				o = &Orphan{}
+				byUpload[id] = o
+				candidates = append(candidates, o)
+			}
+			o.Keys = append(o.Keys, obj.Key)
+			o.Bytes += obj.Size
+			if obj.Created.After(o.Created) {
+				o.Created = obj.Created
+			}
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		token = page.NextPageToken
+	}
+
+	for start := 0; start < len(candidates); start += opts.BatchSize {
+		batch := candidates[start:min(start+opts.BatchSize, len(candidates))]
+		if err := collectBatch(ctx, index, store, batch, cutoff, opts.DryRun, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// collectBatch checks one batch of uploads against the database and
+// deletes the orphans among them
+func collectBatch(ctx context.Context, index uploadIndex, store storage.Store, batch []*Orphan, cutoff time.Time, dryRun bool, report *OrphanReport) error {
+	var keys, urls []string
+	for _, o := range batch {
+		if o.Created.After(cutoff) {
+			continue
+		}
+		for _, k := range imaging.VariantKeys(o.Keys[0]) {
+			keys = append(keys, k)
+			urls = append(urls, store.PublicURL(k))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	inUse, err := index.ImageURLsInUse(urls)
+	if err != nil {
+		return err
+	}
+	deletedKeys, err := index.DeletedUploadKeys(keys)
+	if err != nil {
+		return err
+	}
+	latest, err := index.LatestUploadTimes(keys)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range batch {
+		if o.Created.After(cutoff) {
+			continue
+		}
+		for _, k := range imaging.VariantKeys(o.Keys[0]) {
+			if t, ok := latest[k]; ok && t.After(o.Created) {
+				o.Created = t
+			}
+		}
+		if o.Created.After(cutoff) || keepUpload(store, o, inUse, deletedKeys) {
+			continue
+		}
+		if dryRun {
+			report.Orphans = append(report.Orphans, *o)
+			continue
+		}
+
+		// The rows go first: objects left behind by a failed delete are
+		// untracked orphans the next pass removes
+		removed, err := index.DeleteOrphanedUpload(imaging.VariantKeys(o.Keys[0]), cutoff)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			// Uploaded again since the batch was checked
+			continue
+		}
+		report.Orphans = append(report.Orphans, *o)
+		if !deleteObjects(ctx, store, o.Keys) {
+			report.Failed++
+			continue
+		}
+		report.Deleted += len(o.Keys)
+	}
+	return nil
+}
+
+// keepUpload reports whether any size of the upload is used by a creation
+// or draft, or the upload is soft-deleted and so left to the purger
+func keepUpload(store storage.Store, o *Orphan, inUse, deleted map[string]bool) bool {
+	for _, k := range imaging.VariantKeys(o.Keys[0]) {
+		if inUse[store.PublicURL(k)] || deleted[k] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package cleanup
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/imaging"
+	"github.com/UreshiiPanda/kanji_go/internal/storage"
+)
+
+// fakeIndex is an uploadIndex over fixed references
+type fakeIndex struct {
+	inUse   map[string]bool      // URLs used by a creation or draft
+	deleted map[string]bool      // keys of soft-deleted uploads
+	latest  map[string]time.Time // when keys were last uploaded
+	reused  map[string]bool      // keys uploaded again once the batch is checked
+	removed []string             // keys whose rows DeleteOrphanedUpload removed
+	batches int                  // reference checks made
+}
+
+func (f *fakeIndex) ImageURLsInUse(urls []string) (map[string]bool, error) {
+	f.batches++
+	out := make(map[string]bool)
+	for _, u := range urls {
+		if f.inUse[u] {
+			out[u] = true
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeIndex) DeletedUploadKeys(keys []string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	for _, k := range keys {
+		if f.deleted[k] {
+			out[k] = true
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeIndex) LatestUploadTimes(keys []string) (map[string]time.Time, error) {
+	out := make(map[string]time.Time)
+	for _, k := range keys {
+		if t, ok := f.latest[k]; ok {
+			out[k] = t
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeIndex) DeleteOrphanedUpload(keys []string, cutoff time.Time) (bool, error) {
+	for _, k := range keys {
+		if f.reused[k] {
+			return false, nil
+		}
+	}
+	f.removed = append(f.removed, keys...)
+	return true, nil
+}
+
+// putUpload stores every size of an upload in dir, or key itself if it
+// isn't a variant, and returns the keys
+func putUpload(t *testing.T, store storage.Store, key string) []string {
+	t.Helper()
+	keys := imaging.VariantKeys(key)
+	for _, k := range keys {
+		if _, err := store.Put(context.Background(), k, strings.NewReader("image data"), "image/webp"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keys
+}
+
+// orphanFixture fills a store with one upload of each kind. Everything but
+// the "fresh" upload is stored before the returned time, and the "reused"
+// blob is uploaded again after it.
+func orphanFixture(t *testing.T) (store *storage.MemoryStore, index *fakeIndex, stored time.Time) {
+	store = storage.NewMemoryStore("/files/")
+	putUpload(t, store, "uploads/sha256/orphan/full.webp")
+	putUpload(t, store, "uploads/legacy.png")
+	used := putUpload(t, store, "uploads/sha256/used/full.webp")
+	gone := putUpload(t, store, "uploads/sha256/gone/full.webp")
+	reused := putUpload(t, store, "uploads/sha256/reused/full.webp")
+	putUpload(t, store, "avatars/not-an-upload.png")
+
+	stored = time.Now()
+	time.Sleep(5 * time.Millisecond)
+	putUpload(t, store, "uploads/sha256/fresh/full.webp")
+
+	index = &fakeIndex{
+		inUse:   map[string]bool{store.PublicURL(used[1]): true},
+		deleted: map[string]bool{gone[2]: true},
+		latest:  map[string]time.Time{reused[2]: time.Now()},
+	}
+	return store, index, stored
+}
+
+// listKeys returns every key in the store
+func listKeys(t *testing.T, store storage.Store) []string {
+	t.Helper()
+	page, err := store.List(context.Background(), storage.ListOptions{Limit: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, o := range page.Objects {
+		keys = append(keys, o.Key)
+	}
+	return keys
+}
+
+// orphanKeys flattens the keys of the orphans in a report
+func orphanKeys(r *OrphanReport) []string {
+	var keys []string
+	for _, o := range r.Orphans {
+		keys = append(keys, o.Keys...)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestCollectOrphans(t *testing.T) {
+	store, index, stored := orphanFixture(t)
+	grace := time.Hour
+	opts := OrphanOptions{
+		GracePeriod: grace,
+		BatchSize:   2,
+		now:         func() time.Time { return stored.Add(grace + time.Millisecond) },
+	}
+
+	report, err := collectOrphans(context.Background(), index, store, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(imaging.VariantKeys("uploads/sha256/orphan/full.webp"), "uploads/legacy.png")
+	slices.Sort(want)
+	if got := orphanKeys(report); !slices.Equal(got, want) {
+		t.Errorf("orphans = %v, want %v", got, want)
+	}
+	if report.Scanned != 16 {
+		t.Errorf("Scanned = %d, want 16 objects under uploads/", report.Scanned)
+	}
+	if report.Deleted != len(want) || report.Failed != 0 {
+		t.Errorf("Deleted, Failed = %d, %d; want %d, 0", report.Deleted, report.Failed, len(want))
+	}
+	if index.batches != 3 {
+		t.Errorf("checked %d batches, want 3 (6 uploads, 2 at a time)", index.batches)
+	}
+
+	slices.Sort(index.removed)
+	if !slices.Equal(index.removed, want) {
+		t.Errorf("rows removed for %v, want %v", index.removed, want)
+	}
+	for _, k := range listKeys(t, store) {
+		if slices.Contains(want, k) {
+			t.Errorf("orphan %s is still stored", k)
+		}
+	}
+	for _, kept := range []string{
+		"uploads/sha256/fresh/thumb.webp",  // within the grace period
+		"uploads/sha256/reused/thumb.webp", // uploaded again within it
+		"uploads/sha256/used/full.webp",    // another size is referenced
+		"uploads/sha256/gone/thumb.webp",   // soft-deleted, left to the purger
+		"avatars/not-an-upload.png",        // outside uploads/
+	} {
+		if !slices.Contains(listKeys(t, store), kept) {
+			t.Errorf("%s was deleted", kept)
+		}
+	}
+}
+
+func TestCollectOrphansGracePeriod(t *testing.T) {
+	store, index, _ := orphanFixture(t)
+
+	// Everything was stored just now, so a week's grace keeps it all
+	report, err := collectOrphans(context.Background(), index, store, OrphanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Orphans) != 0 || index.batches != 0 {
+		t.Errorf("orphans = %v after %d checks; want none and no checks", orphanKeys(report), index.batches)
+	}
+}
+
+func TestCollectOrphansReusedDuringPass(t *testing.T) {
+	store, index, stored := orphanFixture(t)
+	orphan := imaging.VariantKeys("uploads/sha256/orphan/full.webp")
+	index.reused = map[string]bool{orphan[2]: true}
+
+	report, err := collectOrphans(context.Background(), index, store, OrphanOptions{
+		GracePeriod: time.Minute,
+		now:         func() time.Time { return stored.Add(time.Minute + time.Millisecond) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := orphanKeys(report); !slices.Equal(got, []string{"uploads/legacy.png"}) {
+		t.Errorf("orphans = %v, want only the legacy upload", got)
+	}
+	for _, k := range orphan {
+		if !slices.Contains(listKeys(t, store), k) {
+			t.Errorf("%s was deleted after being uploaded again", k)
+		}
+	}
+}
+
+func TestCollectOrphansDryRun(t *testing.T) {
+	store, index, stored := orphanFixture(t)
+	before := listKeys(t, store)
+
+	report, err := collectOrphans(context.Background(), index, store, OrphanOptions{
+		GracePeriod: time.Minute,
+		DryRun:      true,
+		now:         func() time.Time { return stored.Add(time.Minute + time.Millisecond) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Orphans) != 2 {
+		t.Errorf("found %d orphans, want 2: %v", len(report.Orphans), orphanKeys(report))
+	}
+	if report.Deleted != 0 || len(index.removed) != 0 {
+		t.Errorf("dry run deleted %d objects and %d rows", report.Deleted, len(index.removed))
+	}
+	if after := listKeys(t, store); !slices.Equal(after, before) {
+		t.Errorf("dry run changed the store: %v, was %v", after, before)
+	}
+}
@@ -0,0 +1,79 @@
+package cleanup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/imaging"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/storage"
+)
+
+// DeletedUploadRetention is how long a deleted upload's objects are kept
+// before they are purged
+const DeletedUploadRetention = 30 * 24 * time.Hour
+
+// purgeBatchSize caps how many uploads one purge pass removes
+const purgeBatchSize = 100
+
+// StartUploadPurger removes the objects and rows of uploads deleted more
+// than DeletedUploadRetention ago, every interval until ctx is cancelled
+func StartUploadPurger(ctx context.Context, db *sql.DB, store storage.Store, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				PurgeDeletedUploads(ctx, db, store, time.Now().Add(-DeletedUploadRetention))
+			}
+		}
+	}()
+}
+
+// PurgeDeletedUploads runs one pass of the upload purger, removing uploads
+// deleted before cutoff. Objects are only deleted with the last upload of
+// an image; any that can't be are left to the orphan collector.
+func PurgeDeletedUploads(ctx context.Context, db *sql.DB, store storage.Store, cutoff time.Time) {
+	uploads, err := models.ListPurgeableUploads(db, cutoff, purgeBatchSize)
+	if err != nil {
+		log.Printf("Error listing deleted uploads: %v", err)
+		return
+	}
+
+	purged := 0
+	for i := range uploads {
+		u := &uploads[i]
+		gone, err := models.PurgeUpload(db, u)
+		if err != nil {
+			log.Printf("Error purging upload %d: %v", u.UploadID, err)
+			continue
+		}
+		if gone {
+			deleteObjects(ctx, store, imaging.VariantKeys(u.ObjectKey))
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d deleted uploads", purged)
+	}
+}
+
+// deleteObjects deletes every key, treating missing objects as deleted,
+// and reports whether they are all gone
+func deleteObjects(ctx context.Context, store storage.Store, keys []string) bool {
+	for _, k := range keys {
+		if err := store.Delete(ctx, k); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error deleting object %s: %v", k, err)
+			return false
+		}
+	}
+	return true
+}
@@ -11,6 +11,14 @@ import (
 type AppConfig struct {
 	Port   string
 	AppEnv string // Added APP_ENV
+
+	// Object storage for uploads
+	StorageBackend string // "gcs", "local" or "memory"
+	StorageDir     string // Root directory for the local backend
+	BucketName     string // Bucket for the gcs backend
+
+	// Apply pending database migrations at startup instead of refusing to start
+	MigrateOnStart bool
 }
 
 // Load loads the application configuration
@@ -32,9 +40,30 @@ func Load() *AppConfig {
 		appEnv = "LOCAL" // Default to LOCAL if not specified
 	}
 	
+	// Get storage backend, defaulting to Cloud Storage
+	storageBackend := os.Getenv("STORAGE_BACKEND")
+	if storageBackend == "" {
+		storageBackend = "gcs"
+	}
+
+	storageDir := os.Getenv("STORAGE_DIR")
+	if storageDir == "" {
+		storageDir = "./data/storage"
+	}
+
+	bucketName := os.Getenv("BUCKET_NAME")
+	if bucketName == "" && storageBackend == "gcs" {
+		log.Println("Warning: BUCKET_NAME not set, using default bucket name")
+		bucketName = "default-bucket-name"
+	}
+
 	return &AppConfig{
-		Port:   port,
-		AppEnv: appEnv,
+		Port:           port,
+		AppEnv:         appEnv,
+		StorageBackend: storageBackend,
+		StorageDir:     storageDir,
+		BucketName:     bucketName,
+		MigrateOnStart: os.Getenv("MIGRATE_ON_START") == "true",
 	}
 }
 
@@ -0,0 +1,4 @@
+-- Emails and usernames are unique regardless of case, and logins look
+-- them up with LOWER() so these indexes also serve the login query
+CREATE UNIQUE INDEX users_email_lower_key ON kanji_go.users (LOWER(email));
+CREATE UNIQUE INDEX users_username_lower_key ON kanji_go.users (LOWER(username));
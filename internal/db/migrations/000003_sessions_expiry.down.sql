@@ -0,0 +1 @@
+DROP INDEX IF EXISTS kanji_go.idx_sessions_updated_at;
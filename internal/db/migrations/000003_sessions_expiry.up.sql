@@ -0,0 +1,2 @@
+-- Expired sessions are garbage-collected by updated_at
+CREATE INDEX idx_sessions_updated_at ON kanji_go.sessions(updated_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS kanji_go.srs_reviews;
+DROP TABLE IF EXISTS kanji_go.srs_cards;
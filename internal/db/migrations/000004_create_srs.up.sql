@@ -0,0 +1,29 @@
+-- Per-user spaced repetition state for each kanji
+CREATE TABLE kanji_go.srs_cards (
+    user_id INT NOT NULL REFERENCES kanji_go.users(id) ON DELETE CASCADE,
+    kanji_char_id INT NOT NULL REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE,
+    state VARCHAR(10) NOT NULL DEFAULT 'learning' CHECK (state IN ('learning', 'review', 'relearning')),
+    ease_factor REAL NOT NULL DEFAULT 2.5,
+    interval_days INT NOT NULL DEFAULT 0,
+    repetitions INT NOT NULL DEFAULT 0,
+    lapses INT NOT NULL DEFAULT 0,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, kanji_char_id)
+);
+
+-- Log of every grading, for stats and future re-scheduling
+CREATE TABLE kanji_go.srs_reviews (
+    review_id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES kanji_go.users(id) ON DELETE CASCADE,
+    kanji_char_id INT NOT NULL REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE,
+    grade SMALLINT NOT NULL CHECK (grade BETWEEN 1 AND 4),
+    interval_days INT NOT NULL,
+    ease_factor REAL NOT NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_srs_cards_user_due ON kanji_go.srs_cards(user_id, due_at);
+CREATE INDEX idx_srs_reviews_user_reviewed ON kanji_go.srs_reviews(user_id, reviewed_at);
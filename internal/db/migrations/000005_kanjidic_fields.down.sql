@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS kanji_go.kanji_vocabulary;
+DROP TABLE IF EXISTS kanji_go.vocabulary;
+DROP TABLE IF EXISTS kanji_go.kanji_meanings;
+
+DROP INDEX IF EXISTS kanji_go.idx_kanji_frequency;
+
+ALTER TABLE kanji_go.kanji
+    ALTER COLUMN romaji_onyomi TYPE VARCHAR(255),
+    ALTER COLUMN romaji_kunyomi TYPE VARCHAR(255),
+    ALTER COLUMN hiragana_onyomi TYPE VARCHAR(255),
+    ALTER COLUMN hiragana_kunyomi TYPE VARCHAR(255);
+
+ALTER TABLE kanji_go.kanji
+    DROP COLUMN IF EXISTS nanori,
+    DROP COLUMN IF EXISTS radical,
+    DROP COLUMN IF EXISTS frequency,
+    DROP COLUMN IF EXISTS grade,
+    DROP COLUMN IF EXISTS stroke_count;
@@ -0,0 +1,44 @@
+-- Extra KANJIDIC2 fields on kanji
+ALTER TABLE kanji_go.kanji
+    ADD COLUMN stroke_count SMALLINT,
+    ADD COLUMN grade SMALLINT,
+    ADD COLUMN frequency INT,
+    ADD COLUMN radical SMALLINT CHECK (radical BETWEEN 1 AND 214),
+    ADD COLUMN nanori TEXT;
+
+-- Some kanji have more readings than fit in 255 characters
+ALTER TABLE kanji_go.kanji
+    ALTER COLUMN romaji_onyomi TYPE TEXT,
+    ALTER COLUMN romaji_kunyomi TYPE TEXT,
+    ALTER COLUMN hiragana_onyomi TYPE TEXT,
+    ALTER COLUMN hiragana_kunyomi TYPE TEXT;
+
+-- English meanings, in dictionary order
+CREATE TABLE kanji_go.kanji_meanings (
+    kanji_char_id INT NOT NULL REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE,
+    position SMALLINT NOT NULL,
+    meaning TEXT NOT NULL,
+    PRIMARY KEY (kanji_char_id, position)
+);
+
+-- Vocabulary imported from JMdict
+CREATE TABLE kanji_go.vocabulary (
+    vocab_id SERIAL PRIMARY KEY,
+    jmdict_seq INT NOT NULL UNIQUE,
+    written VARCHAR(255) NOT NULL,
+    reading VARCHAR(255) NOT NULL,
+    meanings TEXT NOT NULL,
+    is_common BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Which kanji appear in which vocabulary words
+CREATE TABLE kanji_go.kanji_vocabulary (
+    kanji_char_id INT NOT NULL REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE,
+    vocab_id INT NOT NULL REFERENCES kanji_go.vocabulary(vocab_id) ON DELETE CASCADE,
+    PRIMARY KEY (kanji_char_id, vocab_id)
+);
+
+CREATE INDEX idx_kanji_frequency ON kanji_go.kanji(frequency);
+CREATE INDEX idx_kanji_vocabulary_vocab_id ON kanji_go.kanji_vocabulary(vocab_id);
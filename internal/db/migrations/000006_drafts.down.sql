@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS kanji_go.idx_temp_creation_expires_at;
+DROP INDEX IF EXISTS kanji_go.idx_temp_creation_owner_kanji;
+
+ALTER TABLE kanji_go.temp_creation
+    ALTER COLUMN explanation DROP DEFAULT,
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS is_public,
+    DROP COLUMN IF EXISTS owner;
@@ -0,0 +1,16 @@
+-- Give drafts an owner, visibility, and an expiry for abandoned ones
+ALTER TABLE kanji_go.temp_creation
+    ADD COLUMN owner VARCHAR(255) REFERENCES kanji_go.users(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    ADD COLUMN is_public BOOLEAN DEFAULT FALSE,
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() + INTERVAL '30 days';
+
+-- Drafts from before ownership existed can't be attributed to anyone, so
+-- owner stays nullable: those rows are hidden from every user and the draft
+-- janitor removes them once they expire
+ALTER TABLE kanji_go.temp_creation
+    ALTER COLUMN explanation SET DEFAULT '';
+
+-- One draft per user per kanji, so autosave can upsert
+CREATE UNIQUE INDEX idx_temp_creation_owner_kanji ON kanji_go.temp_creation(owner, kanji_char_id);
+CREATE INDEX idx_temp_creation_expires_at ON kanji_go.temp_creation(expires_at);
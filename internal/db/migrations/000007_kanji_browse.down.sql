@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS kanji_go.idx_kanji_browse_grade;
+DROP INDEX IF EXISTS kanji_go.idx_kanji_browse_strokes;
+DROP INDEX IF EXISTS kanji_go.idx_kanji_browse_frequency;
@@ -0,0 +1,5 @@
+-- Keyset indexes for the kanji browse sort orders. NULLs sort last, so the
+-- expressions must match the ones in models.kanjiSorts exactly.
+CREATE INDEX idx_kanji_browse_frequency ON kanji_go.kanji ((COALESCE(frequency, 2147483647)), kanji_char_id);
+CREATE INDEX idx_kanji_browse_strokes ON kanji_go.kanji ((COALESCE(stroke_count, 32767)), kanji_char_id);
+CREATE INDEX idx_kanji_browse_grade ON kanji_go.kanji ((COALESCE(grade, 32767)), kanji_char_id);
@@ -0,0 +1,65 @@
+-- One row per reading, replacing the comma-joined reading columns for
+-- anything that needs individual readings. The old columns are kept as a
+-- display summary and are still written by the importer.
+CREATE TABLE kanji_go.kanji_readings (
+    reading_id SERIAL PRIMARY KEY,
+    kanji_char_id INT NOT NULL REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE,
+    reading_type VARCHAR(6) NOT NULL CHECK (reading_type IN ('on', 'kun', 'nanori')),
+    position SMALLINT NOT NULL,
+    kana TEXT NOT NULL,                   -- full reading in hiragana: たべる
+    stem TEXT NOT NULL,                   -- the part the kanji spells: た
+    okurigana TEXT NOT NULL DEFAULT '',   -- the kana that follow it: べる
+    is_prefix BOOLEAN NOT NULL DEFAULT FALSE,  -- KANJIDIC "お-"
+    is_suffix BOOLEAN NOT NULL DEFAULT FALSE,  -- KANJIDIC "-び"
+    is_common BOOLEAN NOT NULL DEFAULT FALSE,  -- used by a common JMdict word
+    UNIQUE (kanji_char_id, reading_type, position)
+);
+
+CREATE INDEX idx_kanji_readings_kana ON kanji_go.kanji_readings(kana);
+
+-- Backfill from the comma-joined columns, splitting on commas, 、 and
+-- whitespace like models.ParseReadings. On'yomi may have been entered in
+-- katakana, so everything is folded to hiragana.
+INSERT INTO kanji_go.kanji_readings
+    (kanji_char_id, reading_type, position, kana, stem, okurigana, is_prefix, is_suffix)
+SELECT
+    r.kanji_char_id,
+    r.reading_type,
+    row_number() OVER (PARTITION BY r.kanji_char_id, r.reading_type ORDER BY r.ordinal) - 1,
+    replace(trim(both '-' from r.raw), '.', ''),
+    split_part(trim(both '-' from r.raw), '.', 1),
+    split_part(trim(both '-' from r.raw), '.', 2),
+    r.raw LIKE '%-',
+    r.raw LIKE '-%'
+FROM (
+    SELECT k.kanji_char_id, c.reading_type, s.ordinal,
+           translate(s.raw, 'ァアィイゥウェエォオカガキギクグケゲコゴサザシジスズセゼソゾタダチヂッツヅテデトドナニヌネノハバパヒビピフブプヘベペホボポマミムメモャヤュユョヨラリルレロヮワヰヱヲンヴヵヶ', 'ぁあぃいぅうぇえぉおかがきぎくぐけげこごさざしじすずせぜそぞただちぢっつづてでとどなにぬねのはばぱひびぴふぶぷへべぺほぼぽまみむめもゃやゅゆょよらりるれろゎわゐゑをんゔゕゖ') AS raw
+    FROM kanji_go.kanji k
+    CROSS JOIN LATERAL (VALUES
+        ('on', k.hiragana_onyomi),
+        ('kun', k.hiragana_kunyomi),
+        ('nanori', k.nanori)
+    ) AS c(reading_type, list)
+    CROSS JOIN LATERAL regexp_split_to_table(COALESCE(c.list, ''), '[\s　,、]+')
+        WITH ORDINALITY AS s(raw, ordinal)
+) r
+WHERE trim(both '-' from r.raw) <> '';
+
+-- A reading is common if a common word uses it: kun'yomi must spell the
+-- whole word with its okurigana (食べる たべる), on'yomi only need to appear
+-- in the reading of a compound containing the kanji
+UPDATE kanji_go.kanji_readings r
+SET is_common = TRUE
+FROM kanji_go.kanji k
+WHERE k.kanji_char_id = r.kanji_char_id
+  AND EXISTS (
+    SELECT 1
+    FROM kanji_go.kanji_vocabulary kv
+    JOIN kanji_go.vocabulary v ON v.vocab_id = kv.vocab_id
+    WHERE kv.kanji_char_id = r.kanji_char_id
+      AND v.is_common
+      AND CASE r.reading_type
+            WHEN 'kun' THEN v.written = k.kanji_char || r.okurigana AND v.reading = r.kana
+            ELSE strpos(v.reading, r.kana) > 0
+          END
+  );
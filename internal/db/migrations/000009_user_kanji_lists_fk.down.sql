@@ -0,0 +1,2 @@
+ALTER TABLE kanji_go.user_saved_kanji DROP CONSTRAINT IF EXISTS user_saved_kanji_kanji_char_id_fkey;
+ALTER TABLE kanji_go.user_starred_kanji DROP CONSTRAINT IF EXISTS user_starred_kanji_kanji_char_id_fkey;
@@ -0,0 +1,16 @@
+-- Starred/saved kanji never referenced the kanji table; drop rows for kanji
+-- that no longer exist and let deletes cascade from now on
+DELETE FROM kanji_go.user_starred_kanji s
+WHERE NOT EXISTS (SELECT 1 FROM kanji_go.kanji k WHERE k.kanji_char_id = s.kanji_char_id);
+
+DELETE FROM kanji_go.user_saved_kanji s
+WHERE NOT EXISTS (SELECT 1 FROM kanji_go.kanji k WHERE k.kanji_char_id = s.kanji_char_id);
+
+ALTER TABLE kanji_go.user_starred_kanji
+    ADD CONSTRAINT user_starred_kanji_kanji_char_id_fkey
+    FOREIGN KEY (kanji_char_id) REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE;
+
+ALTER TABLE kanji_go.user_saved_kanji
+    ADD CONSTRAINT user_saved_kanji_kanji_char_id_fkey
+    FOREIGN KEY (kanji_char_id) REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE;
+
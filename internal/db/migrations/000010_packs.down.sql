@@ -0,0 +1,28 @@
+CREATE TABLE kanji_go.user_kanji_packs (
+    user_id INT REFERENCES kanji_go.users(id) ON DELETE CASCADE,
+    pack_name VARCHAR(10) NOT NULL,
+    PRIMARY KEY (user_id, pack_name)
+);
+
+CREATE INDEX idx_user_kanji_packs_user_id ON kanji_go.user_kanji_packs(user_id);
+
+-- Only built-in subscriptions fit the old schema; custom decks are lost
+INSERT INTO kanji_go.user_kanji_packs (user_id, pack_name)
+SELECT s.user_id, p.jlpt_level
+FROM kanji_go.pack_subscriptions s
+JOIN kanji_go.packs p ON p.pack_id = s.pack_id
+WHERE p.jlpt_level IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION kanji_go.add_default_pack()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO kanji_go.user_kanji_packs (user_id, pack_name)
+    VALUES (NEW.id, 'n5');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP VIEW IF EXISTS kanji_go.pack_members;
+DROP TABLE IF EXISTS kanji_go.pack_subscriptions;
+DROP TABLE IF EXISTS kanji_go.pack_kanji;
+DROP TABLE IF EXISTS kanji_go.packs;
@@ -0,0 +1,71 @@
+-- Packs replace the JLPT labels in user_kanji_packs. Built-in packs have no
+-- owner and take their kanji from kanji.jlpt_level; custom decks belong to
+-- a user and list their kanji in pack_kanji.
+CREATE TABLE kanji_go.packs (
+    pack_id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id INT REFERENCES kanji_go.users(id) ON DELETE CASCADE,
+    jlpt_level VARCHAR(2) UNIQUE CHECK (jlpt_level IN ('n1', 'n2', 'n3', 'n4', 'n5')),
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK ((owner_id IS NULL) = (jlpt_level IS NOT NULL))
+);
+
+CREATE TABLE kanji_go.pack_kanji (
+    pack_id INT NOT NULL REFERENCES kanji_go.packs(pack_id) ON DELETE CASCADE,
+    kanji_char_id INT NOT NULL REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    added_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (pack_id, kanji_char_id)
+);
+
+CREATE TABLE kanji_go.pack_subscriptions (
+    user_id INT NOT NULL REFERENCES kanji_go.users(id) ON DELETE CASCADE,
+    pack_id INT NOT NULL REFERENCES kanji_go.packs(pack_id) ON DELETE CASCADE,
+    subscribed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, pack_id)
+);
+
+CREATE INDEX idx_packs_owner_id ON kanji_go.packs(owner_id);
+CREATE INDEX idx_pack_kanji_position ON kanji_go.pack_kanji(pack_id, position);
+CREATE INDEX idx_pack_subscriptions_pack_id ON kanji_go.pack_subscriptions(pack_id);
+
+-- Every pack's kanji in study order; built-in packs go most frequent first
+CREATE VIEW kanji_go.pack_members AS
+SELECT pk.pack_id, pk.kanji_char_id, pk.position
+FROM kanji_go.pack_kanji pk
+UNION ALL
+SELECT p.pack_id, k.kanji_char_id,
+       (ROW_NUMBER() OVER (PARTITION BY p.pack_id
+                           ORDER BY COALESCE(k.frequency, 2147483647), k.kanji_char_id))::INT - 1
+FROM kanji_go.packs p
+JOIN kanji_go.kanji k ON k.jlpt_level = p.jlpt_level
+WHERE p.jlpt_level IS NOT NULL;
+
+INSERT INTO kanji_go.packs (name, description, jlpt_level, is_public) VALUES
+    ('JLPT N5', 'Kanji for the JLPT N5 exam', 'n5', TRUE),
+    ('JLPT N4', 'Kanji for the JLPT N4 exam', 'n4', TRUE),
+    ('JLPT N3', 'Kanji for the JLPT N3 exam', 'n3', TRUE),
+    ('JLPT N2', 'Kanji for the JLPT N2 exam', 'n2', TRUE),
+    ('JLPT N1', 'Kanji for the JLPT N1 exam', 'n1', TRUE);
+
+-- Carry over existing pack choices as subscriptions
+INSERT INTO kanji_go.pack_subscriptions (user_id, pack_id)
+SELECT ukp.user_id, p.pack_id
+FROM kanji_go.user_kanji_packs ukp
+JOIN kanji_go.packs p ON p.jlpt_level = ukp.pack_name
+ON CONFLICT DO NOTHING;
+
+-- New users start subscribed to N5
+CREATE OR REPLACE FUNCTION kanji_go.add_default_pack()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO kanji_go.pack_subscriptions (user_id, pack_id)
+    SELECT NEW.id, pack_id FROM kanji_go.packs WHERE jlpt_level = 'n5';
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE kanji_go.user_kanji_packs;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS kanji_go.quiz_results;
@@ -0,0 +1,16 @@
+-- One row per answered quiz question. prompt and answer keep what was shown
+-- and typed, so results still make sense if the kanji's data changes.
+CREATE TABLE kanji_go.quiz_results (
+    result_id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES kanji_go.users(id) ON DELETE CASCADE,
+    kanji_char_id INT NOT NULL REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE,
+    pack_id INT REFERENCES kanji_go.packs(pack_id) ON DELETE SET NULL,
+    mode VARCHAR(10) NOT NULL CHECK (mode IN ('reading', 'meaning', 'kanji')),
+    prompt TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    is_correct BOOLEAN NOT NULL,
+    answered_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_quiz_results_user_answered ON kanji_go.quiz_results(user_id, answered_at);
+CREATE INDEX idx_quiz_results_user_kanji ON kanji_go.quiz_results(user_id, kanji_char_id);
@@ -0,0 +1 @@
+ALTER TABLE kanji_go.quiz_results DROP COLUMN IF EXISTS is_multiple_choice;
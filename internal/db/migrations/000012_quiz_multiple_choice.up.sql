@@ -0,0 +1,4 @@
+-- Multiple-choice answers are much easier than typed ones, so keep them
+-- apart in any stats
+ALTER TABLE kanji_go.quiz_results
+    ADD COLUMN is_multiple_choice BOOLEAN NOT NULL DEFAULT FALSE;
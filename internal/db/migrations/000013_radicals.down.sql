@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS kanji_go.kanji_components;
+DROP TABLE IF EXISTS kanji_go.components;
+DROP TABLE IF EXISTS kanji_go.radicals;
@@ -0,0 +1,241 @@
+-- The 214 Kangxi radicals. kanji.radical holds the radical number.
+CREATE TABLE kanji_go.radicals (
+    radical_number SMALLINT PRIMARY KEY CHECK (radical_number BETWEEN 1 AND 214),
+    radical_char VARCHAR(4) NOT NULL UNIQUE,
+    stroke_count SMALLINT NOT NULL,
+    meaning TEXT NOT NULL
+);
+
+-- Components are the parts kanji are searched by, from RADKFILE/KRADFILE.
+-- Most are radicals; RADKFILE writes a few radical forms with a stand-in
+-- kanji (化 for 亻), so display holds the form to show.
+CREATE TABLE kanji_go.components (
+    component VARCHAR(4) PRIMARY KEY,
+    display VARCHAR(4) NOT NULL,
+    stroke_count SMALLINT,
+    radical_number SMALLINT REFERENCES kanji_go.radicals(radical_number)
+);
+
+CREATE TABLE kanji_go.kanji_components (
+    kanji_char_id INT NOT NULL REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE,
+    component VARCHAR(4) NOT NULL REFERENCES kanji_go.components(component) ON DELETE CASCADE,
+    PRIMARY KEY (kanji_char_id, component)
+);
+
+CREATE INDEX idx_kanji_components_component ON kanji_go.kanji_components(component, kanji_char_id);
+
+INSERT INTO kanji_go.radicals (radical_number, radical_char, stroke_count, meaning) VALUES
+(1, '一', 1, 'one'),
+(2, '丨', 1, 'line'),
+(3, '丶', 1, 'dot'),
+(4, '丿', 1, 'slash'),
+(5, '乙', 1, 'second'),
+(6, '亅', 1, 'hook'),
+(7, '二', 2, 'two'),
+(8, '亠', 2, 'lid'),
+(9, '人', 2, 'man'),
+(10, '儿', 2, 'legs'),
+(11, '入', 2, 'enter'),
+(12, '八', 2, 'eight'),
+(13, '冂', 2, 'down box'),
+(14, '冖', 2, 'cover'),
+(15, '冫', 2, 'ice'),
+(16, '几', 2, 'table'),
+(17, '凵', 2, 'open box'),
+(18, '刀', 2, 'knife'),
+(19, '力', 2, 'power'),
+(20, '勹', 2, 'wrap'),
+(21, '匕', 2, 'spoon'),
+(22, '匚', 2, 'right open box'),
+(23, '匸', 2, 'hiding enclosure'),
+(24, '十', 2, 'ten'),
+(25, '卜', 2, 'divination'),
+(26, '卩', 2, 'seal'),
+(27, '厂', 2, 'cliff'),
+(28, '厶', 2, 'private'),
+(29, '又', 2, 'again'),
+(30, '口', 3, 'mouth'),
+(31, '囗', 3, 'enclosure'),
+(32, '土', 3, 'earth'),
+(33, '士', 3, 'scholar'),
+(34, '夂', 3, 'go'),
+(35, '夊', 3, 'go slowly'),
+(36, '夕', 3, 'evening'),
+(37, '大', 3, 'big'),
+(38, '女', 3, 'woman'),
+(39, '子', 3, 'child'),
+(40, '宀', 3, 'roof'),
+(41, '寸', 3, 'inch'),
+(42, '小', 3, 'small'),
+(43, '尢', 3, 'lame'),
+(44, '尸', 3, 'corpse'),
+(45, '屮', 3, 'sprout'),
+(46, '山', 3, 'mountain'),
+(47, '巛', 3, 'river'),
+(48, '工', 3, 'work'),
+(49, '己', 3, 'oneself'),
+(50, '巾', 3, 'turban'),
+(51, '干', 3, 'dry'),
+(52, '幺', 3, 'short thread'),
+(53, '广', 3, 'dotted cliff'),
+(54, '廴', 3, 'long stride'),
+(55, '廾', 3, 'two hands'),
+(56, '弋', 3, 'shoot'),
+(57, '弓', 3, 'bow'),
+(58, '彐', 3, 'snout'),
+(59, '彡', 3, 'bristle'),
+(60, '彳', 3, 'step'),
+(61, '心', 4, 'heart'),
+(62, '戈', 4, 'halberd'),
+(63, '戶', 4, 'door'),
+(64, '手', 4, 'hand'),
+(65, '支', 4, 'branch'),
+(66, '攴', 4, 'rap'),
+(67, '文', 4, 'script'),
+(68, '斗', 4, 'dipper'),
+(69, '斤', 4, 'axe'),
+(70, '方', 4, 'square'),
+(71, '无', 4, 'not'),
+(72, '日', 4, 'sun'),
+(73, '曰', 4, 'say'),
+(74, '月', 4, 'moon'),
+(75, '木', 4, 'tree'),
+(76, '欠', 4, 'lack'),
+(77, '止', 4, 'stop'),
+(78, '歹', 4, 'death'),
+(79, '殳', 4, 'weapon'),
+(80, '毋', 4, 'do not'),
+(81, '比', 4, 'compare'),
+(82, '毛', 4, 'fur'),
+(83, '氏', 4, 'clan'),
+(84, '气', 4, 'steam'),
+(85, '水', 4, 'water'),
+(86, '火', 4, 'fire'),
+(87, '爪', 4, 'claw'),
+(88, '父', 4, 'father'),
+(89, '爻', 4, 'double x'),
+(90, '爿', 4, 'half tree trunk'),
+(91, '片', 4, 'slice'),
+(92, '牙', 4, 'fang'),
+(93, '牛', 4, 'cow'),
+(94, '犬', 4, 'dog'),
+(95, '玄', 5, 'profound'),
+(96, '玉', 5, 'jade'),
+(97, '瓜', 5, 'melon'),
+(98, '瓦', 5, 'tile'),
+(99, '甘', 5, 'sweet'),
+(100, '生', 5, 'life'),
+(101, '用', 5, 'use'),
+(102, '田', 5, 'field'),
+(103, '疋', 5, 'bolt of cloth'),
+(104, '疒', 5, 'sickness'),
+(105, '癶', 5, 'dotted tent'),
+(106, '白', 5, 'white'),
+(107, '皮', 5, 'skin'),
+(108, '皿', 5, 'dish'),
+(109, '目', 5, 'eye'),
+(110, '矛', 5, 'spear'),
+(111, '矢', 5, 'arrow'),
+(112, '石', 5, 'stone'),
+(113, '示', 5, 'spirit'),
+(114, '禸', 5, 'track'),
+(115, '禾', 5, 'grain'),
+(116, '穴', 5, 'cave'),
+(117, '立', 5, 'stand'),
+(118, '竹', 6, 'bamboo'),
+(119, '米', 6, 'rice'),
+(120, '糸', 6, 'silk'),
+(121, '缶', 6, 'jar'),
+(122, '网', 6, 'net'),
+(123, '羊', 6, 'sheep'),
+(124, '羽', 6, 'feather'),
+(125, '老', 6, 'old'),
+(126, '而', 6, 'and'),
+(127, '耒', 6, 'plow'),
+(128, '耳', 6, 'ear'),
+(129, '聿', 6, 'brush'),
+(130, '肉', 6, 'meat'),
+(131, '臣', 6, 'minister'),
+(132, '自', 6, 'self'),
+(133, '至', 6, 'arrive'),
+(134, '臼', 6, 'mortar'),
+(135, '舌', 6, 'tongue'),
+(136, '舛', 6, 'oppose'),
+(137, '舟', 6, 'boat'),
+(138, '艮', 6, 'stopping'),
+(139, '色', 6, 'color'),
+(140, '艸', 6, 'grass'),
+(141, '虍', 6, 'tiger'),
+(142, '虫', 6, 'insect'),
+(143, '血', 6, 'blood'),
+(144, '行', 6, 'walk enclosure'),
+(145, '衣', 6, 'clothes'),
+(146, '襾', 6, 'west'),
+(147, '見', 7, 'see'),
+(148, '角', 7, 'horn'),
+(149, '言', 7, 'speech'),
+(150, '谷', 7, 'valley'),
+(151, '豆', 7, 'bean'),
+(152, '豕', 7, 'pig'),
+(153, '豸', 7, 'badger'),
+(154, '貝', 7, 'shell'),
+(155, '赤', 7, 'red'),
+(156, '走', 7, 'run'),
+(157, '足', 7, 'foot'),
+(158, '身', 7, 'body'),
+(159, '車', 7, 'cart'),
+(160, '辛', 7, 'bitter'),
+(161, '辰', 7, 'morning'),
+(162, '辵', 7, 'walk'),
+(163, '邑', 7, 'city'),
+(164, '酉', 7, 'wine'),
+(165, '釆', 7, 'distinguish'),
+(166, '里', 7, 'village'),
+(167, '金', 8, 'gold'),
+(168, '長', 8, 'long'),
+(169, '門', 8, 'gate'),
+(170, '阜', 8, 'mound'),
+(171, '隶', 8, 'slave'),
+(172, '隹', 8, 'short tailed bird'),
+(173, '雨', 8, 'rain'),
+(174, '靑', 8, 'blue'),
+(175, '非', 8, 'wrong'),
+(176, '面', 9, 'face'),
+(177, '革', 9, 'leather'),
+(178, '韋', 9, 'tanned leather'),
+(179, '韭', 9, 'leek'),
+(180, '音', 9, 'sound'),
+(181, '頁', 9, 'leaf'),
+(182, '風', 9, 'wind'),
+(183, '飛', 9, 'fly'),
+(184, '食', 9, 'eat'),
+(185, '首', 9, 'head'),
+(186, '香', 9, 'fragrant'),
+(187, '馬', 10, 'horse'),
+(188, '骨', 10, 'bone'),
+(189, '高', 10, 'tall'),
+(190, '髟', 10, 'hair'),
+(191, '鬥', 10, 'fight'),
+(192, '鬯', 10, 'sacrificial wine'),
+(193, '鬲', 10, 'cauldron'),
+(194, '鬼', 10, 'ghost'),
+(195, '魚', 11, 'fish'),
+(196, '鳥', 11, 'bird'),
+(197, '鹵', 11, 'salt'),
+(198, '鹿', 11, 'deer'),
+(199, '麥', 11, 'wheat'),
+(200, '麻', 11, 'hemp'),
+(201, '黃', 12, 'yellow'),
+(202, '黍', 12, 'millet'),
+(203, '黑', 12, 'black'),
+(204, '黹', 12, 'embroidery'),
+(205, '黽', 13, 'frog'),
+(206, '鼎', 13, 'tripod'),
+(207, '鼓', 13, 'drum'),
+(208, '鼠', 13, 'rat'),
+(209, '鼻', 14, 'nose'),
+(210, '齊', 14, 'even'),
+(211, '齒', 15, 'tooth'),
+(212, '龍', 16, 'dragon'),
+(213, '龜', 16, 'turtle'),
+(214, '龠', 17, 'flute');
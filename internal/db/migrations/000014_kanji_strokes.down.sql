@@ -0,0 +1 @@
+DROP TABLE IF EXISTS kanji_go.kanji_strokes;
@@ -0,0 +1,11 @@
+-- Stroke order from KanjiVG: one SVG path per stroke, in writing order,
+-- with where KanjiVG places the stroke's number
+CREATE TABLE kanji_go.kanji_strokes (
+    kanji_char_id INT NOT NULL REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE CASCADE,
+    stroke_number SMALLINT NOT NULL CHECK (stroke_number > 0),
+    stroke_type VARCHAR(16) NOT NULL DEFAULT '', -- CJK stroke character(s), e.g. ㇐
+    path TEXT NOT NULL,                          -- SVG path data on a 109x109 canvas
+    label_x REAL,
+    label_y REAL,
+    PRIMARY KEY (kanji_char_id, stroke_number)
+);
@@ -0,0 +1,20 @@
+-- One row per uploaded image. object_key is the full-size object; the other
+-- sizes sit beside it. content_hash and size_bytes describe the file as
+-- uploaded, width and height the stored full size. Rows are written as
+-- 'pending' before the objects and become 'stored' once every size is in
+-- the bucket, so a pending row left behind marks objects that may be orphaned.
+CREATE TABLE kanji_go.uploads (
+    upload_id BIGSERIAL PRIMARY KEY,
+    object_key VARCHAR(512) NOT NULL UNIQUE,
+    owner_id INT REFERENCES kanji_go.users(id) ON DELETE SET NULL,
+    kanji_char_id INT REFERENCES kanji_go.kanji(kanji_char_id) ON DELETE SET NULL,
+    content_hash CHAR(64) NOT NULL, -- hex SHA-256
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    width INT NOT NULL CHECK (width > 0),
+    height INT NOT NULL CHECK (height > 0),
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'stored')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_uploads_owner ON kanji_go.uploads(owner_id);
+CREATE INDEX idx_uploads_kanji ON kanji_go.uploads(kanji_char_id);
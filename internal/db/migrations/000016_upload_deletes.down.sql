@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS kanji_go.upload_audit;
+
+DROP INDEX IF EXISTS kanji_go.idx_uploads_deleted_at;
+
+-- Soft-deleted rows can't be represented any more
+DELETE FROM kanji_go.uploads WHERE status = 'deleted';
+
+ALTER TABLE kanji_go.uploads
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP CONSTRAINT uploads_status_check,
+    ADD CONSTRAINT uploads_status_check CHECK (status IN ('pending', 'stored'));
+
+ALTER TABLE kanji_go.users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,27 @@
+-- Admins may delete any user's uploads
+ALTER TABLE kanji_go.users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Deleted uploads keep their objects for a retention window before the
+-- purge job removes them
+ALTER TABLE kanji_go.uploads
+    DROP CONSTRAINT uploads_status_check,
+    ADD CONSTRAINT uploads_status_check CHECK (status IN ('pending', 'stored', 'deleted')),
+    ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN deleted_by INT REFERENCES kanji_go.users(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_uploads_deleted_at ON kanji_go.uploads(deleted_at) WHERE status = 'deleted';
+
+-- Who deleted which upload. object_key and actor_name are copied so entries
+-- outlive both the upload row and the user.
+CREATE TABLE kanji_go.upload_audit (
+    audit_id BIGSERIAL PRIMARY KEY,
+    upload_id BIGINT REFERENCES kanji_go.uploads(upload_id) ON DELETE SET NULL,
+    object_key VARCHAR(512) NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('delete', 'purge')),
+    actor_id INT REFERENCES kanji_go.users(id) ON DELETE SET NULL,
+    actor_name VARCHAR(255),                 -- NULL for the purge job
+    as_admin BOOLEAN NOT NULL DEFAULT FALSE, -- the upload wasn't the actor's
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_upload_audit_object_key ON kanji_go.upload_audit(object_key);
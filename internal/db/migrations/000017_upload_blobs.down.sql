@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS kanji_go.idx_uploads_owner_object;
+
+ALTER TABLE kanji_go.uploads DROP CONSTRAINT IF EXISTS uploads_object_key_fkey;
+
+DROP TABLE IF EXISTS kanji_go.upload_blobs;
+
+-- Object keys are unique again, so only the oldest reference to a shared
+-- object survives
+DELETE FROM kanji_go.uploads u
+USING kanji_go.uploads o
+WHERE u.object_key = o.object_key AND u.upload_id > o.upload_id;
+
+ALTER TABLE kanji_go.uploads ADD CONSTRAINT uploads_object_key_key UNIQUE (object_key);
@@ -0,0 +1,26 @@
+-- Uploaded images are stored once per content hash. Each uploads row is now
+-- one user's reference to a blob, and ref_count counts those rows; the
+-- objects are deleted when it drops to zero.
+CREATE TABLE kanji_go.upload_blobs (
+    object_key VARCHAR(512) PRIMARY KEY, -- the full-size object
+    content_hash CHAR(64) NOT NULL,      -- hex SHA-256 of the file as uploaded
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    width INT NOT NULL CHECK (width > 0),
+    height INT NOT NULL CHECK (height > 0),
+    ref_count INT NOT NULL CHECK (ref_count >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_upload_blobs_content_hash ON kanji_go.upload_blobs(content_hash, created_at);
+
+-- Uploads from before deduplication each keep their own blob
+INSERT INTO kanji_go.upload_blobs (object_key, content_hash, size_bytes, width, height, ref_count, created_at)
+SELECT object_key, content_hash, size_bytes, width, height, 1, created_at
+FROM kanji_go.uploads;
+
+ALTER TABLE kanji_go.uploads
+    DROP CONSTRAINT uploads_object_key_key,
+    ADD CONSTRAINT uploads_object_key_fkey FOREIGN KEY (object_key) REFERENCES kanji_go.upload_blobs(object_key);
+
+-- A user holds at most one live reference to each blob
+CREATE UNIQUE INDEX idx_uploads_owner_object ON kanji_go.uploads(owner_id, object_key) WHERE status <> 'deleted';
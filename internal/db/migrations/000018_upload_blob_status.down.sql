@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS kanji_go.idx_uploads_object_key;
+
+DROP INDEX IF EXISTS kanji_go.idx_upload_blobs_content_hash;
+CREATE INDEX idx_upload_blobs_content_hash ON kanji_go.upload_blobs(content_hash, created_at);
+
+ALTER TABLE kanji_go.upload_blobs DROP COLUMN IF EXISTS status;
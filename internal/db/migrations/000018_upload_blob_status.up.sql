@@ -0,0 +1,13 @@
+-- A blob is 'pending' until one of its uploads has written every size, and
+-- only stored blobs are reused for identical uploads. Existing blobs were
+-- recorded once their objects were written.
+ALTER TABLE kanji_go.upload_blobs
+    ADD COLUMN status VARCHAR(10) NOT NULL DEFAULT 'stored' CHECK (status IN ('pending', 'stored'));
+
+ALTER TABLE kanji_go.upload_blobs ALTER COLUMN status SET DEFAULT 'pending';
+
+DROP INDEX IF EXISTS kanji_go.idx_upload_blobs_content_hash;
+CREATE INDEX idx_upload_blobs_content_hash ON kanji_go.upload_blobs(content_hash, created_at) WHERE status = 'stored';
+
+-- The orphan collector looks up the newest reference to each blob
+CREATE INDEX idx_uploads_object_key ON kanji_go.uploads(object_key, created_at);
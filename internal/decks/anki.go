@@ -0,0 +1,353 @@
+package decks
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// MediaSource opens a mnemonic image so it can be packed into an .apkg.
+// It returns ok false for URLs it can't serve (external links), which are
+// left as remote images.
+type MediaSource func(ctx context.Context, url string) (r io.ReadCloser, name string, ok bool, err error)
+
+// ankiSchema creates an Anki 2.1 collection (schema version 11)
+const ankiSchema = `
+CREATE TABLE col (
+    id integer primary key, crt integer not null, mod integer not null, scm integer not null,
+    ver integer not null, dty integer not null, usn integer not null, ls integer not null,
+    conf text not null, models text not null, decks text not null, dconf text not null, tags text not null
+);
+CREATE TABLE notes (
+    id integer primary key, guid text not null, mid integer not null, mod integer not null,
+    usn integer not null, tags text not null, flds text not null, sfld integer not null,
+    csum integer not null, flags integer not null, data text not null
+);
+CREATE TABLE cards (
+    id integer primary key, nid integer not null, did integer not null, ord integer not null,
+    mod integer not null, usn integer not null, type integer not null, queue integer not null,
+    due integer not null, ivl integer not null, factor integer not null, reps integer not null,
+    lapses integer not null, left integer not null, odue integer not null, odid integer not null,
+    flags integer not null, data text not null
+);
+CREATE TABLE revlog (
+    id integer primary key, cid integer not null, usn integer not null, ease integer not null,
+    ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null,
+    type integer not null
+);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn ON notes (usn);
+CREATE INDEX ix_cards_usn ON cards (usn);
+CREATE INDEX ix_revlog_usn ON revlog (usn);
+CREATE INDEX ix_cards_nid ON cards (nid);
+CREATE INDEX ix_cards_sched ON cards (did, queue, due);
+CREATE INDEX ix_revlog_cid ON revlog (cid);
+CREATE INDEX ix_notes_csum ON notes (csum);
+`
+
+// ankiFields are the note type's fields, in order
+var ankiFields = []string{"Kanji", "Meanings", "Onyomi", "Kunyomi", "JLPT", "Mnemonic"}
+
+// ankiCSS styles the exported cards
+const ankiCSS = `.card { font-family: sans-serif; font-size: 20px; text-align: center; }
+.kanji { font-size: 96px; }
+.mnemonic { font-size: 16px; text-align: left; margin-top: 1em; }
+.mnemonic img { max-height: 240px; }`
+
+// Anki card types and queues
+const (
+	ankiNew    = 0
+	ankiReview = 2
+)
+
+// WriteAPKG writes the deck as an Anki package: a zip holding an SQLite
+// collection with one note and card per kanji, plus any mnemonic images
+// media can open. SRS progress becomes Anki review scheduling; kanji still
+// in learning are exported as due reviews, since Anki's learning steps
+// have no equivalent here.
+func WriteAPKG(ctx context.Context, w io.Writer, d *Deck, media MediaSource) error {
+	dir, err := os.MkdirTemp("", "apkg-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	zw := zip.NewWriter(w)
+
+	// Images go in the zip as "0", "1", ... and the "media" file maps
+	// those names back to the file names the notes refer to
+	mediaNames := map[string]string{}
+	embed := func(url string) (string, error) {
+		if media == nil || url == "" {
+			return url, nil
+		}
+		r, name, ok, err := media(ctx, url)
+		if err != nil || !ok {
+			return url, err
+		}
+		defer r.Close()
+
+		idx := strconv.Itoa(len(mediaNames))
+		name = idx + "-" + filepath.Base(name)
+		fw, err := zw.Create(idx)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(fw, r); err != nil {
+			return "", fmt.Errorf("failed to copy media %s: %w", url, err)
+		}
+		mediaNames[idx] = name
+		return name, nil
+	}
+
+	path := filepath.Join(dir, "collection.anki2")
+	if err := writeCollection(ctx, path, d, embed); err != nil {
+		return err
+	}
+
+	fw, err := zw.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to pack collection: %w", err)
+	}
+
+	fw, err = zw.Create("media")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(fw).Encode(mediaNames); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeCollection creates the SQLite collection at path
+func writeCollection(ctx context.Context, path string, d *Deck, embed func(string) (string, error)) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, ankiSchema); err != nil {
+		return fmt.Errorf("failed to create collection schema: %w", err)
+	}
+
+	now := d.ExportedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// Anki counts review due dates in days from the collection's creation,
+	// so start it on the day of the earliest due card
+	y, m, day := now.UTC().Date()
+	crt := time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+	for _, e := range d.Entries {
+		if e.Progress != nil && e.Progress.DueAt.Before(crt) {
+			y, m, day := e.Progress.DueAt.UTC().Date()
+			crt = time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+		}
+	}
+
+	modelID := now.UnixMilli()
+	deckID := modelID + 1
+	if err := writeCollectionRow(ctx, tx, d, crt, now, modelID, deckID); err != nil {
+		return err
+	}
+
+	for i, e := range d.Entries {
+		flds, err := ankiNoteFields(e, embed)
+		if err != nil {
+			return err
+		}
+
+		noteID := modelID + 10 + int64(2*i)
+		cardID := noteID + 1
+		_, err = tx.ExecContext(ctx, `INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, ankiGUID(d.Name, e.Kanji), modelID, now.Unix(), ankiTags(e),
+			strings.Join(flds, "\x1f"), e.Kanji, ankiChecksum(e.Kanji))
+		if err != nil {
+			return fmt.Errorf("failed to insert note: %w", err)
+		}
+
+		cardType, due, ivl, factor, reps, lapses := ankiNew, i, 0, 2500, 0, 0
+		if p := e.Progress; p != nil {
+			cardType = ankiReview
+			due = int(p.DueAt.Sub(crt).Hours() / 24)
+			ivl = max(p.IntervalDays, 1)
+			factor = int(p.EaseFactor * 1000)
+			reps, lapses = p.Repetitions, p.Lapses
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')`,
+			cardID, noteID, deckID, now.Unix(), cardType, cardType, due, ivl, factor, reps, lapses)
+		if err != nil {
+			return fmt.Errorf("failed to insert card: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit collection: %w", err)
+	}
+	return nil
+}
+
+// writeCollectionRow inserts the col row with the note type and deck
+func writeCollectionRow(ctx context.Context, tx *sql.Tx, d *Deck, crt, now time.Time, modelID, deckID int64) error {
+	flds := make([]map[string]any, len(ankiFields))
+	for i, name := range ankiFields {
+		flds[i] = map[string]any{
+			"name": name, "ord": i, "sticky": false, "rtl": false,
+			"font": "Arial", "size": 20, "media": []any{},
+		}
+	}
+
+	models := map[string]any{
+		strconv.FormatInt(modelID, 10): map[string]any{
+			"id": modelID, "name": "Kanji Go", "type": 0, "mod": now.Unix(), "usn": -1,
+			"sortf": 0, "did": deckID, "flds": flds, "css": ankiCSS,
+			"tmpls": []map[string]any{{
+				"name": "Recognition", "ord": 0, "did": nil, "bqfmt": "", "bafmt": "",
+				"qfmt": `<div class="kanji">{{Kanji}}</div>`,
+				"afmt": `{{FrontSide}}<hr id="answer">{{Meanings}}<br>{{Onyomi}}<br>{{Kunyomi}}` +
+					`<div class="mnemonic">{{Mnemonic}}</div>`,
+			}},
+			"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+			"latexPost": "\\end{document}",
+			"req":       []any{[]any{0, "any", []int{0}}},
+			"tags":      []string{}, "vers": []any{},
+		},
+	}
+
+	deck := func(id int64, name, desc string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "desc": desc, "mod": now.Unix(), "usn": -1,
+			"collapsed": false, "browserCollapsed": false, "dyn": 0, "conf": 1,
+			"extendNew": 0, "extendRev": 0,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+	name := d.Name
+	if name == "" {
+		name = "Kanji Go"
+	}
+	decks := map[string]any{
+		"1":                           deck(1, "Default", ""),
+		strconv.FormatInt(deckID, 10): deck(deckID, name, html.EscapeString(d.Description)),
+	}
+
+	dconf := map[string]any{
+		"1": map[string]any{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true,
+			"timer": 0, "replayq": true, "dyn": false,
+			"new": map[string]any{
+				"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500,
+				"order": 1, "perDay": 20, "bury": true, "separate": true,
+			},
+			"rev": map[string]any{
+				"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "maxIvl": 36500,
+				"ivlFct": 1, "bury": true, "minSpace": 1,
+			},
+			"lapse": map[string]any{
+				"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0,
+			},
+		},
+	}
+
+	conf := map[string]any{
+		"nextPos": len(d.Entries), "estTimes": true, "activeDecks": []int64{deckID},
+		"sortType": "noteFld", "timeLim": 0, "sortBackwards": false, "addToCur": true,
+		"curDeck": deckID, "newSpread": 0, "dueCounts": true, "curModel": modelID,
+		"collapseTime": 1200,
+	}
+
+	values := make([]string, 4)
+	for i, v := range []any{conf, models, decks, dconf} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		values[i] = string(b)
+	}
+
+	_, err := tx.ExecContext(ctx, `INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		crt.Unix(), now.UnixMilli(), now.UnixMilli(), values[0], values[1], values[2], values[3])
+	if err != nil {
+		return fmt.Errorf("failed to insert collection: %w", err)
+	}
+	return nil
+}
+
+// ankiNoteFields renders an entry's note fields as HTML
+func ankiNoteFields(e Entry, embed func(string) (string, error)) ([]string, error) {
+	var mnemonic strings.Builder
+	for _, m := range e.Mnemonics {
+		mnemonic.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(m.Explanation), "\n", "<br>") + "</p>")
+		if m.ImageURL != "" {
+			src, err := embed(m.ImageURL)
+			if err != nil {
+				return nil, err
+			}
+			mnemonic.WriteString(`<img src="` + html.EscapeString(src) + `">`)
+		}
+	}
+
+	return []string{
+		html.EscapeString(e.Kanji),
+		html.EscapeString(strings.Join(e.Meanings, ", ")),
+		html.EscapeString(strings.Join(e.Onyomi, "、")),
+		html.EscapeString(strings.Join(e.Kunyomi, "、")),
+		strings.ToUpper(e.JLPTLevel),
+		mnemonic.String(),
+	}, nil
+}
+
+// ankiTags returns the space-separated tags for a note
+func ankiTags(e Entry) string {
+	if e.JLPTLevel == "" {
+		return " kanji_go "
+	}
+	return " kanji_go jlpt_" + e.JLPTLevel + " "
+}
+
+// ankiGUID is stable per deck name and kanji, so importing a newer export
+// into Anki updates the notes instead of duplicating them
+func ankiGUID(deck, kanji string) string {
+	sum := sha1.Sum([]byte("kanji_go:" + deck + ":" + kanji))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ankiChecksum is Anki's duplicate check: the first 8 hex digits of the
+// SHA-1 of the sort field
+func ankiChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
@@ -0,0 +1,272 @@
+package decks
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mapping says which CSV columns hold what. Columns are header names (case
+// insensitive) or 1-based numbers. Progress is read from the columns
+// WriteCSV writes (srs_state, due_at, ...) when the header has them.
+type Mapping struct {
+	Header   bool   // the first row is a header
+	Kanji    string // required; defaults to "kanji", or column 1 without a header
+	Mnemonic string // optional
+}
+
+// csvColumns are the columns WriteCSV writes, in order
+var csvColumns = []string{
+	"kanji", "meanings", "onyomi", "kunyomi", "jlpt_level",
+	"srs_state", "due_at", "interval_days", "ease_factor", "repetitions", "lapses", "last_reviewed_at",
+	"mnemonic",
+}
+
+// progressColumns locates the progress columns in a header; -1 when absent
+type progressColumns struct {
+	state, dueAt, interval, ease, repetitions, lapses, lastReviewed int
+}
+
+// findProgressColumns looks up the progress columns by their WriteCSV
+// names. ok is false unless the header at least has srs_state.
+func findProgressColumns(header []string) (progressColumns, bool) {
+	find := func(name string) int {
+		i, err := column(name, header)
+		if err != nil {
+			return -1
+		}
+		return i
+	}
+	cols := progressColumns{
+		state:        find("srs_state"),
+		dueAt:        find("due_at"),
+		interval:     find("interval_days"),
+		ease:         find("ease_factor"),
+		repetitions:  find("repetitions"),
+		lapses:       find("lapses"),
+		lastReviewed: find("last_reviewed_at"),
+	}
+	return cols, cols.state >= 0
+}
+
+// readProgress parses a row's progress columns. It returns nil for a row
+// without an SRS state, and a reason when a value can't be read.
+func readProgress(rec []string, cols progressColumns) (*Progress, string) {
+	state := strings.TrimSpace(cell(rec, cols.state))
+	if state == "" {
+		return nil, ""
+	}
+	p := &Progress{State: state}
+
+	var err error
+	if v := strings.TrimSpace(cell(rec, cols.dueAt)); v != "" {
+		if p.DueAt, err = parseTime(v); err != nil {
+			return nil, fmt.Sprintf("bad due_at %q; progress skipped", v)
+		}
+	}
+	if v := strings.TrimSpace(cell(rec, cols.lastReviewed)); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return nil, fmt.Sprintf("bad last_reviewed_at %q; progress skipped", v)
+		}
+		p.LastReviewedAt = &t
+	}
+	if v := strings.TrimSpace(cell(rec, cols.ease)); v != "" {
+		if p.EaseFactor, err = strconv.ParseFloat(v, 64); err != nil {
+			return nil, fmt.Sprintf("bad ease_factor %q; progress skipped", v)
+		}
+	}
+	for _, f := range []struct {
+		name string
+		col  int
+		dst  *int
+	}{
+		{"interval_days", cols.interval, &p.IntervalDays},
+		{"repetitions", cols.repetitions, &p.Repetitions},
+		{"lapses", cols.lapses, &p.Lapses},
+	} {
+		if v := strings.TrimSpace(cell(rec, f.col)); v != "" {
+			if *f.dst, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Sprintf("bad %s %q; progress skipped", f.name, v)
+			}
+		}
+	}
+	return p, ""
+}
+
+// parseTime reads an RFC 3339 time or a bare date
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// ReadCSV reads a CSV (comma ',') or TSV (comma '\t') deck. Rows without a
+// usable kanji are reported as problems rather than failing the import.
+func ReadCSV(r io.Reader, comma rune, m Mapping) (*Deck, []Problem, error) {
+	cr := csv.NewReader(io.LimitReader(r, MaxImportSize))
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+
+	var header []string
+	if m.Header {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return &Deck{}, nil, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		header = append([]string(nil), rec...)
+	}
+
+	kanjiSpec := m.Kanji
+	if kanjiSpec == "" {
+		kanjiSpec = "kanji"
+		if !m.Header {
+			kanjiSpec = "1"
+		}
+	}
+	kanjiCol, err := column(kanjiSpec, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	mnemonicCol := -1
+	if m.Mnemonic != "" {
+		if mnemonicCol, err = column(m.Mnemonic, header); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	progressCols, hasProgress := findProgressColumns(header)
+
+	deck := &Deck{}
+	progressProblems := make(map[int]Problem)
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(deck.Entries) >= MaxImportRows {
+			return nil, nil, fmt.Errorf("too many rows (at most %d)", MaxImportRows)
+		}
+		if isBlank(rec) {
+			continue
+		}
+
+		e := Entry{Line: line, Kanji: cell(rec, kanjiCol)}
+		if text := strings.TrimSpace(cell(rec, mnemonicCol)); text != "" {
+			e.Mnemonics = []Mnemonic{{Explanation: text}}
+		}
+		if hasProgress {
+			var reason string
+			if e.Progress, reason = readProgress(rec, progressCols); reason != "" {
+				progressProblems[line] = Problem{Line: line, Value: strings.TrimSpace(e.Kanji), Reason: reason}
+			}
+		}
+		deck.Entries = append(deck.Entries, e)
+	}
+
+	var problems []Problem
+	deck.Entries, problems = validate(deck.Entries)
+
+	// Only report bad progress on rows that are imported
+	for _, e := range deck.Entries {
+		if p, ok := progressProblems[e.Line]; ok {
+			problems = append(problems, p)
+		}
+	}
+	return deck, problems, nil
+}
+
+// WriteCSV writes the deck with one row per kanji and a header row.
+// Readings are joined with "、", meanings with "; ", and only the first
+// mnemonic is included.
+func WriteCSV(w io.Writer, comma rune, d *Deck) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	if err := cw.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, e := range d.Entries {
+		row := []string{
+			e.Kanji,
+			strings.Join(e.Meanings, "; "),
+			strings.Join(e.Onyomi, "、"),
+			strings.Join(e.Kunyomi, "、"),
+			e.JLPTLevel,
+			"", "", "", "", "", "", "",
+			"",
+		}
+		if p := e.Progress; p != nil {
+			row[5] = p.State
+			row[6] = p.DueAt.UTC().Format(time.RFC3339)
+			row[7] = strconv.Itoa(p.IntervalDays)
+			row[8] = strconv.FormatFloat(p.EaseFactor, 'f', 2, 64)
+			row[9] = strconv.Itoa(p.Repetitions)
+			row[10] = strconv.Itoa(p.Lapses)
+			if p.LastReviewedAt != nil {
+				row[11] = p.LastReviewedAt.UTC().Format(time.RFC3339)
+			}
+		}
+		if len(e.Mnemonics) > 0 {
+			row[12] = e.Mnemonics[0].Explanation
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// column resolves a column spec against the header
+func column(spec string, header []string) (int, error) {
+	spec = strings.TrimSpace(spec)
+	if n, err := strconv.Atoi(spec); err == nil {
+		if n < 1 {
+			return 0, fmt.Errorf("column numbers start at 1, got %d", n)
+		}
+		return n - 1, nil
+	}
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), spec) {
+			return i, nil
+		}
+	}
+	if header == nil {
+		return 0, fmt.Errorf("column %q needs a header row, or use a column number", spec)
+	}
+	return 0, fmt.Errorf("no column named %q", spec)
+}
+
+// cell returns a record's field, or "" when the row is too short
+func cell(rec []string, i int) string {
+	if i < 0 || i >= len(rec) {
+		return ""
+	}
+	return rec[i]
+}
+
+// isBlank reports whether every field of a record is empty
+func isBlank(rec []string) bool {
+	for _, f := range rec {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
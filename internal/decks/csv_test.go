@@ -0,0 +1,192 @@
+package decks
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// kanjiOf returns the kanji of each entry
+func kanjiOf(d *Deck) []string {
+	var out []string
+	for _, e := range d.Entries {
+		out = append(out, e.Kanji)
+	}
+	return out
+}
+
+func TestReadCSVMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		comma    rune
+		mapping  Mapping
+		kanji    []string
+		mnemonic string // of the first entry
+	}{
+		{
+			name:    "default header column",
+			in:      "Kanji,Meaning\n日,sun\n本,book\n",
+			comma:   ',',
+			mapping: Mapping{Header: true},
+			kanji:   []string{"日", "本"},
+		},
+		{
+			name:     "named columns, any case",
+			in:       "Meaning,KANJI,Note\nsun,日,a sun\n",
+			comma:    ',',
+			mapping:  Mapping{Header: true, Kanji: "kanji", Mnemonic: "note"},
+			kanji:    []string{"日"},
+			mnemonic: "a sun",
+		},
+		{
+			name:    "first column without a header",
+			in:      "日\t1\n月\t2\n",
+			comma:   '\t',
+			mapping: Mapping{},
+			kanji:   []string{"日", "月"},
+		},
+		{
+			name:     "numbered columns",
+			in:       "sun,日,day mnemonic\nmoon,月\n",
+			comma:    ',',
+			mapping:  Mapping{Kanji: "2", Mnemonic: "3"},
+			kanji:    []string{"日", "月"},
+			mnemonic: "day mnemonic",
+		},
+		{
+			name:    "blank rows are skipped",
+			in:      "kanji\n\n日\n,\n月\n",
+			comma:   ',',
+			mapping: Mapping{Header: true},
+			kanji:   []string{"日", "月"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, problems, err := ReadCSV(strings.NewReader(tt.in), tt.comma, tt.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(problems) != 0 {
+				t.Errorf("problems = %+v", problems)
+			}
+			if got := kanjiOf(d); !reflect.DeepEqual(got, tt.kanji) {
+				t.Errorf("kanji = %v, want %v", got, tt.kanji)
+			}
+			var mnemonic string
+			if len(d.Entries) > 0 && len(d.Entries[0].Mnemonics) > 0 {
+				mnemonic = d.Entries[0].Mnemonics[0].Explanation
+			}
+			if mnemonic != tt.mnemonic {
+				t.Errorf("mnemonic = %q, want %q", mnemonic, tt.mnemonic)
+			}
+		})
+	}
+}
+
+func TestReadCSVMappingErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping Mapping
+	}{
+		{"unknown column", Mapping{Header: true, Kanji: "character"}},
+		{"name without header", Mapping{Kanji: "kanji"}},
+		{"column zero", Mapping{Kanji: "0"}},
+		{"unknown mnemonic column", Mapping{Header: true, Mnemonic: "story"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ReadCSV(strings.NewReader("kanji\n日\n"), ',', tt.mapping); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestReadCSVProblems(t *testing.T) {
+	in := "kanji\n日\n日本\nabc\n\"\"\n日\n月\n"
+	d, problems, err := ReadCSV(strings.NewReader(in), ',', Mapping{Header: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := kanjiOf(d); !reflect.DeepEqual(got, []string{"日", "月"}) {
+		t.Errorf("kanji = %v", got)
+	}
+	want := []Problem{
+		{Line: 3, Value: "日本", Reason: "expected a single kanji"},
+		{Line: 4, Value: "abc", Reason: "expected a single kanji"},
+		{Line: 6, Value: "日", Reason: "duplicate of row 2"},
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %+v, want %+v", problems, want)
+	}
+}
+
+func TestCSVProgressRoundTrip(t *testing.T) {
+	due := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	reviewed := due.AddDate(0, 0, -4)
+	in := &Deck{Entries: []Entry{
+		{Kanji: "日", Meanings: []string{"sun", "day"}, Onyomi: []string{"にち", "じつ"},
+			Progress: &Progress{State: "review", DueAt: due, IntervalDays: 4, EaseFactor: 2.35,
+				Repetitions: 3, Lapses: 1, LastReviewedAt: &reviewed},
+			Mnemonics: []Mnemonic{{Explanation: "a \"sun\"\nover the line"}}},
+		{Kanji: "月"},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, ',', in); err != nil {
+		t.Fatal(err)
+	}
+	out, problems, err := ReadCSV(&buf, ',', Mapping{Header: true, Mnemonic: "mnemonic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("problems = %+v", problems)
+	}
+	if len(out.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(out.Entries))
+	}
+	if got, want := out.Entries[0].Progress, in.Entries[0].Progress; !reflect.DeepEqual(got, want) {
+		t.Errorf("progress = %+v, want %+v", got, want)
+	}
+	if got := out.Entries[0].Mnemonics; len(got) != 1 || got[0].Explanation != in.Entries[0].Mnemonics[0].Explanation {
+		t.Errorf("mnemonics = %+v", got)
+	}
+	if out.Entries[1].Progress != nil {
+		t.Errorf("progress for a kanji without any = %+v", out.Entries[1].Progress)
+	}
+}
+
+func TestReadCSVBadProgress(t *testing.T) {
+	in := "kanji,srs_state,due_at,interval_days\n" +
+		"日,review,yesterday,3\n" +
+		"月,review,2026-03-01,x\n" +
+		"火,learning,2026-03-01,\n" +
+		"火,review,never,\n"
+	d, problems, err := ReadCSV(strings.NewReader(in), ',', Mapping{Header: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := kanjiOf(d); !reflect.DeepEqual(got, []string{"日", "月", "火"}) {
+		t.Errorf("kanji = %v", got)
+	}
+	if d.Entries[0].Progress != nil || d.Entries[1].Progress != nil {
+		t.Error("unreadable progress was kept")
+	}
+	if p := d.Entries[2].Progress; p == nil || p.State != "learning" || !p.DueAt.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("progress = %+v", p)
+	}
+	// The duplicate 火 is reported once, not also for its bad due_at
+	want := []Problem{
+		{Line: 5, Value: "火", Reason: "duplicate of row 4"},
+		{Line: 2, Value: "日", Reason: `bad due_at "yesterday"; progress skipped`},
+		{Line: 3, Value: "月", Reason: `bad interval_days "x"; progress skipped`},
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %+v, want %+v", problems, want)
+	}
+}
@@ -0,0 +1,124 @@
+// Package decks reads and writes kanji decks: CSV/TSV with a column
+// mapping, a versioned JSON format with progress and mnemonics, and Anki
+// .apkg packages.
+package decks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
+)
+
+// Import limits
+const (
+	MaxImportSize = 2 << 20
+	MaxImportRows = 5000
+)
+
+// Deck is a pack with its kanji, as exported or read from an import
+type Deck struct {
+	Name        string
+	Description string
+	IsPublic    bool
+	ExportedAt  time.Time
+	Entries     []Entry
+}
+
+// Entry is one kanji in a deck
+type Entry struct {
+	Line      int        `json:"-"` // input row or entry number, for reports
+	Kanji     string     `json:"kanji"`
+	Meanings  []string   `json:"meanings,omitempty"`
+	Onyomi    []string   `json:"onyomi,omitempty"`
+	Kunyomi   []string   `json:"kunyomi,omitempty"`
+	JLPTLevel string     `json:"jlpt_level,omitempty"`
+	Progress  *Progress  `json:"progress,omitempty"`
+	Mnemonics []Mnemonic `json:"mnemonics,omitempty"`
+}
+
+// Progress is the exporting user's SRS state for a kanji
+type Progress struct {
+	State          string     `json:"state"`
+	EaseFactor     float64    `json:"ease_factor"`
+	IntervalDays   int        `json:"interval_days"`
+	Repetitions    int        `json:"repetitions"`
+	Lapses         int        `json:"lapses"`
+	DueAt          time.Time  `json:"due_at"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty"`
+}
+
+// Mnemonic is a kanji_creations row
+type Mnemonic struct {
+	Explanation string    `json:"explanation"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	MappingURL  string    `json:"mapping_url,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	IsPublic    bool      `json:"is_public"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Problem is an input row that was not imported
+type Problem struct {
+	Line   int
+	Value  string
+	Reason string
+}
+
+// checkKanji validates the kanji cell of an entry, returning a reason when
+// it can't be used
+func checkKanji(s string) string {
+	switch {
+	case s == "":
+		return "no kanji in this row"
+	case utf8.RuneCountInString(s) != 1:
+		return "expected a single kanji"
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	if !kana.IsKanji(r) {
+		return "not a kanji"
+	}
+	return ""
+}
+
+// validate drops entries with a bad or repeated kanji, reporting each one
+func validate(entries []Entry) ([]Entry, []Problem) {
+	var problems []Problem
+	seen := make(map[string]int)
+	kept := entries[:0]
+	for _, e := range entries {
+		e.Kanji = strings.TrimSpace(e.Kanji)
+		if reason := checkKanji(e.Kanji); reason != "" {
+			problems = append(problems, Problem{Line: e.Line, Value: e.Kanji, Reason: reason})
+			continue
+		}
+		if first, ok := seen[e.Kanji]; ok {
+			problems = append(problems, Problem{Line: e.Line, Value: e.Kanji, Reason: fmt.Sprintf("duplicate of row %d", first)})
+			continue
+		}
+		seen[e.Kanji] = e.Line
+		kept = append(kept, e)
+	}
+	return kept, problems
+}
+
+// FileName returns a download file name for the deck with the given
+// extension
+func (d *Deck) FileName(ext string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(d.Name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '_':
+			b.WriteRune('-')
+		}
+	}
+	name := strings.Trim(b.String(), "-")
+	if name == "" {
+		name = "deck"
+	}
+	return name + ext
+}
@@ -0,0 +1,88 @@
+package decks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// JSON format identification. Bump FormatVersion for incompatible changes
+// and keep reading the old versions.
+const (
+	FormatName    = "kanji_go.deck"
+	FormatVersion = 1
+)
+
+// ErrUnsupportedFormat is returned for JSON that isn't a deck file this
+// version can read
+var ErrUnsupportedFormat = errors.New("unsupported deck format")
+
+// jsonDeck is the on-disk JSON document
+type jsonDeck struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Pack       jsonPack  `json:"pack"`
+	Kanji      []Entry   `json:"kanji"`
+}
+
+// jsonPack is the pack metadata in a JSON deck
+type jsonPack struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+// WriteJSON writes the deck in the current JSON format version
+func WriteJSON(w io.Writer, d *Deck) error {
+	doc := jsonDeck{
+		Format:     FormatName,
+		Version:    FormatVersion,
+		ExportedAt: d.ExportedAt,
+		Pack:       jsonPack{Name: d.Name, Description: d.Description, IsPublic: d.IsPublic},
+		Kanji:      d.Entries,
+	}
+	if doc.Kanji == nil {
+		doc.Kanji = []Entry{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// ReadJSON reads a JSON deck. Entries without a usable kanji are reported
+// as problems, numbered from 1 in file order.
+func ReadJSON(r io.Reader) (*Deck, []Problem, error) {
+	var doc jsonDeck
+	dec := json.NewDecoder(io.LimitReader(r, MaxImportSize))
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if doc.Format != FormatName {
+		return nil, nil, fmt.Errorf("%w: expected format %q", ErrUnsupportedFormat, FormatName)
+	}
+	if doc.Version < 1 || doc.Version > FormatVersion {
+		return nil, nil, fmt.Errorf("%w: version %d (this server reads up to %d)", ErrUnsupportedFormat, doc.Version, FormatVersion)
+	}
+	if len(doc.Kanji) > MaxImportRows {
+		return nil, nil, fmt.Errorf("too many kanji (at most %d)", MaxImportRows)
+	}
+
+	deck := &Deck{
+		Name:        doc.Pack.Name,
+		Description: doc.Pack.Description,
+		IsPublic:    doc.Pack.IsPublic,
+		ExportedAt:  doc.ExportedAt,
+		Entries:     doc.Kanji,
+	}
+	for i := range deck.Entries {
+		deck.Entries[i].Line = i + 1
+	}
+
+	var problems []Problem
+	deck.Entries, problems = validate(deck.Entries)
+	return deck, problems, nil
+}
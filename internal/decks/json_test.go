@@ -0,0 +1,89 @@
+package decks
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	reviewed := time.Date(2026, 2, 20, 8, 30, 0, 0, time.UTC)
+	in := &Deck{
+		Name:        "My deck",
+		Description: "Things I keep forgetting",
+		IsPublic:    true,
+		ExportedAt:  time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		Entries: []Entry{
+			{Kanji: "日", Meanings: []string{"sun"}, Kunyomi: []string{"ひ", "-び"}, JLPTLevel: "n5",
+				Progress: &Progress{State: "review", EaseFactor: 2.5, IntervalDays: 6, Repetitions: 2,
+					DueAt: reviewed.AddDate(0, 0, 6), LastReviewedAt: &reviewed},
+				Mnemonics: []Mnemonic{{Explanation: "sun", ImageURL: "/files/uploads/a.webp", IsPublic: true, CreatedAt: reviewed}}},
+			{Kanji: "月"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	out, problems, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("problems = %+v", problems)
+	}
+	in.Entries[0].Line, in.Entries[1].Line = 1, 2
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("ReadJSON = %+v\nwant %+v", out, in)
+	}
+}
+
+func TestReadJSONProblems(t *testing.T) {
+	in := `{"format":"kanji_go.deck","version":1,"pack":{"name":"x"},
+		"kanji":[{"kanji":"日"},{"kanji":"あ"},{"kanji":" 日 "},{"kanji":""},{"kanji":"月"}]}`
+	d, problems, err := ReadJSON(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := kanjiOf(d); !reflect.DeepEqual(got, []string{"日", "月"}) {
+		t.Errorf("kanji = %v", got)
+	}
+	want := []Problem{
+		{Line: 2, Value: "あ", Reason: "not a kanji"},
+		{Line: 3, Value: "日", Reason: "duplicate of row 1"},
+		{Line: 4, Value: "", Reason: "no kanji in this row"},
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %+v, want %+v", problems, want)
+	}
+}
+
+func TestReadJSONRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		unsupported bool
+	}{
+		{"newer version", `{"format":"kanji_go.deck","version":2,"kanji":[]}`, true},
+		{"version zero", `{"format":"kanji_go.deck","version":0,"kanji":[]}`, true},
+		{"missing version", `{"format":"kanji_go.deck","kanji":[]}`, true},
+		{"other format", `{"format":"anki","version":1,"kanji":[]}`, true},
+		{"not a deck", `[1, 2, 3]`, false},
+		{"not JSON", `kanji,meaning`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ReadJSON(strings.NewReader(tt.in))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrUnsupportedFormat) != tt.unsupported {
+				t.Errorf("err = %v; ErrUnsupportedFormat = %v", err, !tt.unsupported)
+			}
+		})
+	}
+}
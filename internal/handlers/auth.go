@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/UreshiiPanda/kanji_go/internal/auth"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/session"
+)
+
+// Password length limits (argon2 has no input limit, but keep requests sane)
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 128
+)
+
+// loginFailedMessage is shown for every failed login, whatever the cause
+const loginFailedMessage = "Invalid email/username or password"
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+// authDialogData is the template data for the login-dialog fragment
+type authDialogData struct {
+	Mode     string // "login" or "signup"
+	Error    string
+	Email    string
+	Username string
+	Login    string
+}
+
+// LoginDialogHandler returns the login/signup dialog
+func LoginDialogHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mode := r.URL.Query().Get("mode")
+		if mode != "signup" {
+			mode = "login"
+		}
+
+		// Remember the open dialog so it survives a page reload
+		if sess := session.FromContext(r.Context()); sess != nil {
+			sess.LoginPopupActive = true
+		}
+
+		renderAuthDialog(w, tmpl, authDialogData{Mode: mode})
+	}
+}
+
+// AuthStatusHandler renders the signed-in user (or a login button)
+func AuthStatusHandler(tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		data := map[string]any{"Username": session.CurrentUser(r.Context())}
+		if err := tmpl.ExecuteTemplate(w, "auth-status", data); err != nil {
+			log.Printf("Error executing auth-status template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// SignupHandler creates a new account and logs the user in
+func SignupHandler(db *sql.DB, sessions *session.Manager, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+
+		data := authDialogData{
+			Mode:     "signup",
+			Email:    strings.ToLower(strings.TrimSpace(r.FormValue("email"))),
+			Username: strings.TrimSpace(r.FormValue("username")),
+		}
+		password := r.FormValue("password")
+
+		// Validate input
+		if addr, err := mail.ParseAddress(data.Email); err != nil || addr.Address != data.Email {
+			data.Error = "Please enter a valid email address"
+		} else if !usernamePattern.MatchString(data.Username) {
+			data.Error = "Usernames are 3-32 letters, numbers or underscores"
+		} else if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+			data.Error = "Passwords must be between 8 and 128 characters"
+		} else if password != r.FormValue("password_confirm") {
+			data.Error = "Passwords do not match"
+		}
+		if data.Error != "" {
+			renderAuthDialog(w, tmpl, data)
+			return
+		}
+
+		hash, err := auth.HashPassword(password)
+		if err != nil {
+			log.Printf("Error hashing password: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		user := &models.User{Email: data.Email, Username: data.Username, PasswordHash: hash}
+		err = models.CreateUser(db, user)
+		switch {
+		case errors.Is(err, models.ErrDuplicateEmail):
+			data.Error = "An account with that email already exists"
+		case errors.Is(err, models.ErrDuplicateUsername):
+			data.Error = "That username is taken"
+		case err != nil:
+			log.Printf("Error creating user: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if data.Error != "" {
+			renderAuthDialog(w, tmpl, data)
+			return
+		}
+
+		log.Printf("Created user %s (id %d)", user.Username, user.ID)
+		startSession(w, r, sessions, tmpl, user.Username)
+	}
+}
+
+// LoginHandler authenticates a user by email or username and password
+func LoginHandler(db *sql.DB, sessions *session.Manager, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+
+		login := strings.TrimSpace(r.FormValue("login"))
+		password := r.FormValue("password")
+		failed := authDialogData{Mode: "login", Error: loginFailedMessage, Login: login}
+
+		user, err := models.GetUserByLogin(db, login)
+		if err != nil {
+			if !errors.Is(err, models.ErrUserNotFound) {
+				log.Printf("Error looking up user: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			// Do the same hashing work as a real check so timing doesn't
+			// reveal which accounts exist
+			auth.VerifyDummy(password)
+			renderAuthDialog(w, tmpl, failed)
+			return
+		}
+
+		ok, err := auth.VerifyPassword(password, user.PasswordHash)
+		if err != nil {
+			log.Printf("Error verifying password for user %d: %v", user.ID, err)
+		}
+		if !ok {
+			renderAuthDialog(w, tmpl, failed)
+			return
+		}
+
+		startSession(w, r, sessions, tmpl, user.Username)
+	}
+}
+
+// LogoutHandler ends the current session
+func LogoutHandler(sessions *session.Manager, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := sessions.Renew(w, r, nil); err != nil {
+			log.Printf("Error renewing session on logout: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		if err := tmpl.ExecuteTemplate(w, "auth-status", map[string]any{"Username": ""}); err != nil {
+			log.Printf("Error executing auth-status template: %v", err)
+		}
+	}
+}
+
+// startSession logs the user into a fresh session, closes the dialog and
+// swaps the new auth status into the page
+func startSession(w http.ResponseWriter, r *http.Request, sessions *session.Manager, tmpl *template.Template, username string) {
+	if _, err := sessions.Renew(w, r, &username); err != nil {
+		log.Printf("Error creating session: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Empty body clears #dialog-container; auth status is swapped out-of-band
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, "auth-status", map[string]any{"Username": username, "OOB": true}); err != nil {
+		log.Printf("Error executing auth-status template: %v", err)
+	}
+}
+
+// renderAuthDialog renders the login-dialog fragment
+func renderAuthDialog(w http.ResponseWriter, tmpl *template.Template, data authDialogData) {
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, "login-dialog", data); err != nil {
+		log.Printf("Error executing login-dialog template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// requireUser returns the logged-in user. For anonymous requests it opens
+// the login dialog instead and returns false; the handler should return.
+func requireUser(db *sql.DB, w http.ResponseWriter, r *http.Request, tmpl *template.Template) (*models.User, bool) {
+	username := session.CurrentUser(r.Context())
+	if username != "" {
+		user, err := models.GetUserByUsername(db, username)
+		if err == nil {
+			return user, true
+		}
+		if !errors.Is(err, models.ErrUserNotFound) {
+			log.Printf("Error loading user %s: %v", username, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return nil, false
+		}
+	}
+
+	if sess := session.FromContext(r.Context()); sess != nil {
+		sess.LoginPopupActive = true
+	}
+
+	// Swap the login dialog in wherever the request was targeting
+	w.Header().Set("HX-Retarget", "#dialog-container")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	renderAuthDialog(w, tmpl, authDialogData{Mode: "login", Error: "Please log in to continue"})
+	return nil, false
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/session"
+	"github.com/go-chi/chi/v5"
+)
+
+// Limits on creation fields (URL columns are VARCHAR(1024))
+const (
+	maxExplanationLength = 5000
+	maxCreationURLLength = 1024
+)
+
+// creationView is the template data for a single creation-card
+type creationView struct {
+	Creation models.KanjiCreation
+	IsOwner  bool
+	Error    string
+}
+
+// ListCreationsHandler renders the mnemonics shown alongside a kanji card
+func ListCreationsHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kanjiID, err := strconv.Atoi(chi.URLParam(r, "kanjiID"))
+		if err != nil {
+			http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
+			return
+		}
+		renderCreationList(w, r, db, tmpl, kanjiID, "")
+	}
+}
+
+// CreateCreationHandler adds a mnemonic to a kanji for the logged-in user
+func CreateCreationHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		kanjiID, err := strconv.Atoi(chi.URLParam(r, "kanjiID"))
+		if err != nil {
+			http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
+			return
+		}
+		if _, err := models.GetKanjiByID(db, kanjiID); err != nil {
+			http.Error(w, "Kanji not found", http.StatusNotFound)
+			return
+		}
+
+		c := models.KanjiCreation{KanjiCharID: kanjiID, CreatedBy: user.Username}
+		if msg := parseCreationForm(r, &c); msg != "" {
+			renderCreationList(w, r, db, tmpl, kanjiID, msg)
+			return
+		}
+
+		if err := models.CreateCreation(db, &c); err != nil {
+			log.Printf("Error creating kanji creation: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderCreationList(w, r, db, tmpl, kanjiID, "")
+	}
+}
+
+// GetCreationHandler renders a single creation card
+func GetCreationHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := loadCreation(w, r, db)
+		if !ok {
+			return
+		}
+
+		username := session.CurrentUser(r.Context())
+		isOwner := username != "" && c.CreatedBy == username
+		if !c.IsPublic && !isOwner {
+			http.Error(w, "Kanji creation not found", http.StatusNotFound)
+			return
+		}
+
+		renderCreation(w, tmpl, "creation-card", creationView{Creation: *c, IsOwner: isOwner})
+	}
+}
+
+// EditCreationHandler renders the edit form for the owner's creation
+func EditCreationHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := loadOwnedCreation(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+		renderCreation(w, tmpl, "creation-form", creationView{Creation: *c, IsOwner: true})
+	}
+}
+
+// UpdateCreationHandler saves edits to the owner's creation
+func UpdateCreationHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := loadOwnedCreation(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		if msg := parseCreationForm(r, c); msg != "" {
+			renderCreation(w, tmpl, "creation-form", creationView{Creation: *c, IsOwner: true, Error: msg})
+			return
+		}
+
+		if err := models.UpdateCreation(db, c); err != nil {
+			log.Printf("Error updating kanji creation %d: %v", c.KanjiCreationID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderCreation(w, tmpl, "creation-card", creationView{Creation: *c, IsOwner: true})
+	}
+}
+
+// ToggleCreationVisibilityHandler flips the owner's creation between public and private
+func ToggleCreationVisibilityHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := loadOwnedCreation(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		c.IsPublic = !c.IsPublic
+		if err := models.UpdateCreation(db, c); err != nil {
+			log.Printf("Error updating kanji creation %d: %v", c.KanjiCreationID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderCreation(w, tmpl, "creation-card", creationView{Creation: *c, IsOwner: true})
+	}
+}
+
+// DeleteCreationHandler deletes the owner's creation
+func DeleteCreationHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := loadOwnedCreation(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		if err := models.DeleteCreation(db, c.KanjiCreationID, c.CreatedBy); err != nil {
+			log.Printf("Error deleting kanji creation %d: %v", c.KanjiCreationID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		// Empty response removes the card
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(""))
+	}
+}
+
+// loadCreation loads the creation named by the {creationID} URL parameter
+func loadCreation(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.KanjiCreation, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "creationID"))
+	if err != nil {
+		http.Error(w, "Invalid creation ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	c, err := models.GetCreation(db, id)
+	if errors.Is(err, models.ErrCreationNotFound) {
+		http.Error(w, "Kanji creation not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error loading kanji creation %d: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return c, true
+}
+
+// loadOwnedCreation loads the creation and checks the logged-in user owns it
+func loadOwnedCreation(w http.ResponseWriter, r *http.Request, db *sql.DB, tmpl *template.Template) (*models.KanjiCreation, bool) {
+	user, ok := requireUser(db, w, r, tmpl)
+	if !ok {
+		return nil, false
+	}
+
+	c, ok := loadCreation(w, r, db)
+	if !ok {
+		return nil, false
+	}
+
+	if c.CreatedBy != user.Username {
+		http.Error(w, "You can only change your own creations", http.StatusForbidden)
+		return nil, false
+	}
+
+	return c, true
+}
+
+// parseCreationForm validates the form into c, returning a message for the
+// user if something is wrong
+func parseCreationForm(r *http.Request, c *models.KanjiCreation) string {
+	if err := r.ParseForm(); err != nil {
+		return "Could not read the form"
+	}
+
+	explanation := strings.TrimSpace(r.FormValue("explanation"))
+	if explanation == "" {
+		return "Please write an explanation"
+	}
+	if utf8.RuneCountInString(explanation) > maxExplanationLength {
+		return "Explanations can be at most 5000 characters"
+	}
+
+	imageURL, ok := optionalURL(r.FormValue("image_url"))
+	if !ok {
+		return "Image URL must be an http(s) link or an uploaded file"
+	}
+	mappingURL, ok := optionalURL(r.FormValue("mapping_url"))
+	if !ok {
+		return "Mapping URL must be an http(s) link or an uploaded file"
+	}
+
+	c.Explanation = explanation
+	c.ImageURL = imageURL
+	c.MappingURL = mappingURL
+	c.IsPublic = r.FormValue("is_public") != ""
+	return ""
+}
+
+// optionalURL validates an optional URL field, returning nil when empty
+func optionalURL(raw string) (*string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, true
+	}
+	if len(raw) > maxCreationURLLength {
+		return nil, false
+	}
+
+	// Files served by the app itself
+	if strings.HasPrefix(raw, "/files/") {
+		return &raw, true
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, false
+	}
+	return &raw, true
+}
+
+// creationListData builds the creation-list template data for a kanji
+func creationListData(r *http.Request, db *sql.DB, kanjiID int, formError string) (map[string]any, error) {
+	username := session.CurrentUser(r.Context())
+
+	creations, err := models.ListCreationsForKanji(db, kanjiID, username)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]creationView, len(creations))
+	for i, c := range creations {
+		views[i] = creationView{Creation: c, IsOwner: username != "" && c.CreatedBy == username}
+	}
+
+	return map[string]any{
+		"KanjiID":   kanjiID,
+		"Creations": views,
+		"Username":  username,
+		"Error":     formError,
+	}, nil
+}
+
+// renderCreationList renders all creations visible to the user for a kanji
+func renderCreationList(w http.ResponseWriter, r *http.Request, db *sql.DB, tmpl *template.Template, kanjiID int, formError string) {
+	data, err := creationListData(r, db, kanjiID, formError)
+	if err != nil {
+		log.Printf("Error listing kanji creations: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, "creation-list", data); err != nil {
+		log.Printf("Error executing creation-list template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// renderCreation renders a creation-card or creation-form fragment
+func renderCreation(w http.ResponseWriter, tmpl *template.Template, name string, data creationView) {
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Error executing %s template: %v", name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/UreshiiPanda/kanji_go/internal/decks"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/srs"
+	"github.com/UreshiiPanda/kanji_go/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxReportProblems caps how many skipped rows an import report lists
+const maxReportProblems = 200
+
+// importReport is the template data for the import-report fragment
+type importReport struct {
+	Pack      *models.Pack
+	Rows      int // rows that matched a kanji
+	Added     int // kanji added to the pack
+	Progress  int // SRS cards created or updated
+	Mnemonics int // mnemonics added
+	Problems  []decks.Problem
+	More      int // problems not listed
+	Error     string
+}
+
+// deckFormats maps each export format to its content type and extension
+var deckFormats = map[string][2]string{
+	"csv":  {"text/csv; charset=utf-8", ".csv"},
+	"tsv":  {"text/tab-separated-values; charset=utf-8", ".tsv"},
+	"json": {"application/json", ".json"},
+	"apkg": {"application/octet-stream", ".apkg"},
+}
+
+// ExportPackHandler downloads a pack as CSV, TSV, JSON or an Anki package,
+// with the user's progress and the mnemonics they can see
+func ExportPackHandler(db *sql.DB, store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := currentUserOrNil(db, w, r)
+		if !ok {
+			return
+		}
+		p, ok := loadPack(w, r, db, user)
+		if !ok {
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		ft, ok := deckFormats[format]
+		if !ok {
+			http.Error(w, "Unknown export format", http.StatusBadRequest)
+			return
+		}
+
+		userID, username := 0, ""
+		if user != nil {
+			userID, username = user.ID, user.Username
+		}
+		items, err := models.GetPackExport(db, p.PackID, userID, username)
+		if err != nil {
+			log.Printf("Error loading pack %d for export: %v", p.PackID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		deck := deckFromPack(p, items)
+
+		// Buffer the file so a failure part way through is still an error page
+		var buf bytes.Buffer
+		switch format {
+		case "csv":
+			err = decks.WriteCSV(&buf, ',', deck)
+		case "tsv":
+			err = decks.WriteCSV(&buf, '\t', deck)
+		case "json":
+			err = decks.WriteJSON(&buf, deck)
+		case "apkg":
+			ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+			defer cancel()
+			err = decks.WriteAPKG(ctx, &buf, deck, storeMedia(store))
+		}
+		if err != nil {
+			log.Printf("Error exporting pack %d as %s: %v", p.PackID, format, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", ft[0])
+		w.Header().Set("Content-Disposition", `attachment; filename="`+deck.FileName(ft[1])+`"`)
+		w.Write(buf.Bytes())
+	}
+}
+
+// ImportPackHandler imports a CSV, TSV or JSON deck, into the pack named by
+// {packID} when there is one and otherwise into a new deck, and renders a
+// report of what was added and which rows were skipped
+func ImportPackHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var user *models.User
+		var p *models.Pack
+		var ok bool
+		if chi.URLParam(r, "packID") != "" {
+			user, p, ok = loadOwnedPack(w, r, db, tmpl)
+		} else {
+			user, ok = requireUser(db, w, r, tmpl)
+		}
+		if !ok {
+			return
+		}
+
+		report := importReport{Pack: p}
+		deck, fileName, problems, msg := readImport(w, r)
+		if msg != "" {
+			report.Error = msg
+			renderImportReport(w, tmpl, report)
+			return
+		}
+
+		// Rows must name a kanji we have
+		chars := make([]string, len(deck.Entries))
+		for i, e := range deck.Entries {
+			chars[i] = e.Kanji
+		}
+		known, err := models.GetKanjiIDsByChar(db, chars)
+		if err != nil {
+			log.Printf("Error matching imported kanji: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		var entries []decks.Entry
+		var ids []int
+		for _, e := range deck.Entries {
+			id, found := known[e.Kanji]
+			if !found {
+				problems = append(problems, decks.Problem{Line: e.Line, Value: e.Kanji, Reason: "not in the kanji dictionary"})
+				continue
+			}
+			entries = append(entries, e)
+			ids = append(ids, id)
+		}
+		report.Rows = len(entries)
+
+		if p == nil {
+			p = &models.Pack{OwnerID: &user.ID, Owner: user.Username, Description: truncateRunes(deck.Description, maxPackDescriptionLength)}
+			p.Name = strings.TrimSpace(r.FormValue("name"))
+			if p.Name == "" {
+				p.Name = deck.Name
+			}
+			if p.Name == "" {
+				p.Name = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+			}
+			p.Name = truncateRunes(p.Name, maxPackNameLength)
+			if p.Name == "" {
+				p.Name = "Imported deck"
+			}
+			if err := models.CreatePack(db, p); err != nil {
+				log.Printf("Error creating imported pack: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			report.Pack = p
+		}
+
+		if report.Added, err = models.AddKanjiToPack(db, p.PackID, user.ID, ids); err != nil {
+			log.Printf("Error adding imported kanji to pack %d: %v", p.PackID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if r.FormValue("include_progress") != "" {
+			var cards []models.SRSCard
+			for i, e := range entries {
+				if e.Progress == nil {
+					continue
+				}
+				card, valid := srs.Sanitize(models.SRSCard{
+					UserID:         user.ID,
+					KanjiCharID:    ids[i],
+					State:          e.Progress.State,
+					EaseFactor:     e.Progress.EaseFactor,
+					IntervalDays:   e.Progress.IntervalDays,
+					Repetitions:    e.Progress.Repetitions,
+					Lapses:         e.Progress.Lapses,
+					DueAt:          e.Progress.DueAt,
+					LastReviewedAt: e.Progress.LastReviewedAt,
+				})
+				if !valid {
+					problems = append(problems, decks.Problem{Line: e.Line, Value: e.Kanji, Reason: "unknown SRS state " + e.Progress.State + "; progress skipped"})
+					continue
+				}
+				cards = append(cards, card)
+			}
+			if report.Progress, err = models.ImportSRSCards(db, cards); err != nil {
+				log.Printf("Error importing progress: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if r.FormValue("include_mnemonics") != "" {
+			var creations []models.KanjiCreation
+			for i, e := range entries {
+				for _, m := range e.Mnemonics {
+					explanation := strings.TrimSpace(m.Explanation)
+					if explanation == "" {
+						continue
+					}
+					if utf8.RuneCountInString(explanation) > maxExplanationLength {
+						problems = append(problems, decks.Problem{Line: e.Line, Value: e.Kanji, Reason: "mnemonic longer than 5000 characters; skipped"})
+						continue
+					}
+					// Links that don't validate are dropped, keeping the text
+					imageURL, _ := optionalURL(m.ImageURL)
+					mappingURL, _ := optionalURL(m.MappingURL)
+					creations = append(creations, models.KanjiCreation{
+						KanjiCharID: ids[i],
+						CreatedBy:   user.Username,
+						ImageURL:    imageURL,
+						MappingURL:  mappingURL,
+						Explanation: explanation,
+					})
+				}
+			}
+			if report.Mnemonics, err = models.ImportCreations(db, creations); err != nil {
+				log.Printf("Error importing mnemonics: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+		if len(problems) > maxReportProblems {
+			report.More = len(problems) - maxReportProblems
+			problems = problems[:maxReportProblems]
+		}
+		report.Problems = problems
+		renderImportReport(w, tmpl, report)
+	}
+}
+
+// readImport parses the uploaded deck file. On failure it returns a message
+// for the user.
+func readImport(w http.ResponseWriter, r *http.Request) (*decks.Deck, string, []decks.Problem, string) {
+	r.Body = http.MaxBytesReader(w, r.Body, decks.MaxImportSize+64<<10)
+	if err := r.ParseMultipartForm(decks.MaxImportSize); err != nil {
+		return nil, "", nil, "The file is too large (at most 2 MB)"
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", nil, "Please choose a file to import"
+	}
+	defer file.Close()
+
+	format := r.FormValue("format")
+	if format == "" || format == "auto" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".json":
+			format = "json"
+		case ".tsv", ".txt":
+			format = "tsv"
+		default:
+			format = "csv"
+		}
+	}
+
+	mapping := decks.Mapping{
+		Header:   r.FormValue("has_header") != "",
+		Kanji:    r.FormValue("kanji_column"),
+		Mnemonic: r.FormValue("mnemonic_column"),
+	}
+
+	var deck *decks.Deck
+	var problems []decks.Problem
+	switch format {
+	case "csv":
+		deck, problems, err = decks.ReadCSV(file, ',', mapping)
+	case "tsv":
+		deck, problems, err = decks.ReadCSV(file, '\t', mapping)
+	case "json":
+		deck, problems, err = decks.ReadJSON(file)
+	default:
+		return nil, "", nil, "Unknown import format"
+	}
+	if err != nil {
+		return nil, "", nil, "Could not read the file: " + err.Error()
+	}
+
+	return deck, header.Filename, problems, ""
+}
+
+// deckFromPack builds the exported deck for a pack
+func deckFromPack(p *models.Pack, items []models.PackExportItem) *decks.Deck {
+	deck := &decks.Deck{
+		Name:        p.Name,
+		Description: p.Description,
+		IsPublic:    p.IsPublic,
+		ExportedAt:  time.Now().UTC(),
+	}
+
+	for _, item := range items {
+		k := item.Kanji
+		e := decks.Entry{
+			Kanji:     k.KanjiChar,
+			Meanings:  k.Meanings,
+			JLPTLevel: k.JLPTLevel,
+		}
+		for _, reading := range models.FilterReadings(k.Readings, models.ReadingOn) {
+			e.Onyomi = append(e.Onyomi, reading.String())
+		}
+		for _, reading := range models.FilterReadings(k.Readings, models.ReadingKun) {
+			e.Kunyomi = append(e.Kunyomi, reading.String())
+		}
+
+		if c := item.Card; c != nil {
+			e.Progress = &decks.Progress{
+				State:          c.State,
+				EaseFactor:     c.EaseFactor,
+				IntervalDays:   c.IntervalDays,
+				Repetitions:    c.Repetitions,
+				Lapses:         c.Lapses,
+				DueAt:          c.DueAt,
+				LastReviewedAt: c.LastReviewedAt,
+			}
+		}
+
+		for _, c := range item.Creations {
+			m := decks.Mnemonic{
+				Explanation: c.Explanation,
+				Author:      c.CreatedBy,
+				IsPublic:    c.IsPublic,
+				CreatedAt:   c.CreatedDate,
+			}
+			if c.ImageURL != nil {
+				m.ImageURL = *c.ImageURL
+			}
+			if c.MappingURL != nil {
+				m.MappingURL = *c.MappingURL
+			}
+			e.Mnemonics = append(e.Mnemonics, m)
+		}
+
+		deck.Entries = append(deck.Entries, e)
+	}
+
+	return deck
+}
+
+// storeMedia lets an Anki export embed mnemonic images kept in the store
+func storeMedia(store storage.Store) decks.MediaSource {
+	return func(ctx context.Context, url string) (io.ReadCloser, string, bool, error) {
+		key, ok := storage.KeyFromURL(store, url)
+		if !ok {
+			return nil, "", false, nil
+		}
+		rc, _, err := store.Get(ctx, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Mnemonic image %s is missing from storage", key)
+			return nil, "", false, nil
+		}
+		if err != nil {
+			return nil, "", false, err
+		}
+		return rc, path.Base(key), true, nil
+	}
+}
+
+// truncateRunes shortens s to at most n runes
+func truncateRunes(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:n]))
+}
+
+// renderImportReport renders the import-report fragment
+func renderImportReport(w http.ResponseWriter, tmpl *template.Template, report importReport) {
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, "import-report", report); err != nil {
+		log.Printf("Error executing import-report template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
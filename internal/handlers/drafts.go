@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/UreshiiPanda/kanji_go/internal/cleanup"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// draftView is the template data for the draft-editor fragment
+type draftView struct {
+	KanjiID int
+	Draft   models.TempCreation
+	Saved   bool
+	Error   string
+	Images  []storedImage // every size of an image just uploaded
+}
+
+// DraftEditorHandler opens the user's draft for a kanji (empty if none yet)
+func DraftEditorHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, kanjiID, ok := draftRequest(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		draft, ok := loadDraft(w, db, user.Username, kanjiID)
+		if !ok {
+			return
+		}
+
+		renderDraft(w, tmpl, "draft-editor", draftView{KanjiID: kanjiID, Draft: *draft, Saved: draft.TempID != 0})
+	}
+}
+
+// SaveDraftHandler autosaves the draft's text fields
+func SaveDraftHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, kanjiID, ok := draftRequest(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		draft, ok := loadDraft(w, db, user.Username, kanjiID)
+		if !ok {
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+
+		// Drafts may be incomplete, but still respect the column limits
+		view := draftView{KanjiID: kanjiID, Draft: *draft}
+		explanation := r.FormValue("explanation")
+		mappingURL, urlOK := optionalURL(r.FormValue("mapping_url"))
+		switch {
+		case utf8.RuneCountInString(explanation) > maxExplanationLength:
+			view.Error = "Explanations can be at most 5000 characters"
+		case !urlOK:
+			view.Error = "Mapping URL must be an http(s) link or an uploaded file"
+		}
+		if view.Error != "" {
+			renderDraft(w, tmpl, "draft-status", view)
+			return
+		}
+
+		draft.Explanation = explanation
+		draft.MappingURL = mappingURL
+		draft.IsPublic = r.FormValue("is_public") != ""
+
+		if err := models.SaveDraft(db, draft); err != nil {
+			log.Printf("Error saving draft: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		view.Draft = *draft
+		view.Saved = true
+		renderDraft(w, tmpl, "draft-status", view)
+	}
+}
+
+// DraftImageHandler uploads an image and attaches it to the draft,
+// replacing (and cleaning up) any previous one
+func DraftImageHandler(db *sql.DB, store storage.Store, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, kanjiID, ok := draftRequest(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		draft, ok := loadDraft(w, db, user.Username, kanjiID)
+		if !ok {
+			return
+		}
+
+		upload := &models.Upload{OwnerID: &user.ID, KanjiCharID: &kanjiID}
+		images, _, ok := saveUploadedImage(w, r, db, store, upload)
+		if !ok {
+			return
+		}
+
+		// The card size is what creations show; the others sit beside it
+		previous := draft.ImageURL
+		url := imageURL(images, "card")
+		draft.ImageURL = &url
+
+		if err := models.SaveDraft(db, draft); err != nil {
+			log.Printf("Error saving draft: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if previous != nil && *previous != url {
+			cleanup.ReleaseImageIfUnused(db, store, user.Username, *previous)
+		}
+
+		renderDraft(w, tmpl, "draft-editor", draftView{KanjiID: kanjiID, Draft: *draft, Saved: true, Images: images})
+	}
+}
+
+// PreviewDraftHandler shows the draft as it would appear once published
+func PreviewDraftHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, kanjiID, ok := draftRequest(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		draft, err := models.GetDraftForKanji(db, user.Username, kanjiID)
+		if errors.Is(err, models.ErrDraftNotFound) {
+			http.Error(w, "Draft not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error loading draft: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		preview := models.KanjiCreation{
+			KanjiCharID: draft.KanjiCharID,
+			CreatedBy:   draft.Owner,
+			CreatedDate: time.Now(),
+			ImageURL:    draft.ImageURL,
+			MappingURL:  draft.MappingURL,
+			Explanation: draft.Explanation,
+			IsPublic:    draft.IsPublic,
+		}
+		renderDraft(w, tmpl, "draft-preview", creationView{Creation: preview})
+	}
+}
+
+// PublishDraftHandler promotes the draft into a kanji creation
+func PublishDraftHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, kanjiID, ok := draftRequest(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		_, err := models.PublishDraft(db, user.Username, kanjiID)
+		switch {
+		case errors.Is(err, models.ErrDraftNotFound):
+			http.Error(w, "Draft not found", http.StatusNotFound)
+			return
+		case errors.Is(err, models.ErrDraftIncomplete):
+			draft, _ := models.GetDraftForKanji(db, user.Username, kanjiID)
+			view := draftView{KanjiID: kanjiID, Saved: true, Error: "Write an explanation before publishing"}
+			if draft != nil {
+				view.Draft = *draft
+			}
+			renderDraft(w, tmpl, "draft-editor", view)
+			return
+		case err != nil:
+			log.Printf("Error publishing draft: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		// Show the kanji's mnemonics, now including the published one
+		w.Header().Set("HX-Retarget", "#creations-"+strconv.Itoa(kanjiID))
+		w.Header().Set("HX-Reswap", "outerHTML")
+		renderCreationList(w, r, db, tmpl, kanjiID, "")
+	}
+}
+
+// DiscardDraftHandler deletes the draft and its image if unused elsewhere
+func DiscardDraftHandler(db *sql.DB, store storage.Store, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, kanjiID, ok := draftRequest(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		draft, err := models.GetDraftForKanji(db, user.Username, kanjiID)
+		if err != nil && !errors.Is(err, models.ErrDraftNotFound) {
+			log.Printf("Error loading draft: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if draft != nil {
+			if err := models.DeleteDraft(db, user.Username, kanjiID); err != nil && !errors.Is(err, models.ErrDraftNotFound) {
+				log.Printf("Error deleting draft: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if draft.ImageURL != nil {
+				cleanup.ReleaseImageIfUnused(db, store, user.Username, *draft.ImageURL)
+			}
+		}
+
+		// Empty response closes the editor
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(""))
+	}
+}
+
+// draftRequest checks the user is logged in and the {kanjiID} exists
+func draftRequest(w http.ResponseWriter, r *http.Request, db *sql.DB, tmpl *template.Template) (*models.User, int, bool) {
+	user, ok := requireUser(db, w, r, tmpl)
+	if !ok {
+		return nil, 0, false
+	}
+
+	kanjiID, err := strconv.Atoi(chi.URLParam(r, "kanjiID"))
+	if err != nil {
+		http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
+		return nil, 0, false
+	}
+
+	if _, err := models.GetKanjiByID(db, kanjiID); err != nil {
+		if !errors.Is(err, models.ErrKanjiNotFound) {
+			log.Printf("Error loading kanji %d: %v", kanjiID, err)
+		}
+		http.Error(w, "Kanji not found", http.StatusNotFound)
+		return nil, 0, false
+	}
+
+	return user, kanjiID, true
+}
+
+// loadDraft returns the user's draft for a kanji, or a new unsaved one
+func loadDraft(w http.ResponseWriter, db *sql.DB, owner string, kanjiID int) (*models.TempCreation, bool) {
+	draft, err := models.GetDraftForKanji(db, owner, kanjiID)
+	if errors.Is(err, models.ErrDraftNotFound) {
+		return &models.TempCreation{KanjiCharID: kanjiID, Owner: owner}, true
+	}
+	if err != nil {
+		log.Printf("Error loading draft: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return draft, true
+}
+
+// renderDraft renders one of the draft fragments
+func renderDraft(w http.ResponseWriter, tmpl *template.Template, name string, data any) {
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Error executing %s template: %v", name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
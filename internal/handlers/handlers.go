@@ -2,10 +2,17 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/session"
 	"github.com/gorilla/csrf"
 )
 
@@ -16,6 +23,8 @@ func HomeHandler(tmpl *template.Template) http.HandlerFunc {
 			"Title":     "Kanji Go",
 			"Message":   "Welcome to Kanji Go!",
 			"csrfToken": csrf.Token(r), // Add CSRF token for HTMX
+			"Session":   session.FromContext(r.Context()),
+			"Levels":    jlptLevels,
 		}
 
 		err := tmpl.ExecuteTemplate(w, "base.html", data)
@@ -36,57 +45,215 @@ type KanjiData struct {
     HiraganaOnyomi  string
     HiraganaKunyomi string
     JLPTLevel       string
+    Onyomi          []models.Reading
+    Kunyomi         []models.Reading
+    Nanori          []models.Reading
+    Starred         bool
+    Saved           bool
 }
 
-// GetKanjiHandler returns all kanji from the database
-func GetKanjiHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
-    return func(w http.ResponseWriter, r *http.Request) {
-        // Query the database
-        rows, err := db.Query(`
-            SELECT kanji_char_id, kanji_char, romaji_onyomi, romaji_kunyomi, 
-                   hiragana_onyomi, hiragana_kunyomi, jlpt_level
-            FROM kanji_go.kanji
-            ORDER BY kanji_char_id
-        `)
-        if err != nil {
-            log.Printf("Error querying kanji: %v", err)
-            http.Error(w, "Failed to retrieve kanji", http.StatusInternalServerError)
-            return
-        }
-        defer rows.Close()
-
-        // Create a slice to hold all kanji data
-        var kanjiList []KanjiData
-
-        // Process each row
-        for rows.Next() {
-            var kanji KanjiData
-            if err := rows.Scan(&kanji.ID, &kanji.KanjiChar, &kanji.RomajiOnyomi, 
-                               &kanji.RomajiKunyomi, &kanji.HiraganaOnyomi, 
-                               &kanji.HiraganaKunyomi, &kanji.JLPTLevel); err != nil {
-                log.Printf("Error scanning row: %v", err)
-                continue
-            }
-            kanjiList = append(kanjiList, kanji)
-        }
-
-        // Check for errors from iterating over rows
-        if err := rows.Err(); err != nil {
-            log.Printf("Error iterating rows: %v", err)
-        }
-
-        // Prepare template data
-        data := map[string]any{
-            "KanjiList": kanjiList,
-        }
-
-        // Execute the template
-        w.Header().Set("Content-Type", "text/html")
-        if err := tmpl.ExecuteTemplate(w, "kanji-list", data); err != nil {
-            log.Printf("Error executing kanji-list template: %v", err)
-            http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-        }
-    }
+// ListState returns the kanji-list-state fragment data for the card
+func (k KanjiData) ListState() kanjiListStateView {
+	return kanjiListStateView{KanjiID: k.ID, KanjiListState: models.KanjiListState{Starred: k.Starred, Saved: k.Saved}}
+}
+
+// kanjiDataFrom converts a kanji row to template data
+func kanjiDataFrom(k models.Kanji) KanjiData {
+	return KanjiData{
+		ID:              k.KanjiCharID,
+		KanjiChar:       k.KanjiChar,
+		RomajiOnyomi:    k.RomajiOnyomi,
+		RomajiKunyomi:   k.RomajiKunyomi,
+		HiraganaOnyomi:  k.HiraganaOnyomi,
+		HiraganaKunyomi: k.HiraganaKunyomi,
+		JLPTLevel:       k.JLPTLevel,
+		Onyomi:          models.FilterReadings(k.Readings, models.ReadingOn),
+		Kunyomi:         models.FilterReadings(k.Readings, models.ReadingKun),
+		Nanori:          models.FilterReadings(k.Readings, models.ReadingNanori),
+	}
+}
+
+// kanjiBrowseResponse is the JSON form of a browse page
+type kanjiBrowseResponse struct {
+	Kanji      []models.Kanji `json:"kanji"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	HasMore    bool           `json:"has_more"`
+	Total      int            `json:"total"`
+	Limit      int            `json:"limit"`
+}
+
+// writeJSONError answers a JSON request with {"error": message}
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": message}); err != nil {
+		log.Printf("Error encoding error response: %v", err)
+	}
+}
+
+// BrowseKanjiHandler returns one page of kanji, filtered by ?jlpt= and
+// ?component= (repeatable) and ordered by ?sort= / ?order=. Requests with
+// a ?cursor= render only the next page of cards for infinite scroll;
+// ?format=json returns JSON. ?list=starred or ?list=saved need a login; JSON
+// clients get a 401 rather than the login dialog.
+func BrowseKanjiHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		opts := models.BrowseOptions{
+			JLPTLevel: q.Get("jlpt"),
+			Sort:      q.Get("sort"),
+			Desc:      q.Get("order") == "desc",
+			Limit:     models.DefaultBrowseLimit,
+		}
+		if opts.JLPTLevel != "" && !slices.Contains(jlptLevels, opts.JLPTLevel) {
+			http.Error(w, "Invalid JLPT level", http.StatusBadRequest)
+			return
+		}
+		if opts.Sort == "" {
+			opts.Sort = "id"
+		}
+		if !slices.Contains(models.KanjiSorts, opts.Sort) {
+			http.Error(w, "Invalid sort", http.StatusBadRequest)
+			return
+		}
+		if limit := q.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 1 || n > models.MaxBrowseLimit {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			opts.Limit = n
+		}
+		// "My starred" / "My saved" browse the user's list instead
+		var user *models.User
+		if list := q.Get("list"); list != "" {
+			if list != models.ListStarred && list != models.ListSaved {
+				http.Error(w, "Invalid list", http.StatusBadRequest)
+				return
+			}
+			var ok bool
+			if q.Get("format") == "json" {
+				if user, ok = currentUserOrNil(db, w, r); !ok {
+					return
+				}
+				if user == nil {
+					writeJSONError(w, http.StatusUnauthorized, "login required")
+					return
+				}
+			} else if user, ok = requireUser(db, w, r, tmpl); !ok {
+				return
+			}
+			opts.List, opts.UserID = list, user.ID
+		}
+		components, ok := parseComponents(q["component"])
+		if !ok {
+			http.Error(w, "Invalid components", http.StatusBadRequest)
+			return
+		}
+		opts.Components = components
+		if cursor := q.Get("cursor"); cursor != "" {
+			after, err := models.ParseKanjiCursor(cursor)
+			if err != nil {
+				http.Error(w, "Invalid cursor", http.StatusBadRequest)
+				return
+			}
+			opts.After = after
+		}
+
+		page, err := models.BrowseKanji(db, opts)
+		if errors.Is(err, models.ErrInvalidCursor) {
+			http.Error(w, "Cursor does not match the sort order", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Error browsing kanji: %v", err)
+			http.Error(w, "Failed to retrieve kanji", http.StatusInternalServerError)
+			return
+		}
+
+		nextCursor := ""
+		if page.Next != nil {
+			nextCursor = page.Next.String()
+		}
+
+		if q.Get("format") == "json" {
+			resp := kanjiBrowseResponse{
+				Kanji:      page.Kanji,
+				NextCursor: nextCursor,
+				HasMore:    page.Next != nil,
+				Total:      page.Total,
+				Limit:      opts.Limit,
+			}
+			if resp.Kanji == nil {
+				resp.Kanji = []models.Kanji{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				log.Printf("Error encoding kanji page: %v", err)
+			}
+			return
+		}
+
+		// Mark the kanji the user has starred or saved
+		username := session.CurrentUser(r.Context())
+		user, err = currentUserWithLists(db, username)
+		if err != nil {
+			log.Printf("Error loading kanji lists: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		kanjiList := make([]KanjiData, 0, len(page.Kanji))
+		for _, k := range page.Kanji {
+			data := kanjiDataFrom(k)
+			if user != nil {
+				data.Starred = slices.Contains(user.StarredKanji, k.KanjiCharID)
+				data.Saved = slices.Contains(user.SavedKanji, k.KanjiCharID)
+			}
+			kanjiList = append(kanjiList, data)
+		}
+
+		// The next page keeps the same filter and sort
+		nextURL := ""
+		if nextCursor != "" {
+			next := url.Values{}
+			for _, key := range []string{"jlpt", "sort", "order", "limit", "list"} {
+				if v := q.Get(key); v != "" {
+					next.Set(key, v)
+				}
+			}
+			next["component"] = opts.Components
+			next.Set("cursor", nextCursor)
+			nextURL = "/api/kanji?" + next.Encode()
+		}
+
+		data := map[string]any{
+			"KanjiList":  kanjiList,
+			"NextURL":    nextURL,
+			"Total":      page.Total,
+			"JLPTLevel":  opts.JLPTLevel,
+			"Sort":       opts.Sort,
+			"Desc":       opts.Desc,
+			"Levels":     jlptLevels,
+			"Sorts":      models.KanjiSorts,
+			"List":       opts.List,
+			"Components": opts.Components,
+			"Username":   username,
+		}
+
+		// Infinite scroll requests only need the next batch of cards
+		name := "kanji-list"
+		if opts.After != nil {
+			name = "kanji-page"
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+			log.Printf("Error executing %s template: %v", name, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
 }
 
 // GetDialogHandler returns a BeerCSS dialog
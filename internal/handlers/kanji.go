@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/session"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/csrf"
+)
+
+// detailVocabularyLimit caps the compounds listed on the detail page
+const detailVocabularyLimit = 30
+
+// KanjiDetailHandler renders the /kanji/{char} page. A numeric {char} is
+// treated as a kanji ID and redirected to the character URL.
+func KanjiDetailHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		param := chi.URLParam(r, "char")
+
+		if id, err := strconv.Atoi(param); err == nil {
+			kanji, err := models.GetKanjiByID(db, id)
+			if err != nil {
+				kanjiLookupError(w, err)
+				return
+			}
+			http.Redirect(w, r, kanjiURL(kanji.KanjiChar), http.StatusMovedPermanently)
+			return
+		}
+
+		if utf8.RuneCountInString(param) != 1 {
+			http.Error(w, "Kanji not found", http.StatusNotFound)
+			return
+		}
+
+		kanji, err := models.GetKanjiByChar(db, param)
+		if err != nil {
+			kanjiLookupError(w, err)
+			return
+		}
+
+		if kanji.Meanings, err = models.GetKanjiMeanings(db, kanji.KanjiCharID); err != nil {
+			log.Printf("Error loading meanings: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if kanji.Readings, err = models.GetKanjiReadings(db, kanji.KanjiCharID); err != nil {
+			log.Printf("Error loading readings: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		// A radical number without a row is shown as the bare number
+		var radical *models.Radical
+		if kanji.Radical != nil {
+			radical, err = models.GetRadical(db, *kanji.Radical)
+			if err != nil && !errors.Is(err, models.ErrRadicalNotFound) {
+				log.Printf("Error loading radical: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+		components, err := models.GetKanjiComponents(db, kanji.KanjiCharID)
+		if err != nil {
+			log.Printf("Error loading components: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		strokes, err := models.GetKanjiStrokes(db, kanji.KanjiCharID)
+		if err != nil {
+			log.Printf("Error loading strokes: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		vocabulary, err := models.GetVocabularyForKanji(db, kanji.KanjiCharID, detailVocabularyLimit)
+		if err != nil {
+			log.Printf("Error loading vocabulary: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		creations, err := creationListData(r, db, kanji.KanjiCharID, "")
+		if err != nil {
+			log.Printf("Error listing kanji creations: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		// Starred/saved state only applies to logged-in users
+		username := session.CurrentUser(r.Context())
+		var state models.KanjiListState
+		if username != "" {
+			user, err := models.GetUserByUsername(db, username)
+			if err == nil {
+				state, err = models.GetKanjiListState(db, user.ID, kanji.KanjiCharID)
+			}
+			if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+				log.Printf("Error loading kanji list state: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// KANJIDIC2 and KanjiVG occasionally count strokes differently
+		strokeDiff := kanji.StrokeCount != nil && len(strokes) > 0 && *kanji.StrokeCount != len(strokes)
+
+		data := map[string]any{
+			"Title":      kanji.KanjiChar + " - Kanji Go",
+			"csrfToken":  csrf.Token(r),
+			"Session":    session.FromContext(r.Context()),
+			"Kanji":      kanji,
+			"Onyomi":     models.FilterReadings(kanji.Readings, models.ReadingOn),
+			"Kunyomi":    models.FilterReadings(kanji.Readings, models.ReadingKun),
+			"Nanori":     models.FilterReadings(kanji.Readings, models.ReadingNanori),
+			"Radical":    radical,
+			"Components": components,
+			"Strokes":    strokePlayer(kanji.KanjiChar, strokes, 0),
+			"StrokeDiff": strokeDiff,
+			"Vocabulary": vocabulary,
+			"Creations":  creations,
+			"Username":   username,
+			"State":      kanjiListStateView{KanjiID: kanji.KanjiCharID, KanjiListState: state},
+		}
+
+		if err := tmpl.ExecuteTemplate(w, "kanji.html", data); err != nil {
+			log.Printf("Error executing kanji template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// kanjiURL returns the detail page URL for a kanji character
+func kanjiURL(char string) string {
+	return "/kanji/" + url.PathEscape(char)
+}
+
+// kanjiLookupError reports a failed kanji lookup
+func kanjiLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, models.ErrKanjiNotFound) {
+		http.Error(w, "Kanji not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("Error loading kanji: %v", err)
+	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+}
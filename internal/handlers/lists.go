@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// kanjiListStateView is the template data for the kanji-list-state fragment
+type kanjiListStateView struct {
+	KanjiID int
+	models.KanjiListState
+}
+
+// ToggleKanjiListHandler stars/unstars or saves/unsaves a kanji for the
+// current user, depending on list
+func ToggleKanjiListHandler(db *sql.DB, tmpl *template.Template, list string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		kanjiID, err := strconv.Atoi(chi.URLParam(r, "kanjiID"))
+		if err != nil {
+			http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
+			return
+		}
+		if _, err := models.GetKanjiByID(db, kanjiID); err != nil {
+			kanjiLookupError(w, err)
+			return
+		}
+
+		if _, err := models.ToggleKanjiInList(db, list, user.ID, kanjiID); err != nil {
+			log.Printf("Error toggling %s kanji: %v", list, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		state, err := models.GetKanjiListState(db, user.ID, kanjiID)
+		if err != nil {
+			log.Printf("Error loading kanji list state: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		if err := tmpl.ExecuteTemplate(w, "kanji-list-state", kanjiListStateView{KanjiID: kanjiID, KanjiListState: state}); err != nil {
+			log.Printf("Error executing kanji-list-state template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// currentUserWithLists loads the logged-in user with their starred and saved
+// kanji, or returns nil if nobody is logged in
+func currentUserWithLists(db *sql.DB, username string) (*models.User, error) {
+	if username == "" {
+		return nil, nil
+	}
+
+	user, err := models.GetUserByUsername(db, username)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := models.LoadUserKanjiLists(db, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
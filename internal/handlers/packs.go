@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/session"
+	"github.com/go-chi/chi/v5"
+)
+
+// Limits on pack fields (name is VARCHAR(100))
+const (
+	maxPackNameLength        = 100
+	maxPackDescriptionLength = 1000
+	maxPackKanjiPerAdd       = 200
+)
+
+// packListView is the template data for the pack-list fragment
+type packListView struct {
+	BuiltIn  []models.Pack
+	Mine     []models.Pack
+	Public   []models.Pack // other users' public decks
+	Username string
+	Error    string
+}
+
+// packView is the template data for the pack-detail fragment
+type packView struct {
+	Pack     models.Pack
+	Kanji    []models.Kanji
+	IsOwner  bool
+	Username string
+	Error    string
+}
+
+// ListPacksHandler renders the built-in packs, the user's own decks and
+// other users' public decks
+func ListPacksHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := currentUserOrNil(db, w, r)
+		if !ok {
+			return
+		}
+		renderPackList(w, db, tmpl, user, "")
+	}
+}
+
+// CreatePackHandler creates a custom deck for the logged-in user
+func CreatePackHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		p := models.Pack{OwnerID: &user.ID, Owner: user.Username}
+		if msg := parsePackForm(r, &p); msg != "" {
+			renderPackList(w, db, tmpl, user, msg)
+			return
+		}
+
+		if err := models.CreatePack(db, &p); err != nil {
+			log.Printf("Error creating pack: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderPackDetail(w, db, tmpl, user, &p, "")
+	}
+}
+
+// GetPackHandler renders a pack and its kanji
+func GetPackHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := currentUserOrNil(db, w, r)
+		if !ok {
+			return
+		}
+		p, ok := loadPack(w, r, db, user)
+		if !ok {
+			return
+		}
+		renderPackDetail(w, db, tmpl, user, p, "")
+	}
+}
+
+// EditPackHandler renders the edit form for the owner's deck, in place of
+// the pack header
+func EditPackHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, p, ok := loadOwnedPack(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+		renderPack(w, tmpl, "pack-form", packView{Pack: *p, IsOwner: true, Username: user.Username})
+	}
+}
+
+// UpdatePackHandler saves the name, description and visibility of the
+// owner's deck
+func UpdatePackHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, p, ok := loadOwnedPack(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		// The form posts to the whole pack; errors go back into the form
+		if msg := parsePackForm(r, p); msg != "" {
+			w.Header().Set("HX-Retarget", "#pack-header-"+strconv.Itoa(p.PackID))
+			w.Header().Set("HX-Reswap", "outerHTML")
+			renderPack(w, tmpl, "pack-form", packView{Pack: *p, IsOwner: true, Username: user.Username, Error: msg})
+			return
+		}
+
+		if err := models.UpdatePack(db, p); err != nil {
+			log.Printf("Error updating pack %d: %v", p.PackID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderPackDetail(w, db, tmpl, user, p, "")
+	}
+}
+
+// DeletePackHandler deletes the owner's deck and shows the pack list again
+func DeletePackHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, p, ok := loadOwnedPack(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		if err := models.DeletePack(db, p.PackID, user.ID); err != nil {
+			log.Printf("Error deleting pack %d: %v", p.PackID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderPackList(w, db, tmpl, user, "")
+	}
+}
+
+// AddPackKanjiHandler appends the kanji typed into the form to the owner's
+// deck, in the order they were typed
+func AddPackKanjiHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, p, ok := loadOwnedPack(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		var chars []string
+		for _, c := range r.FormValue("kanji") {
+			if kana.IsKanji(c) {
+				chars = append(chars, string(c))
+			}
+		}
+		if len(chars) == 0 {
+			renderPackDetail(w, db, tmpl, user, p, "Type the kanji to add, e.g. 日本語")
+			return
+		}
+		if len(chars) > maxPackKanjiPerAdd {
+			renderPackDetail(w, db, tmpl, user, p, "Add at most 200 kanji at a time")
+			return
+		}
+
+		var ids []int
+		var unknown []string
+		for _, c := range chars {
+			k, err := models.GetKanjiByChar(db, c)
+			if errors.Is(err, models.ErrKanjiNotFound) {
+				unknown = append(unknown, c)
+				continue
+			}
+			if err != nil {
+				log.Printf("Error loading kanji %s: %v", c, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			ids = append(ids, k.KanjiCharID)
+		}
+
+		if _, err := models.AddKanjiToPack(db, p.PackID, user.ID, ids); err != nil {
+			log.Printf("Error adding kanji to pack %d: %v", p.PackID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		msg := ""
+		if len(unknown) > 0 {
+			msg = "Not in the dictionary: " + strings.Join(unknown, " ")
+		}
+		renderPackDetail(w, db, tmpl, user, p, msg)
+	}
+}
+
+// RemovePackKanjiHandler removes a kanji from the owner's deck
+func RemovePackKanjiHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, p, ok := loadOwnedPack(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		kanjiID, err := strconv.Atoi(chi.URLParam(r, "kanjiID"))
+		if err != nil {
+			http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := models.RemoveKanjiFromPack(db, p.PackID, user.ID, kanjiID); err != nil {
+			log.Printf("Error removing kanji from pack %d: %v", p.PackID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderPackDetail(w, db, tmpl, user, p, "")
+	}
+}
+
+// MovePackKanjiHandler moves a kanji one place up or down in the owner's deck
+func MovePackKanjiHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, p, ok := loadOwnedPack(w, r, db, tmpl)
+		if !ok {
+			return
+		}
+
+		kanjiID, err := strconv.Atoi(chi.URLParam(r, "kanjiID"))
+		if err != nil {
+			http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
+			return
+		}
+
+		dir := r.FormValue("dir")
+		if dir != "up" && dir != "down" {
+			http.Error(w, "Invalid direction", http.StatusBadRequest)
+			return
+		}
+
+		err = models.MoveKanjiInPack(db, p.PackID, user.ID, kanjiID, dir == "up")
+		if errors.Is(err, models.ErrKanjiNotFound) {
+			http.Error(w, "Kanji not in pack", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error reordering pack %d: %v", p.PackID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderPackDetail(w, db, tmpl, user, p, "")
+	}
+}
+
+// TogglePackSubscriptionHandler subscribes or unsubscribes the user from a
+// pack they can see. A subscription to a pack since made private can still
+// be removed.
+func TogglePackSubscriptionHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+		packID, err := strconv.Atoi(chi.URLParam(r, "packID"))
+		if err != nil {
+			http.Error(w, "Invalid pack ID", http.StatusBadRequest)
+			return
+		}
+
+		p, err := models.GetPack(db, packID, user.ID)
+		switch {
+		case errors.Is(err, models.ErrPackNotFound):
+			removed, err := models.RemovePackSubscription(db, user.ID, packID)
+			if err != nil {
+				log.Printf("Error removing subscription to pack %d: %v", packID, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !removed {
+				http.Error(w, "Pack not found", http.StatusNotFound)
+				return
+			}
+			p = &models.Pack{PackID: packID}
+		case err != nil:
+			log.Printf("Error loading pack %d: %v", packID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		default:
+			subscribed, err := models.TogglePackSubscription(db, user.ID, p.PackID)
+			if err != nil {
+				log.Printf("Error toggling subscription to pack %d: %v", p.PackID, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			p.Subscribed = subscribed
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		if err := tmpl.ExecuteTemplate(w, "pack-subscribe", p); err != nil {
+			log.Printf("Error executing pack-subscribe template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// currentUserOrNil returns the logged-in user, or nil for anonymous
+// requests. It returns false after writing an error response.
+func currentUserOrNil(db *sql.DB, w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	username := session.CurrentUser(r.Context())
+	if username == "" {
+		return nil, true
+	}
+
+	user, err := models.GetUserByUsername(db, username)
+	if errors.Is(err, models.ErrUserNotFound) {
+		return nil, true
+	}
+	if err != nil {
+		log.Printf("Error loading user %s: %v", username, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return user, true
+}
+
+// loadPack loads the pack named by the {packID} URL parameter, if the user
+// (nil when anonymous) can see it
+func loadPack(w http.ResponseWriter, r *http.Request, db *sql.DB, user *models.User) (*models.Pack, bool) {
+	packID, err := strconv.Atoi(chi.URLParam(r, "packID"))
+	if err != nil {
+		http.Error(w, "Invalid pack ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	viewerID := 0
+	if user != nil {
+		viewerID = user.ID
+	}
+
+	p, err := models.GetPack(db, packID, viewerID)
+	if errors.Is(err, models.ErrPackNotFound) {
+		http.Error(w, "Pack not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error loading pack %d: %v", packID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return p, true
+}
+
+// loadOwnedPack loads the pack and checks the logged-in user owns it.
+// Built-in packs have no owner and can't be changed.
+func loadOwnedPack(w http.ResponseWriter, r *http.Request, db *sql.DB, tmpl *template.Template) (*models.User, *models.Pack, bool) {
+	user, ok := requireUser(db, w, r, tmpl)
+	if !ok {
+		return nil, nil, false
+	}
+
+	p, ok := loadPack(w, r, db, user)
+	if !ok {
+		return nil, nil, false
+	}
+
+	if !p.OwnedBy(user.ID) {
+		http.Error(w, "You can only change your own packs", http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	return user, p, true
+}
+
+// parsePackForm validates the form into p, returning a message for the user
+// if something is wrong
+func parsePackForm(r *http.Request, p *models.Pack) string {
+	if err := r.ParseForm(); err != nil {
+		return "Could not read the form"
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		return "Please give the pack a name"
+	}
+	if utf8.RuneCountInString(name) > maxPackNameLength {
+		return "Pack names can be at most 100 characters"
+	}
+
+	description := strings.TrimSpace(r.FormValue("description"))
+	if utf8.RuneCountInString(description) > maxPackDescriptionLength {
+		return "Descriptions can be at most 1000 characters"
+	}
+
+	p.Name = name
+	p.Description = description
+	p.IsPublic = r.FormValue("is_public") != ""
+	return ""
+}
+
+// renderPackList renders the pack-list fragment for the user (nil when
+// anonymous)
+func renderPackList(w http.ResponseWriter, db *sql.DB, tmpl *template.Template, user *models.User, formError string) {
+	data := packListView{Error: formError}
+	viewerID := 0
+	if user != nil {
+		viewerID = user.ID
+		data.Username = user.Username
+	}
+
+	packs, err := models.ListPacks(db, viewerID)
+	if err != nil {
+		log.Printf("Error listing packs: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	for _, p := range packs {
+		switch {
+		case p.IsBuiltin():
+			data.BuiltIn = append(data.BuiltIn, p)
+		case p.OwnedBy(viewerID):
+			data.Mine = append(data.Mine, p)
+		default:
+			data.Public = append(data.Public, p)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, "pack-list", data); err != nil {
+		log.Printf("Error executing pack-list template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// renderPackDetail reloads the pack and its kanji and renders pack-detail
+func renderPackDetail(w http.ResponseWriter, db *sql.DB, tmpl *template.Template, user *models.User, p *models.Pack, formError string) {
+	viewerID := 0
+	data := packView{Error: formError}
+	if user != nil {
+		viewerID = user.ID
+		data.Username = user.Username
+	}
+
+	fresh, err := models.GetPack(db, p.PackID, viewerID)
+	if err != nil {
+		log.Printf("Error loading pack %d: %v", p.PackID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data.Pack = *fresh
+	data.IsOwner = fresh.OwnedBy(viewerID)
+
+	if data.Kanji, err = models.GetPackKanji(db, p.PackID); err != nil {
+		log.Printf("Error loading kanji for pack %d: %v", p.PackID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	renderPack(w, tmpl, "pack-detail", data)
+}
+
+// renderPack renders a pack fragment: pack-detail or pack-form
+func renderPack(w http.ResponseWriter, tmpl *template.Template, name string, data packView) {
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Error executing %s template: %v", name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/quiz"
+	"github.com/UreshiiPanda/kanji_go/internal/session"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// quizCandidates is how many random kanji are drawn per question, so
+	// one without the readings or meanings a mode needs can be skipped
+	quizCandidates = 10
+
+	// maxQuizAnswerLength caps what is stored of a typed answer
+	maxQuizAnswerLength = 100
+
+	// distractorPoolSize is how many similar kanji multiple-choice
+	// distractors are drawn from
+	distractorPoolSize = 30
+)
+
+// quizSetupView is the template data for the quiz-setup fragment
+type quizSetupView struct {
+	Packs          []models.Pack
+	PackID         int
+	Modes          []quiz.Mode
+	Mode           quiz.Mode
+	MultipleChoice bool
+}
+
+// quizView is the template data for the quiz-card fragment
+type quizView struct {
+	Pack           *models.Pack
+	Mode           quiz.Mode
+	MultipleChoice bool
+	Started        int64 // Unix time the quiz began; the score counts from here
+	Question       *quiz.Question
+	Choices        []string     // options when MultipleChoice
+	Result         *quiz.Result // nil until the question is answered
+	Correct        int
+	Total          int
+	Error          string
+}
+
+// QuizHandler shows the quiz setup: which pack to draw from, what to ask
+// and whether answers are typed or picked from similar-looking options.
+// The pack for the session's JLPT level is selected by default.
+func QuizHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		packs, err := models.ListPacks(db, user.ID)
+		if err != nil {
+			log.Printf("Error listing packs: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		data := quizSetupView{Packs: packs, Modes: quiz.Modes, Mode: quiz.ModeReading}
+		level := "n5"
+		if sess := session.FromContext(r.Context()); sess != nil && sess.CurrentJLPTLevel != "" {
+			level = sess.CurrentJLPTLevel
+		}
+		for _, p := range packs {
+			if p.JLPTLevel != nil && *p.JLPTLevel == level {
+				data.PackID = p.PackID
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		if err := tmpl.ExecuteTemplate(w, "quiz-setup", data); err != nil {
+			log.Printf("Error executing quiz-setup template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// QuizQuestionHandler asks a question about a random kanji from the pack,
+// avoiding the one just asked (?after=)
+func QuizQuestionHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+		data, ok := quizParams(w, r, db, user.ID)
+		if !ok {
+			return
+		}
+
+		after, _ := strconv.Atoi(r.FormValue("after"))
+		candidates, err := models.GetQuizKanji(db, data.Pack.PackID, after, quizCandidates)
+		if err != nil {
+			log.Printf("Error picking quiz kanji: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		for _, k := range candidates {
+			if q, ok := quiz.NewQuestion(data.Mode, k); ok {
+				data.Question = &q
+				break
+			}
+		}
+		if data.Question == nil {
+			data.Error = "No kanji in this pack can be asked this way yet."
+		}
+
+		if data.Question != nil && data.MultipleChoice {
+			pool, err := models.GetDistractorPool(db, &data.Question.Kanji, distractorPoolSize)
+			if err != nil {
+				log.Printf("Error loading distractors: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			data.Choices = quiz.Choices(*data.Question, pool, quiz.DefaultChoices, rand.Uint64())
+			if len(data.Choices) < 2 {
+				// Nothing similar enough to offer; fall back to typing
+				data.Choices = nil
+			}
+		}
+
+		renderQuizCard(w, db, tmpl, user.ID, data)
+	}
+}
+
+// QuizAnswerHandler grades a typed or picked answer, records the result and shows
+// the accepted answers
+func QuizAnswerHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		kanjiID, err := strconv.Atoi(chi.URLParam(r, "kanjiID"))
+		if err != nil {
+			http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
+			return
+		}
+		data, ok := quizParams(w, r, db, user.ID)
+		if !ok {
+			return
+		}
+
+		// Results count toward the pack, so only its own kanji are graded
+		member, err := models.PackHasKanji(db, data.Pack.PackID, kanjiID)
+		if err != nil {
+			log.Printf("Error checking pack %d for kanji %d: %v", data.Pack.PackID, kanjiID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if !member {
+			http.Error(w, "Kanji is not in this pack", http.StatusBadRequest)
+			return
+		}
+
+		kanji, err := models.GetKanjiForQuiz(db, kanjiID)
+		if errors.Is(err, models.ErrKanjiNotFound) {
+			http.Error(w, "Kanji not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error loading kanji %d: %v", kanjiID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		q, ok := quiz.NewQuestion(data.Mode, *kanji)
+		if !ok {
+			http.Error(w, "Kanji can't be asked this way", http.StatusBadRequest)
+			return
+		}
+		res := quiz.Grade(q, truncateRunes(r.FormValue("answer"), maxQuizAnswerLength))
+
+		err = models.SaveQuizResult(db, &models.QuizResult{
+			UserID:         user.ID,
+			KanjiCharID:    kanjiID,
+			PackID:         &data.Pack.PackID,
+			Mode:           string(data.Mode),
+			MultipleChoice: data.MultipleChoice && r.FormValue("typed") == "",
+			Prompt:         q.Prompt,
+			Answer:         res.Answer,
+			IsCorrect:      res.Correct,
+		})
+		if err != nil {
+			log.Printf("Error saving quiz result: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		data.Question = &q
+		data.Result = &res
+		renderQuizCard(w, db, tmpl, user.ID, data)
+	}
+}
+
+// quizParams reads the pack, mode, answer style and start time every quiz
+// request carries
+func quizParams(w http.ResponseWriter, r *http.Request, db *sql.DB, userID int) (quizView, bool) {
+	var data quizView
+
+	mode, ok := quiz.ParseMode(r.FormValue("mode"))
+	if !ok {
+		http.Error(w, "Invalid quiz mode", http.StatusBadRequest)
+		return data, false
+	}
+	data.Mode = mode
+	data.MultipleChoice = r.FormValue("choice") == "1"
+
+	pack, ok := reviewPack(w, r, db, userID)
+	if !ok {
+		return data, false
+	}
+	if pack == nil {
+		http.Error(w, "Choose a pack to quiz on", http.StatusBadRequest)
+		return data, false
+	}
+	data.Pack = pack
+
+	data.Started, _ = strconv.ParseInt(r.FormValue("started"), 10, 64)
+	if data.Started <= 0 || data.Started > time.Now().Unix() {
+		data.Started = time.Now().Unix()
+	}
+
+	return data, true
+}
+
+// renderQuizCard fills in the score so far and renders the quiz-card fragment
+func renderQuizCard(w http.ResponseWriter, db *sql.DB, tmpl *template.Template, userID int, data quizView) {
+	var err error
+	data.Correct, data.Total, err = models.GetQuizScore(db, userID, time.Unix(data.Started, 0))
+	if err != nil {
+		log.Printf("Error loading quiz score: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, "quiz-card", data); err != nil {
+		log.Printf("Error executing quiz-card template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
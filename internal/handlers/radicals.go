@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+const (
+	// maxSearchComponents caps how many components one search can combine
+	maxSearchComponents = 10
+
+	// componentSearchLimit caps the kanji shown in the picker; the rest are
+	// reachable through the kanji list
+	componentSearchLimit = 100
+)
+
+// componentOption is one toggle in the radical picker
+type componentOption struct {
+	models.Component
+	Selected bool
+	Disabled bool // no kanji has this together with the selected components
+}
+
+// componentGroup is the picker row for one stroke count (0 if unknown)
+type componentGroup struct {
+	StrokeCount int
+	Components  []componentOption
+}
+
+// radicalPickerView is the template data for the radical-picker fragment
+type radicalPickerView struct {
+	Groups    []componentGroup
+	Selected  []string
+	Kanji     []models.Kanji
+	Total     int
+	BrowseURL string // the full result in the kanji list
+}
+
+// RadicalPickerHandler shows every component grouped by stroke count, with
+// nothing selected
+func RadicalPickerHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderRadicalPicker(w, db, tmpl, nil, nil)
+	}
+}
+
+// RadicalSearchHandler re-renders the picker for the ?component= values:
+// the kanji containing all of them, and which components can still be
+// added without leaving no results
+func RadicalSearchHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		selected, ok := parseComponents(r.URL.Query()["component"])
+		if !ok {
+			http.Error(w, "Invalid components", http.StatusBadRequest)
+			return
+		}
+
+		res, err := models.SearchByComponents(db, selected, componentSearchLimit)
+		if err != nil {
+			log.Printf("Error searching by components: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		renderRadicalPicker(w, db, tmpl, selected, res)
+	}
+}
+
+// parseComponents validates ?component= values: single characters, at
+// most maxSearchComponents of them. Duplicates are dropped.
+func parseComponents(values []string) ([]string, bool) {
+	var comps []string
+	for _, c := range values {
+		if utf8.RuneCountInString(c) != 1 {
+			return nil, false
+		}
+		if !slices.Contains(comps, c) {
+			comps = append(comps, c)
+		}
+	}
+	if len(comps) > maxSearchComponents {
+		return nil, false
+	}
+	return comps, true
+}
+
+// renderRadicalPicker renders the radical-picker fragment. res is nil
+// when nothing is selected.
+func renderRadicalPicker(w http.ResponseWriter, db *sql.DB, tmpl *template.Template, selected []string, res *models.ComponentSearch) {
+	comps, err := models.ListComponents(db)
+	if err != nil {
+		log.Printf("Error listing components: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := radicalPickerView{Selected: selected}
+	for _, c := range comps {
+		strokes := 0
+		if c.StrokeCount != nil {
+			strokes = *c.StrokeCount
+		}
+		if len(data.Groups) == 0 || data.Groups[len(data.Groups)-1].StrokeCount != strokes {
+			data.Groups = append(data.Groups, componentGroup{StrokeCount: strokes})
+		}
+		opt := componentOption{Component: c, Selected: slices.Contains(selected, c.Key)}
+		opt.Disabled = res != nil && !opt.Selected && !res.Possible[c.Key]
+		group := &data.Groups[len(data.Groups)-1]
+		group.Components = append(group.Components, opt)
+	}
+
+	if res != nil {
+		data.Kanji, data.Total = res.Kanji, res.Total
+		browse := url.Values{"component": selected}
+		data.BrowseURL = "/api/kanji?" + browse.Encode()
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, "radical-picker", data); err != nil {
+		log.Printf("Error executing radical-picker template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
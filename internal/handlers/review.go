@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/srs"
+	"github.com/go-chi/chi/v5"
+)
+
+// reviewData is the template data for the review-card fragment
+type reviewData struct {
+	Item       *models.ReviewItem
+	ShowAnswer bool
+	DueCount   int
+	NewCount   int
+	Pack       *models.Pack // nil when reviewing every subscribed pack
+}
+
+// ReviewHandler shows the next card in the user's daily review queue, or
+// in one pack's queue when ?pack= is given
+func ReviewHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+		pack, ok := reviewPack(w, r, db, user.ID)
+		if !ok {
+			return
+		}
+		renderNextReview(w, db, tmpl, user.ID, pack)
+	}
+}
+
+// ReviewAnswerHandler reveals the back of a card with the grade buttons
+func ReviewAnswerHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		kanjiID, err := strconv.Atoi(chi.URLParam(r, "kanjiID"))
+		if err != nil {
+			http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
+			return
+		}
+		pack, ok := reviewPack(w, r, db, user.ID)
+		if !ok {
+			return
+		}
+
+		kanji, err := models.GetKanjiByID(db, kanjiID)
+		if errors.Is(err, models.ErrKanjiNotFound) {
+			http.Error(w, "Kanji not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Error loading kanji %d: %v", kanjiID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		item := &models.ReviewItem{Kanji: *kanji}
+		card, err := models.GetSRSCard(db, user.ID, kanjiID)
+		if err != nil && !errors.Is(err, models.ErrCardNotFound) {
+			log.Printf("Error loading srs card: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		item.Card = card
+
+		renderReviewCard(w, tmpl, reviewData{Item: item, ShowAnswer: true, Pack: pack})
+	}
+}
+
+// GradeReviewHandler records a grade for a kanji and shows the next card
+func GradeReviewHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		kanjiID, err := strconv.Atoi(chi.URLParam(r, "kanjiID"))
+		if err != nil {
+			http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
+			return
+		}
+
+		grade, err := srs.ParseGrade(r.FormValue("grade"))
+		if err != nil {
+			http.Error(w, "Invalid grade", http.StatusBadRequest)
+			return
+		}
+		pack, ok := reviewPack(w, r, db, user.ID)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+
+		// Grading a kanji for the first time starts a new card
+		var card models.SRSCard
+		existing, err := models.GetSRSCard(db, user.ID, kanjiID)
+		switch {
+		case errors.Is(err, models.ErrCardNotFound):
+			if _, err := models.GetKanjiByID(db, kanjiID); err != nil {
+				http.Error(w, "Kanji not found", http.StatusNotFound)
+				return
+			}
+			card = srs.NewCard(user.ID, kanjiID, now)
+		case err != nil:
+			log.Printf("Error loading srs card: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		default:
+			card = *existing
+		}
+
+		next := srs.Schedule(card, grade, now)
+		if err := models.SaveReview(db, &next, int(grade)); err != nil {
+			log.Printf("Error saving review: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		renderNextReview(w, db, tmpl, user.ID, pack)
+	}
+}
+
+// reviewPack loads the pack named by the pack form value, or returns nil
+// when the review isn't limited to one pack
+func reviewPack(w http.ResponseWriter, r *http.Request, db *sql.DB, userID int) (*models.Pack, bool) {
+	raw := r.FormValue("pack")
+	if raw == "" || raw == "0" {
+		return nil, true
+	}
+
+	packID, err := strconv.Atoi(raw)
+	if err != nil {
+		http.Error(w, "Invalid pack ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	pack, err := models.GetPack(db, packID, userID)
+	if errors.Is(err, models.ErrPackNotFound) {
+		http.Error(w, "Pack not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error loading pack %d: %v", packID, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return pack, true
+}
+
+// renderNextReview renders the front of the next due (or new) card, or the
+// done message when the queue is empty. A non-nil pack limits the queue to
+// that pack's kanji.
+func renderNextReview(w http.ResponseWriter, db *sql.DB, tmpl *template.Template, userID int, pack *models.Pack) {
+	now := time.Now()
+	data := reviewData{Pack: pack}
+
+	packID := 0
+	if pack != nil {
+		packID = pack.PackID
+	}
+
+	var err error
+	data.DueCount, err = models.CountDueReviews(db, userID, packID, now)
+	if err != nil {
+		log.Printf("Error counting due reviews: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// New cards are limited per day, counted from local midnight
+	y, m, d := now.Date()
+	startedToday, err := models.CountNewCardsSince(db, userID, time.Date(y, m, d, 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		log.Printf("Error counting new cards: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data.NewCount = max(0, srs.NewCardsPerDay-startedToday)
+
+	// Due reviews come first, then new kanji
+	items, err := models.GetDueReviews(db, userID, packID, now, 1)
+	if err == nil && len(items) == 0 && data.NewCount > 0 {
+		items, err = models.GetNewKanji(db, userID, packID, 1)
+	}
+	if err != nil {
+		log.Printf("Error loading review queue: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if len(items) > 0 {
+		data.Item = &items[0]
+	}
+
+	renderReviewCard(w, tmpl, data)
+}
+
+// renderReviewCard renders the review-card fragment
+func renderReviewCard(w http.ResponseWriter, tmpl *template.Template, data reviewData) {
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmpl.ExecuteTemplate(w, "review-card", data); err != nil {
+		log.Printf("Error executing review-card template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/UreshiiPanda/kanji_go/internal/search"
+)
+
+// Search result limits
+const (
+	searchLimit    = 20
+	maxSearchLimit = 100
+)
+
+// searchResponse is the JSON form of a search
+type searchResponse struct {
+	Query   string          `json:"query"`
+	Results []search.Result `json:"results"`
+}
+
+// SearchHandler renders results for the search-as-you-type box
+func SearchHandler(index *search.Index, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+
+		data := map[string]any{
+			"Query":   query,
+			"Results": index.Search(query, searchLimit),
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		if err := tmpl.ExecuteTemplate(w, "search-results", data); err != nil {
+			log.Printf("Error executing search-results template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// SearchAPIHandler returns search results as JSON (?q=, optional ?limit=)
+func SearchAPIHandler(index *search.Index) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		limit := searchLimit
+		if raw := q.Get("limit"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 || n > maxSearchLimit {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		resp := searchResponse{Query: q.Get("q"), Results: index.Search(q.Get("q"), limit)}
+		if resp.Results == nil {
+			resp.Results = []search.Result{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Printf("Error encoding search results: %v", err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/UreshiiPanda/kanji_go/internal/session"
+)
+
+// jlptLevels are the valid values of kanji.jlpt_level / sessions.curr_jlpt_level
+var jlptLevels = []string{"n1", "n2", "n3", "n4", "n5"}
+
+// PreferencesHandler updates the UI state stored on the session. Each form
+// field is optional; only the ones present are changed.
+func PreferencesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sess := session.FromContext(r.Context())
+		if sess == nil {
+			http.Error(w, "No session", http.StatusBadRequest)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Error parsing form", http.StatusBadRequest)
+			return
+		}
+
+		if level := r.FormValue("jlpt_level"); level != "" {
+			if !slices.Contains(jlptLevels, level) {
+				http.Error(w, "Invalid JLPT level", http.StatusBadRequest)
+				return
+			}
+			sess.CurrentJLPTLevel = level
+		}
+
+		if page := r.FormValue("page"); page != "" {
+			if len(page) > 255 {
+				http.Error(w, "Invalid page", http.StatusBadRequest)
+				return
+			}
+			sess.CurrentPage = page
+		}
+
+		if r.Form.Has("toggle_sidebar") {
+			sess.LeftSidebarActive = !sess.LeftSidebarActive
+		}
+
+		if r.Form.Has("toggle_dark_mode") {
+			sess.DarkModeActive = !sess.DarkModeActive
+			// The theme class lives on <body>, so reload to apply it
+			w.Header().Set("HX-Refresh", "true")
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CloseDialogHandler clears the dialog container and forgets any open popup
+func CloseDialogHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if sess := session.FromContext(r.Context()); sess != nil {
+			sess.LoginPopupActive = false
+			sess.ContactPopupActive = false
+			sess.PaymentPopupActive = false
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(""))
+	}
+}
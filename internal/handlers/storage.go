@@ -1,176 +1,367 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"html/template"
 	"io"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
-	"html/template"
 
-	"cloud.google.com/go/storage"
-	"github.com/google/uuid"
-	"google.golang.org/api/iterator"
+	"github.com/UreshiiPanda/kanji_go/internal/cleanup"
+	"github.com/UreshiiPanda/kanji_go/internal/imaging"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/UreshiiPanda/kanji_go/internal/storage"
 )
 
 // Maximum file size (5MB)
 const maxUploadSize = 5 << 20
 
-// Storage client instance
-var storageClient *storage.Client
+// uploadsPrefix is the key prefix for user uploads
+const uploadsPrefix = "uploads/"
 
-// InitStorage initializes the Cloud Storage client
-func InitStorage(ctx context.Context) error {
-	var err error
-	
-	// For production, this will use the service account credentials
-	// For local development, this uses local gcloud credentials
-	storageClient, err = storage.NewClient(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create storage client: %w", err)
-	}
-	
-	return nil
+// blobDir is where every size of an image with the given content hash is
+// stored, so identical uploads share their objects
+func blobDir(contentHash string) string {
+	return uploadsPrefix + "sha256/" + contentHash
 }
 
-// CloseStorage closes the storage client
-func CloseStorage() {
-	if storageClient != nil {
-		storageClient.Close()
-	}
-}
+// filesPageSize is how many files ListFilesHandler shows per page
+const filesPageSize = 30
 
-// GetBucketName returns the configured bucket name
-func GetBucketName() string {
-	bucketName := os.Getenv("BUCKET_NAME")
-	if bucketName == "" {
-		log.Println("Warning: BUCKET_NAME not set, using default bucket name")
-		bucketName = "default-bucket-name"
-	}
-	return bucketName
-}
-
-// UploadHandler handles file uploads to Google Cloud Storage
-func UploadHandler() http.HandlerFunc {
+// UploadHandler handles image uploads to object storage, recording who
+// uploaded it and for which kanji. The response shows the thumbnail and
+// links every stored size; an image that is already stored gets its
+// existing URLs.
+func UploadHandler(db *sql.DB, store storage.Store, tmpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Println("Upload handler started")
-		
-		// Set a reasonable timeout for the upload
-		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
-		defer cancel()
 
-		// Limit file size
-		r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
-		if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-			log.Printf("Error parsing multipart form: %v", err)
-			http.Error(w, "File too large or invalid form", http.StatusBadRequest)
+		// Every upload has an owner
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
 			return
 		}
-		
-		// Get the file from the form
-		file, header, err := r.FormFile("image")
-		if err != nil {
-			log.Printf("Error getting file from form: %v", err)
-			http.Error(w, "Error retrieving file", http.StatusBadRequest)
-			return
-		}
-		defer file.Close()
-		
-		log.Printf("Received file: %s (size: %d bytes, type: %s)", 
-			header.Filename, header.Size, header.Header.Get("Content-Type"))
-
-		// Validate file type
-		if !isAllowedFileType(header.Filename) {
-			log.Printf("Invalid file type: %s", filepath.Ext(header.Filename))
-			http.Error(w, "Invalid file type. Only jpg, jpeg, png, and gif are allowed", http.StatusBadRequest)
+		if !parseUploadForm(w, r) {
 			return
 		}
+		upload := &models.Upload{OwnerID: &user.ID}
 
-		// Generate a unique filename
-		filename := generateUniqueFilename(header.Filename)
-		log.Printf("Generated unique filename: %s", filename)
-		
-		// Get bucket name from environment
-		bucketName := GetBucketName()
-		
-		// Check if storageClient is initialized
-		if storageClient == nil {
-			log.Println("Storage client not initialized, initializing now")
-			if err := InitStorage(ctx); err != nil {
-				log.Printf("Failed to initialize storage client: %v", err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+		// Link the kanji_char_id from the form (if it exists)
+		var kanji *models.Kanji
+		if s := r.FormValue("kanji_char_id"); s != "" {
+			kanjiID, err := strconv.Atoi(s)
+			if err != nil {
+				http.Error(w, "Invalid kanji ID", http.StatusBadRequest)
 				return
 			}
-			defer CloseStorage()
+			kanji, err = models.GetKanjiByID(db, kanjiID)
+			if err != nil {
+				if !errors.Is(err, models.ErrKanjiNotFound) {
+					log.Printf("Error loading kanji %d: %v", kanjiID, err)
+				}
+				http.Error(w, "Kanji not found", http.StatusNotFound)
+				return
+			}
+			upload.KanjiCharID = &kanjiID
 		}
-		
-		// Upload the file to Google Cloud Storage
-		objectName := fmt.Sprintf("uploads/%s", filename)
-		log.Printf("Uploading to object: %s in bucket: %s", objectName, bucketName)
-		
-		object := storageClient.Bucket(bucketName).Object(objectName)
-		wc := object.NewWriter(ctx)
-		
-		// Set Content-Type based on file extension
-		contentType := getContentType(filename)
-		wc.ContentType = contentType
-		log.Printf("Set content type to: %s", contentType)
-		
-		// Copy the file to GCS
-		bytesWritten, err := io.Copy(wc, file)
-		if err != nil {
-			log.Printf("Error copying file to GCS: %v", err)
-			http.Error(w, "Error uploading file", http.StatusInternalServerError)
+
+		images, reused, ok := saveUploadedImage(w, r, db, store, upload)
+		if !ok {
 			return
 		}
-		log.Printf("Copied %d bytes to GCS", bytesWritten)
-		
-		// Close the writer to finalize the upload
-		if err := wc.Close(); err != nil {
-			log.Printf("Error closing GCS writer: %v", err)
-			http.Error(w, "Error finalizing upload", http.StatusInternalServerError)
-			return
+		message := "File uploaded successfully!"
+		if reused {
+			message = "This image was already uploaded, so the stored copy is reused."
 		}
-		log.Println("Successfully closed GCS writer")
-		
-		// Generate a public URL for the file
-		publicURL := fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucketName, objectName)
-		log.Printf("Generated public URL: %s", publicURL)
-		
-		// Get the kanji_char_id from the form (if it exists)
-		kanjiID := r.FormValue("kanji_char_id")
+
+		// Describe the link as saved; an earlier upload of the image may
+		// carry one when the form had none
 		kanjiIDText := ""
-		if kanjiID != "" {
-			kanjiIDText = fmt.Sprintf("<p>Associated with Kanji ID: %s</p>", kanjiID)
+		if upload.KanjiCharID != nil {
+			if kanji == nil || kanji.KanjiCharID != *upload.KanjiCharID {
+				var err error
+				if kanji, err = models.GetKanjiByID(db, *upload.KanjiCharID); err != nil {
+					log.Printf("Error loading kanji %d: %v", *upload.KanjiCharID, err)
+					kanji = nil
+				}
+			}
+			if kanji != nil {
+				kanjiIDText = fmt.Sprintf("<p>Associated with Kanji %s (ID %d)</p>", template.HTMLEscapeString(kanji.KanjiChar), kanji.KanjiCharID)
+			}
+		}
+
+		var links strings.Builder
+		for _, img := range images {
+			fmt.Fprintf(&links, `<a href="%s" target="_blank" class="text-blue-600 hover:text-blue-800 mr-2">%s (%d&times;%d)</a>`,
+				template.HTMLEscapeString(img.URL), img.Size, img.Width, img.Height)
 		}
-		
-		// Return the URL in the response for HTMX
+
+		// Return the URLs in the response for HTMX
 		w.Header().Set("Content-Type", "text/html")
 		successHTML := fmt.Sprintf(`
 			<div class="upload-success bg-green-100 border border-green-400 text-green-700 px-4 py-3 rounded mb-4">
-				<p>File uploaded successfully!</p>
+				<p>%s</p>
 				%s
 				<div class="mt-2">
-					<img src="%s" alt="Uploaded image" class="max-w-full h-auto rounded shadow" style="max-height: 200px;">
+					<img src="%s" alt="Uploaded image" class="h-auto rounded shadow">
 				</div>
-				<p class="mt-2 text-sm">
-					<a href="%s" target="_blank" class="text-blue-600 hover:text-blue-800">View full image</a>
-				</p>
+				<p class="mt-2 text-sm">%s</p>
 				<input type="hidden" name="imageURL" value="%s">
 			</div>
-		`, kanjiIDText, publicURL, publicURL, publicURL)
-		
+		`, message, kanjiIDText, imageURL(images, "thumb"), links.String(), imageURL(images, "card"))
+
 		log.Println("Upload handler completed successfully")
 		w.Write([]byte(successHTML))
 	}
 }
 
-// ServeFileHandler serves a file from Google Cloud Storage
-func ServeFileHandler() http.HandlerFunc {
+// storedImage is one stored size of an uploaded image
+type storedImage struct {
+	Size   string
+	Key    string
+	URL    string
+	Width  int
+	Height int
+}
+
+// imageURL returns the URL of the named size
+func imageURL(images []storedImage, size string) string {
+	for _, img := range images {
+		if img.Size == size {
+			return img.URL
+		}
+	}
+	return ""
+}
+
+// parseUploadForm reads a multipart upload request, capped at
+// maxUploadSize. Parsing again once it has succeeded is a no-op.
+func parseUploadForm(w http.ResponseWriter, r *http.Request) bool {
+	if r.MultipartForm != nil {
+		return true
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		log.Printf("Error parsing multipart form: %v", err)
+		http.Error(w, "File too large or invalid form", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// saveUploadedImage validates the "image" file in a multipart request and
+// stores it. Images are keyed by the SHA-256 of the file: content whose
+// every size is already stored just gets another reference (reused is
+// true); anything else, including content another upload is still
+// writing, is checked by content and every imaging size of it stored under
+// uploads/sha256/<hash>/. upload carries the owner and kanji; the rest of
+// its row is filled in and, for new content, written as pending before the
+// objects, then marked stored. On failure it removes whatever was written,
+// writes the error response and returns ok false.
+func saveUploadedImage(w http.ResponseWriter, r *http.Request, db *sql.DB, store storage.Store, upload *models.Upload) (images []storedImage, reused bool, ok bool) {
+	// Set a reasonable timeout for the upload
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	// Limit file size
+	if !parseUploadForm(w, r) {
+		return nil, false, false
+	}
+
+	// Get the file from the form
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		log.Printf("Error getting file from form: %v", err)
+		http.Error(w, "Error retrieving file", http.StatusBadRequest)
+		return nil, false, false
+	}
+	defer file.Close()
+
+	log.Printf("Received file: %s (size: %d bytes, type: %s)",
+		header.Filename, header.Size, header.Header.Get("Content-Type"))
+
+	// Validate file type
+	if !isAllowedFileType(header.Filename) {
+		log.Printf("Invalid file type: %s", filepath.Ext(header.Filename))
+		http.Error(w, "Invalid file type. Only jpg, jpeg, png, gif and webp are allowed", http.StatusBadRequest)
+		return nil, false, false
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		log.Printf("Error reading upload: %v", err)
+		http.Error(w, "Error retrieving file", http.StatusBadRequest)
+		return nil, false, false
+	}
+	hash := sha256.Sum256(data)
+	upload.ContentHash = hex.EncodeToString(hash[:])
+	upload.SizeBytes = int64(len(data))
+
+	// The same file may already be stored, by anyone
+	images, err = reuseBlob(db, store, upload)
+	if err != nil {
+		log.Printf("Error checking for a stored copy: %v", err)
+		http.Error(w, "Error uploading file", http.StatusInternalServerError)
+		return nil, false, false
+	}
+	if images != nil {
+		log.Printf("Reusing %s for %s", upload.ObjectKey, header.Filename)
+		return images, true, true
+	}
+
+	// The extension is only a hint; decode the file to be sure
+	variants, err := imaging.Process(data)
+	if errors.Is(err, imaging.ErrUnsupported) || errors.Is(err, imaging.ErrTooLarge) {
+		log.Printf("Rejected upload %s: %v", header.Filename, err)
+		http.Error(w, "The file is not a supported image, or its dimensions are too large", http.StatusBadRequest)
+		return nil, false, false
+	}
+	if err != nil {
+		log.Printf("Error processing image: %v", err)
+		http.Error(w, "Error processing image", http.StatusInternalServerError)
+		return nil, false, false
+	}
+
+	// Every size of one image shares a directory; the row points at the
+	// largest
+	dir := blobDir(upload.ContentHash)
+	full := variants[len(variants)-1]
+	upload.ObjectKey = imaging.VariantKey(dir, full.Size)
+	upload.Width, upload.Height = full.Width, full.Height
+	err = models.CreateUpload(db, upload)
+	if errors.Is(err, models.ErrUploadInProgress) {
+		// The same file submitted twice; the first is still being stored
+		log.Printf("Upload of %s already in progress", header.Filename)
+		http.Error(w, "This image is already being uploaded. Please wait a moment and try again.", http.StatusConflict)
+		return nil, false, false
+	}
+	if err != nil {
+		log.Printf("Error recording upload: %v", err)
+		http.Error(w, "Error uploading file", http.StatusInternalServerError)
+		return nil, false, false
+	}
+	log.Printf("Uploading %d sizes to %s/", len(variants), dir)
+
+	for _, v := range variants {
+		key := imaging.VariantKey(dir, v.Size)
+		if _, err := store.Put(ctx, key, bytes.NewReader(v.Data), imaging.ContentType); err != nil {
+			log.Printf("Error uploading %s: %v", key, err)
+			discardUpload(db, store, upload, images)
+			http.Error(w, "Error uploading file", http.StatusInternalServerError)
+			return nil, false, false
+		}
+		images = append(images, storedImage{Size: v.Size, Key: key, URL: store.PublicURL(key), Width: v.Width, Height: v.Height})
+	}
+
+	if err := models.MarkUploadStored(db, upload.UploadID); err != nil {
+		log.Printf("Error recording upload: %v", err)
+		discardUpload(db, store, upload, images)
+		http.Error(w, "Error uploading file", http.StatusInternalServerError)
+		return nil, false, false
+	}
+	upload.Status = models.UploadStored
+	log.Printf("Stored %s in %d sizes", header.Filename, len(images))
+
+	return images, false, true
+}
+
+// reuseBlob points upload at the stored copy of its content, if there is
+// one, and returns that copy's sizes. A user uploading the same image again
+// gets their existing upload back rather than a second one, linked to the
+// new upload's kanji if it names one. Returns nil images if the content has
+// to be stored.
+func reuseBlob(db *sql.DB, store storage.Store, upload *models.Upload) ([]storedImage, error) {
+	blob, err := models.GetUploadBlob(db, upload.ContentHash)
+	if errors.Is(err, models.ErrUploadNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	images := blobImages(store, blob)
+
+	existing, err := models.GetOwnerUpload(db, *upload.OwnerID, blob.ObjectKey)
+	if err == nil {
+		if k := upload.KanjiCharID; k != nil && (existing.KanjiCharID == nil || *existing.KanjiCharID != *k) {
+			if err := models.SetUploadKanji(db, existing.UploadID, *k); err != nil {
+				return nil, err
+			}
+			existing.KanjiCharID = k
+		}
+		*upload = *existing
+		return images, nil
+	}
+	if !errors.Is(err, models.ErrUploadNotFound) {
+		return nil, err
+	}
+
+	upload.ObjectKey = blob.ObjectKey
+	upload.Width, upload.Height = blob.Width, blob.Height
+	err = models.AddUploadReference(db, upload)
+	if errors.Is(err, models.ErrUploadNotFound) {
+		// Released since the lookup; store it afresh
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// blobImages lists the stored sizes of a blob, working out each size's
+// dimensions from the full size's
+func blobImages(store storage.Store, blob *models.UploadBlob) []storedImage {
+	keys := imaging.VariantKeys(blob.ObjectKey)
+	images := make([]storedImage, 0, len(keys))
+	for i, size := range imaging.Sizes {
+		if i >= len(keys) {
+			break
+		}
+		w, h := imaging.Fit(blob.Width, blob.Height, size.MaxSide)
+		images = append(images, storedImage{Size: size.Name, Key: keys[i], URL: store.PublicURL(keys[i]), Width: w, Height: h})
+	}
+	return images
+}
+
+// discardUpload removes the row of an upload that failed part way, and the
+// sizes already stored unless another upload of the same image uses them.
+// If the row can't be deleted it stays pending.
+func discardUpload(db *sql.DB, store storage.Store, upload *models.Upload, images []storedImage) {
+	gone, err := models.DiscardUpload(db, upload.UploadID)
+	if err != nil {
+		log.Printf("Error removing upload %d: %v", upload.UploadID, err)
+		return
+	}
+	if gone {
+		deleteStoredImages(store, images)
+	}
+}
+
+// deleteStoredImages removes the sizes already stored for an upload that
+// failed part way
+func deleteStoredImages(store storage.Store, images []storedImage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, img := range images {
+		if err := store.Delete(ctx, img.Key); err != nil {
+			log.Printf("Error deleting %s: %v", img.Key, err)
+		}
+	}
+}
+
+// ServeFileHandler serves a file from object storage. Deleted uploads are
+// hidden while they wait to be purged.
+func ServeFileHandler(db *sql.DB, store storage.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract the object name from the request
 		// Assumes path format like /files/{objectName}
@@ -179,31 +370,41 @@ func ServeFileHandler() http.HandlerFunc {
 			http.Error(w, "File not specified", http.StatusBadRequest)
 			return
 		}
-		
+
+		if strings.HasPrefix(objectName, uploadsPrefix) {
+			deleted, err := models.UploadIsDeleted(db, imaging.VariantKeys(objectName))
+			if err != nil {
+				log.Printf("Error checking upload %s: %v", objectName, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if deleted {
+				http.Error(w, "File not found", http.StatusNotFound)
+				return
+			}
+		}
+
 		// Set a reasonable timeout
 		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 		defer cancel()
-		
-		// Get bucket name from environment
-		bucketName := GetBucketName()
-		
-		// Get the object from GCS
-		object := storageClient.Bucket(bucketName).Object(objectName)
-		reader, err := object.NewReader(ctx)
+
+		// Get the object from storage
+		reader, obj, err := store.Get(ctx, objectName)
 		if err != nil {
-			log.Printf("Error creating reader for object %s: %v", objectName, err)
+			if !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, storage.ErrInvalidKey) {
+				log.Printf("Error creating reader for object %s: %v", objectName, err)
+			}
 			http.Error(w, "File not found", http.StatusNotFound)
 			return
 		}
 		defer reader.Close()
-		
+
 		// Set content type based on the object's metadata
-		w.Header().Set("Content-Type", reader.Attrs.ContentType)
-		
+		w.Header().Set("Content-Type", obj.ContentType)
+
 		// Copy the file contents to the response
 		if _, err := io.Copy(w, reader); err != nil {
 			log.Printf("Error serving file: %v", err)
-			http.Error(w, "Error serving file", http.StatusInternalServerError)
 			return
 		}
 	}
@@ -213,172 +414,179 @@ func ServeFileHandler() http.HandlerFunc {
 func isAllowedFileType(filename string) bool {
 	ext := strings.ToLower(filepath.Ext(filename))
 	switch ext {
-	case ".jpg", ".jpeg", ".png", ".gif":
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
 		return true
 	}
 	return false
 }
 
-// generateUniqueFilename creates a unique filename to prevent collisions
-func generateUniqueFilename(originalFilename string) string {
-	ext := filepath.Ext(originalFilename)
-	return uuid.New().String() + ext
-}
-
-// getContentType determines the content type based on file extension
-func getContentType(filename string) string {
-	ext := strings.ToLower(filepath.Ext(filename))
-	switch ext {
-	case ".jpg", ".jpeg":
-		return "image/jpeg"
-	case ".png":
-		return "image/png"
-	case ".gif":
-		return "image/gif"
-	default:
-		return "application/octet-stream" // Default content type
-	}
-}
-
 // FileData represents file information
 type FileData struct {
-    Name      string
-    SizeKB    int64
-    Created   string
-    PublicURL string
+	UploadID  int64
+	Name      string
+	SizeKB    int64
+	Width     int
+	Height    int
+	Created   string
+	PublicURL string
+	ThumbURL  string
+	Owner     string // empty if the owner's account is gone
+	KanjiChar string // empty if not linked to a kanji
+	CanDelete bool   // the viewer owns it or is an admin
 }
 
-// ListFilesHandler lists files in the Cloud Storage bucket
-func ListFilesHandler(tmpl *template.Template) http.HandlerFunc {
-    return func(w http.ResponseWriter, r *http.Request) {
-        ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-        defer cancel()
-        
-        // Check if storageClient is initialized
-        if storageClient == nil {
-            log.Println("Storage client not initialized, initializing now")
-            if err := InitStorage(ctx); err != nil {
-                log.Printf("Failed to initialize storage client: %v", err)
-                http.Error(w, "Internal server error", http.StatusInternalServerError)
-                return
-            }
-        }
-        
-        // Get bucket name from environment
-        bucketName := GetBucketName()
-        
-        // List objects in the bucket
-        it := storageClient.Bucket(bucketName).Objects(ctx, &storage.Query{
-            Prefix: "uploads/", // Optional: filter by prefix
-        })
-        
-        // Create a slice to hold file data
-        var files []FileData
-        
-        // Process each object
-        for {
-            attrs, err := it.Next()
-            if err == iterator.Done {
-                break
-            }
-            if err != nil {
-                log.Printf("Error iterating bucket objects: %v", err)
-                http.Error(w, "Error listing files", http.StatusInternalServerError)
-                return
-            }
-            
-            // Only display images
-            if isAllowedFileType(attrs.Name) {
-                // Generate a public URL for the file
-                publicURL := fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucketName, attrs.Name)
-                
-                file := FileData{
-                    Name:      attrs.Name,
-                    SizeKB:    attrs.Size / 1024,
-                    Created:   attrs.Created.Format("2006-01-02"),
-                    PublicURL: publicURL,
-                }
-                
-                files = append(files, file)
-            }
-        }
-        
-        // Prepare template data
-        data := map[string]any{
-            "Files": files,
-        }
-        
-        // Execute the template
-        w.Header().Set("Content-Type", "text/html")
-        if err := tmpl.ExecuteTemplate(w, "files-list", data); err != nil {
-            log.Printf("Error executing files-list template: %v", err)
-            http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-        }
-    }
+// ListFilesHandler lists uploaded files from the uploads table, newest
+// first, one page at a time
+func ListFilesHandler(db *sql.DB, store storage.Store, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := currentUserOrNil(db, w, r)
+		if !ok {
+			return
+		}
+
+		// The page token is the ID of the last upload on the previous page
+		var before int64
+		if token := r.URL.Query().Get("page"); token != "" {
+			id, err := strconv.ParseInt(token, 10, 64)
+			if err != nil || id <= 0 {
+				http.Error(w, "Invalid page token", http.StatusBadRequest)
+				return
+			}
+			before = id
+		}
+
+		uploads, next, err := models.ListUploads(db, before, filesPageSize)
+		if err != nil {
+			log.Printf("Error listing uploads: %v", err)
+			http.Error(w, "Error listing files", http.StatusInternalServerError)
+			return
+		}
+
+		// Create a slice to hold file data
+		var files []FileData
+		for _, u := range uploads {
+			file := FileData{
+				UploadID:  u.UploadID,
+				Name:      u.ObjectKey,
+				SizeKB:    u.SizeBytes / 1024,
+				Width:     u.Width,
+				Height:    u.Height,
+				Created:   u.CreatedAt.Format("2006-01-02"),
+				PublicURL: store.PublicURL(u.ObjectKey),
+				ThumbURL:  store.PublicURL(imaging.VariantKeys(u.ObjectKey)[0]),
+				CanDelete: canDeleteUpload(user, &u),
+			}
+			if u.OwnerName != nil {
+				file.Owner = *u.OwnerName
+			}
+			if u.KanjiChar != nil {
+				file.KanjiChar = *u.KanjiChar
+			}
+			files = append(files, file)
+		}
+
+		nextToken := ""
+		if next > 0 {
+			nextToken = strconv.FormatInt(next, 10)
+		}
+
+		// Prepare template data
+		data := map[string]any{
+			"Files":         files,
+			"NextPageToken": nextToken,
+		}
+
+		// Execute the template
+		w.Header().Set("Content-Type", "text/html")
+		if err := tmpl.ExecuteTemplate(w, "files-list", data); err != nil {
+			log.Printf("Error executing files-list template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
 }
 
-// DeleteFileHandler deletes a file from Google Cloud Storage
-func DeleteFileHandler() http.HandlerFunc {
+// DeleteFileHandler soft-deletes an upload: it disappears at once and is
+// purged after cleanup.DeletedUploadRetention, taking the stored image with
+// it if no other upload shares it. Users may delete their own uploads,
+// admins anyone's; every deletion is audited.
+func DeleteFileHandler(db *sql.DB, store storage.Store, tmpl *template.Template) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST requests for deletion
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		
-		// Extract the object name from the request
+
+		user, ok := requireUser(db, w, r, tmpl)
+		if !ok {
+			return
+		}
+
+		// Extract the upload from the request
 		if err := r.ParseForm(); err != nil {
 			log.Printf("Error parsing form: %v", err)
 			http.Error(w, "Error parsing form", http.StatusBadRequest)
 			return
 		}
-		
-		objectName := r.FormValue("objectName")
-		if objectName == "" {
-			http.Error(w, "Object name not provided", http.StatusBadRequest)
+
+		// Objects can be shared by several users' uploads, so deletes name
+		// the upload rather than the object
+		uploadID, err := strconv.ParseInt(r.FormValue("uploadID"), 10, 64)
+		if err != nil || uploadID <= 0 {
+			http.Error(w, "Invalid upload ID", http.StatusBadRequest)
 			return
 		}
-		
-		log.Printf("Request to delete object: %s", objectName)
-		
-		// Set a reasonable timeout
-		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
-		defer cancel()
-		
-		// Get bucket name from environment
-		bucketName := GetBucketName()
-		
-		// Check if storageClient is initialized
-		if storageClient == nil {
-			log.Println("Storage client not initialized, initializing now")
-			if err := InitStorage(ctx); err != nil {
-				log.Printf("Failed to initialize storage client: %v", err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+
+		log.Printf("Request by %s to delete upload: %d", user.Username, uploadID)
+
+		upload, err := models.GetUpload(db, uploadID)
+		if err != nil && !errors.Is(err, models.ErrUploadNotFound) {
+			log.Printf("Error loading upload %d: %v", uploadID, err)
+			http.Error(w, "Error deleting file", http.StatusInternalServerError)
+			return
+		}
+		if err != nil || upload.Status != models.UploadStored {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		if !canDeleteUpload(user, upload) {
+			http.Error(w, "You can only delete your own uploads", http.StatusForbidden)
+			return
+		}
+
+		asAdmin := upload.OwnerID == nil || *upload.OwnerID != user.ID
+		if err := models.SoftDeleteUpload(db, upload.UploadID, user, asAdmin); err != nil {
+			if errors.Is(err, models.ErrUploadNotFound) {
+				http.Error(w, "File not found", http.StatusNotFound)
 				return
 			}
-		}
-		
-		// Delete the object
-		object := storageClient.Bucket(bucketName).Object(objectName)
-		if err := object.Delete(ctx); err != nil {
-			log.Printf("Error deleting object %s: %v", objectName, err)
+			log.Printf("Error deleting upload %d: %v", upload.UploadID, err)
 			http.Error(w, "Error deleting file", http.StatusInternalServerError)
 			return
 		}
-		
-		log.Printf("Successfully deleted object: %s", objectName)
-		
+
+		log.Printf("Successfully deleted upload %d of %s", upload.UploadID, upload.ObjectKey)
+
 		// Return success response for HTMX
 		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte(`
+		fmt.Fprintf(w, `
 			<div class="delete-success bg-blue-100 border border-blue-400 text-blue-700 px-4 py-3 rounded mb-4">
-				<p>File deleted successfully!</p>
+				<p>File deleted successfully! The stored image is removed after %d days unless another upload shares it.</p>
 				<form hx-get="/list-files" hx-target="#files-list" class="mt-2">
 					<button type="submit" class="bg-blue-500 hover:bg-blue-700 text-white text-xs py-1 px-2 rounded">
 						Refresh File List
 					</button>
 				</form>
 			</div>
-		`))
+		`, int(cleanup.DeletedUploadRetention.Hours()/24))
+	}
+}
+
+// canDeleteUpload reports whether user (nil when anonymous) may delete the
+// upload: their own, or anyone's for an admin
+func canDeleteUpload(user *models.User, u *models.Upload) bool {
+	if user == nil {
+		return false
 	}
+	return user.IsAdmin || (u.OwnerID != nil && *u.OwnerID == user.ID)
 }
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// strokeDelay is the pause between strokes when the whole kanji is
+// animated; each stroke takes about this long to draw
+const strokeDelay = 0.7
+
+// strokeView is one stroke of a diagram
+type strokeView struct {
+	models.Stroke
+	State string // "done", "current" (animated) or "todo" (faint)
+	Delay string // CSS animation-delay for current strokes
+}
+
+// strokePlayerView is the template data for the stroke-player and
+// stroke-svg fragments
+type strokePlayerView struct {
+	KanjiChar string
+	Strokes   []strokeView
+	Step      int // stroke being drawn, or 0 to animate them all
+	Prev      int
+	Next      int // 0 on the last stroke
+}
+
+// strokeCountResponse is the JSON form of a kanji's stroke order
+type strokeCountResponse struct {
+	Kanji       string          `json:"kanji"`
+	StrokeCount int             `json:"stroke_count"`
+	Strokes     []models.Stroke `json:"strokes"`
+}
+
+// KanjiStrokesHandler renders the stroke order player for /kanji/{char}:
+// ?step=N draws stroke N over the ones before it, no step animates every
+// stroke in turn. ?format=json returns the strokes and their count.
+func KanjiStrokesHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kanji, strokes, ok := kanjiStrokes(w, r, db)
+		if !ok {
+			return
+		}
+
+		if r.URL.Query().Get("format") == "json" {
+			resp := strokeCountResponse{Kanji: kanji.KanjiChar, StrokeCount: len(strokes), Strokes: strokes}
+			if resp.Strokes == nil {
+				resp.Strokes = []models.Stroke{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				log.Printf("Error encoding strokes: %v", err)
+			}
+			return
+		}
+
+		step := 0
+		if s := r.URL.Query().Get("step"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 || n > len(strokes) {
+				http.Error(w, "Invalid step", http.StatusBadRequest)
+				return
+			}
+			step = n
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		if err := tmpl.ExecuteTemplate(w, "stroke-player", strokePlayer(kanji.KanjiChar, strokes, step)); err != nil {
+			log.Printf("Error executing stroke-player template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// KanjiStrokesSVGHandler serves /kanji/{char}/strokes.svg: every stroke
+// with its number, for printing or linking to
+func KanjiStrokesSVGHandler(db *sql.DB, tmpl *template.Template) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kanji, strokes, ok := kanjiStrokes(w, r, db)
+		if !ok {
+			return
+		}
+		if len(strokes) == 0 {
+			http.Error(w, "No stroke order for this kanji", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		if err := tmpl.ExecuteTemplate(w, "stroke-svg", strokePlayer(kanji.KanjiChar, strokes, 0)); err != nil {
+			log.Printf("Error executing stroke-svg template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// kanjiStrokes looks up the {char} kanji and its strokes
+func kanjiStrokes(w http.ResponseWriter, r *http.Request, db *sql.DB) (*models.Kanji, []models.Stroke, bool) {
+	char := chi.URLParam(r, "char")
+	if utf8.RuneCountInString(char) != 1 {
+		http.Error(w, "Kanji not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	kanji, err := models.GetKanjiByChar(db, char)
+	if err != nil {
+		kanjiLookupError(w, err)
+		return nil, nil, false
+	}
+	strokes, err := models.GetKanjiStrokes(db, kanji.KanjiCharID)
+	if err != nil {
+		log.Printf("Error loading strokes: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return kanji, strokes, true
+}
+
+// strokePlayer builds the player for one step: strokes before it drawn,
+// the step's stroke animated and the rest shown faintly. Step 0 animates
+// every stroke one after another.
+func strokePlayer(char string, strokes []models.Stroke, step int) strokePlayerView {
+	data := strokePlayerView{KanjiChar: char, Step: step, Prev: max(step-1, 1)}
+	if step < len(strokes) {
+		data.Next = step + 1
+	}
+	for i, s := range strokes {
+		v := strokeView{Stroke: s, State: "current", Delay: "0s"}
+		switch {
+		case step == 0:
+			v.Delay = fmt.Sprintf("%.1fs", float64(i)*strokeDelay)
+		case s.Number < step:
+			v.State = "done"
+		case s.Number > step:
+			v.State = "todo"
+		}
+		data.Strokes = append(data.Strokes, v)
+	}
+	return data
+}
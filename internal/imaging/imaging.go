@@ -0,0 +1,167 @@
+// Package imaging turns uploaded images into the sizes the app serves:
+// decoded and checked by content, turned upright, stripped of metadata and
+// re-encoded as WebP.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register the GIF decoder
+	_ "image/jpeg" // register the JPEG decoder
+	_ "image/png"  // register the PNG decoder
+	"path"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// ErrUnsupported is returned for data that isn't a JPEG, PNG, GIF or WebP
+// image, whatever its file name says
+var ErrUnsupported = errors.New("unsupported image format")
+
+// ErrTooLarge is returned for images with more pixels than MaxPixels
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// MaxPixels caps width*height, so a small file can't decode into a huge
+// bitmap. 16 MP is what most phone cameras take and decodes to 64 MB at
+// most.
+const MaxPixels = 16_000_000
+
+// ContentType is the type of every variant
+const ContentType = "image/webp"
+
+// Size is one derived size: the image is scaled down to fit in a
+// MaxSide x MaxSide box, never up
+type Size struct {
+	Name    string
+	MaxSide int
+}
+
+// Sizes are the variants stored for every upload, smallest first. WebP is
+// written lossless, which suits drawn mnemonics but makes large photos
+// heavy, so "full" stays modest.
+var Sizes = []Size{
+	{Name: "thumb", MaxSide: 160},
+	{Name: "card", MaxSide: 480},
+	{Name: "full", MaxSide: 1280},
+}
+
+// Variant is an encoded size of an image
+type Variant struct {
+	Size   string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// allowedFormats are the image.Decode format names accepted
+var allowedFormats = map[string]bool{"jpeg": true, "png": true, "gif": true, "webp": true}
+
+// Process decodes an uploaded image and returns one Variant per Size. JPEGs
+// are rotated according to their EXIF orientation, once scaled down to the
+// largest size; no metadata is carried over. Animated GIFs keep only their
+// first frame.
+func Process(data []byte) ([]Variant, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !allowedFormats[format] {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	// Every size is scaled from the largest, which is small enough to turn
+	// upright without copying the decoded bitmap. The boxes are square, so
+	// scaling first doesn't change the upright sizes.
+	bw, bh := Fit(img.Bounds().Dx(), img.Bounds().Dy(), Sizes[len(Sizes)-1].MaxSide)
+	base := image.NewNRGBA(image.Rect(0, 0, bw, bh))
+	if bw == img.Bounds().Dx() && bh == img.Bounds().Dy() {
+		draw.Draw(base, base.Bounds(), img, img.Bounds().Min, draw.Src)
+	} else {
+		draw.CatmullRom.Scale(base, base.Bounds(), img, img.Bounds(), draw.Src, nil)
+	}
+	img = base
+	if format == "jpeg" {
+		img = orient(base, exifOrientation(data))
+	}
+
+	variants := make([]Variant, 0, len(Sizes))
+	for _, size := range Sizes {
+		w, h := Fit(img.Bounds().Dx(), img.Bounds().Dy(), size.MaxSide)
+
+		// A small image comes out the same in several sizes; encode it once
+		if n := len(variants); n > 0 && variants[n-1].Width == w && variants[n-1].Height == h {
+			v := variants[n-1]
+			v.Size = size.Name
+			variants = append(variants, v)
+			continue
+		}
+
+		dst := img
+		if w != img.Bounds().Dx() || h != img.Bounds().Dy() {
+			scaled := image.NewNRGBA(image.Rect(0, 0, w, h))
+			draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+			dst = scaled
+		}
+
+		var buf bytes.Buffer
+		if err := nativewebp.Encode(&buf, dst, nil); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", size.Name, err)
+		}
+		variants = append(variants, Variant{Size: size.Name, Width: w, Height: h, Data: buf.Bytes()})
+	}
+	return variants, nil
+}
+
+// Fit scales w x h down to fit in a side x side box, keeping the aspect
+// ratio
+func Fit(w, h, side int) (int, int) {
+	if w <= side && h <= side {
+		return w, h
+	}
+	if w >= h {
+		return side, max(1, h*side/w)
+	}
+	return max(1, w*side/h), side
+}
+
+// VariantKey is where a size of the upload stored under dir is kept
+func VariantKey(dir, size string) string {
+	return dir + "/" + size + ".webp"
+}
+
+// VariantKeys returns the keys of every size stored alongside key, or just
+// key if it isn't a variant (uploads from before sizes were kept)
+func VariantKeys(key string) []string {
+	dir, file := path.Split(key)
+	size, ok := strings.CutSuffix(file, ".webp")
+	if !ok || dir == "" || !isSize(size) {
+		return []string{key}
+	}
+	dir = strings.TrimSuffix(dir, "/")
+
+	keys := make([]string, 0, len(Sizes))
+	for _, s := range Sizes {
+		keys = append(keys, VariantKey(dir, s.Name))
+	}
+	return keys
+}
+
+// isSize reports whether name is one of Sizes
+func isSize(name string) bool {
+	for _, s := range Sizes {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
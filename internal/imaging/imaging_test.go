@@ -0,0 +1,157 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, side   int
+		wantW, wantH int
+	}{
+		{100, 50, 160, 100, 50},   // already fits
+		{160, 160, 160, 160, 160}, // exactly the box
+		{3200, 1600, 1280, 1280, 640},
+		{1600, 3200, 1280, 640, 1280},
+		{4000, 3000, 480, 480, 360},
+		{10000, 10, 160, 160, 1}, // never rounds to nothing
+		{10, 10000, 160, 1, 160},
+	}
+	for _, tt := range tests {
+		if w, h := Fit(tt.w, tt.h, tt.side); w != tt.wantW || h != tt.wantH {
+			t.Errorf("Fit(%d, %d, %d) = %d, %d; want %d, %d", tt.w, tt.h, tt.side, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestVariantKeys(t *testing.T) {
+	sizes := []string{
+		"uploads/sha256/abc/thumb.webp",
+		"uploads/sha256/abc/card.webp",
+		"uploads/sha256/abc/full.webp",
+	}
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"uploads/sha256/abc/full.webp", sizes},
+		{"uploads/sha256/abc/thumb.webp", sizes},
+		{"uploads/sha256/abc/card.webp", sizes},
+
+		// Legacy uploads are a single file
+		{"uploads/1700000000_cat.png", []string{"uploads/1700000000_cat.png"}},
+		{"uploads/1700000000_cat.webp", []string{"uploads/1700000000_cat.webp"}},
+		{"uploads/sha256/abc/full.png", []string{"uploads/sha256/abc/full.png"}},
+		{"full.webp", []string{"full.webp"}},
+	}
+	for _, tt := range tests {
+		if got := VariantKeys(tt.key); !slices.Equal(got, tt.want) {
+			t.Errorf("VariantKeys(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	if got := VariantKey("uploads/sha256/abc", "card"); got != sizes[1] {
+		t.Errorf("VariantKey = %q, want %q", got, sizes[1])
+	}
+}
+
+// encodeJPEG encodes a w x h image, with the EXIF orientation if it isn't 0
+func encodeJPEG(t *testing.T, w, h int, orientation uint16) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+
+	// Splice the APP1 segment in after SOI
+	app1 := exifJPEG(binary.BigEndian, orientation)
+	app1 = app1[2 : len(app1)-4]
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		thumb, full [2]int // width and height
+	}{
+		{"landscape", encodeJPEG(t, 400, 200, 0), [2]int{160, 80}, [2]int{400, 200}},
+		{"rotated", encodeJPEG(t, 400, 200, 6), [2]int{80, 160}, [2]int{200, 400}},
+		{"scaled then rotated", encodeJPEG(t, 2000, 1000, 8), [2]int{80, 160}, [2]int{640, 1280}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := Process(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(variants) != len(Sizes) {
+				t.Fatalf("%d variants, want %d", len(variants), len(Sizes))
+			}
+			thumb, full := variants[0], variants[len(variants)-1]
+			if thumb.Width != tt.thumb[0] || thumb.Height != tt.thumb[1] {
+				t.Errorf("thumb is %dx%d, want %dx%d", thumb.Width, thumb.Height, tt.thumb[0], tt.thumb[1])
+			}
+			if full.Width != tt.full[0] || full.Height != tt.full[1] {
+				t.Errorf("full is %dx%d, want %dx%d", full.Width, full.Height, tt.full[0], tt.full[1])
+			}
+
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(full.Data))
+			if err != nil || format != "webp" {
+				t.Fatalf("full size decodes as %q: %v", format, err)
+			}
+			if cfg.Width != full.Width || cfg.Height != full.Height {
+				t.Errorf("full size encoded at %dx%d, recorded as %dx%d", cfg.Width, cfg.Height, full.Width, full.Height)
+			}
+		})
+	}
+}
+
+func TestProcessSmallImage(t *testing.T) {
+	// Smaller than every box: each size is the same encoding
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+	variants, err := Process(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range variants {
+		if v.Size != Sizes[i].Name || v.Width != 40 || v.Height != 30 {
+			t.Errorf("variant %d is %s %dx%d, want %s 40x30", i, v.Size, v.Width, v.Height, Sizes[i].Name)
+		}
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	// A GIF header claiming 5000x5000, more than MaxPixels; only the
+	// header is read
+	huge := []byte("GIF89a\x88\x13\x88\x13\x00\x00\x00")
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"too large", huge, ErrTooLarge},
+		{"not an image", []byte("<svg xmlns='http://www.w3.org/2000/svg'/>"), ErrUnsupported},
+		{"empty", nil, ErrUnsupported},
+	}
+	for _, tt := range tests {
+		if _, err := Process(tt.data); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Process error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+)
+
+// tiffHeader builds a TIFF header whose IFD0 holds the given tags, each a
+// SHORT
+func tiffHeader(order binary.ByteOrder, tags map[uint16]uint16) []byte {
+	tiff := make([]byte, 10, 10+12*len(tags))
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], uint16(len(tags)))
+
+	for tag, value := range tags {
+		entry := make([]byte, 12)
+		order.PutUint16(entry, tag)
+		order.PutUint16(entry[2:], 3) // SHORT
+		order.PutUint32(entry[4:], 1)
+		order.PutUint16(entry[8:], value)
+		tiff = append(tiff, entry...)
+	}
+	return tiff
+}
+
+// jpegWithSegment builds the start of a JPEG: SOI, one marker segment with
+// the given payload, then the start of scan
+func jpegWithSegment(marker byte, payload []byte) []byte {
+	data := []byte{0xFF, 0xD8, 0xFF, marker}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(payload)+2))
+	data = append(data, payload...)
+	return append(data, 0xFF, 0xDA, 0x00, 0x02)
+}
+
+// exifJPEG builds a JPEG header carrying the EXIF orientation
+func exifJPEG(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := tiffHeader(order, map[uint16]uint16{0x0112: orientation})
+	return jpegWithSegment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+func TestExifOrientation(t *testing.T) {
+	valid := exifJPEG(binary.BigEndian, 6)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"little endian", exifJPEG(binary.LittleEndian, 6), 6},
+		{"big endian", exifJPEG(binary.BigEndian, 8), 8},
+		{"upright", exifJPEG(binary.LittleEndian, 1), 1},
+		{"zero", exifJPEG(binary.LittleEndian, 0), 1},
+		{"out of range", exifJPEG(binary.BigEndian, 9), 1},
+		{"other tags only", jpegWithSegment(0xE1, append([]byte("Exif\x00\x00"),
+			tiffHeader(binary.LittleEndian, map[uint16]uint16{0x0100: 640})...)), 1},
+		{"after another segment", append([]byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x04, 'J', 'F'}, valid[2:]...), 6},
+		{"not exif", jpegWithSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00")), 1},
+		{"bad byte order", jpegWithSegment(0xE1, []byte("Exif\x00\x00XX\x00\x2A\x00\x00\x00\x08\x00\x00")), 1},
+		{"truncated segment", valid[:len(valid)-8], 1},
+		{"truncated IFD", func() []byte {
+			// The segment is whole but claims more entries than it holds,
+			// the orientation among the missing ones
+			tiff := tiffHeader(binary.BigEndian, map[uint16]uint16{0x0100: 640})
+			binary.BigEndian.PutUint16(tiff[8:], 3)
+			return jpegWithSegment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+		}(), 1},
+		{"no exif", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}, 1},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.data); got != tt.want {
+				t.Errorf("exifOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// labelled builds an image whose pixels are the letters of rows, one row
+// per string
+func labelled(rows ...string) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x := range len(row) {
+			img.SetNRGBA(x, y, color.NRGBA{R: row[x], A: 255})
+		}
+	}
+	return img
+}
+
+// labels reads back the letters of an image built by labelled
+func labels(img image.Image) []string {
+	b := img.Bounds()
+	rows := make([]string, 0, b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := make([]byte, 0, b.Dx())
+		for x := b.Min.X; x < b.Max.X; x++ {
+			row = append(row, color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA).R)
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+func TestOrient(t *testing.T) {
+	// Each orientation says how the stored pixels were turned, so orient
+	// undoes it
+	src := []string{
+		"ab",
+		"cd",
+		"ef",
+	}
+	tests := []struct {
+		orientation int
+		want        []string
+	}{
+		{0, src},
+		{1, src},
+		{2, []string{"ba", "dc", "fe"}},
+		{3, []string{"fe", "dc", "ba"}},
+		{4, []string{"ef", "cd", "ab"}},
+		{5, []string{"ace", "bdf"}},
+		{6, []string{"eca", "fdb"}},
+		{7, []string{"fdb", "eca"}},
+		{8, []string{"bdf", "ace"}},
+		{9, src},
+	}
+	for _, tt := range tests {
+		if got := labels(orient(labelled(src...), tt.orientation)); !slices.Equal(got, tt.want) {
+			t.Errorf("orient(%d) = %q, want %q", tt.orientation, got, tt.want)
+		}
+	}
+}
+
+func TestOrientOffsetBounds(t *testing.T) {
+	// A sub-image doesn't start at the origin
+	img := labelled("xxx", "xab", "xcd").SubImage(image.Rect(1, 1, 3, 3))
+	got := labels(orient(img, 6))
+	if !slices.Equal(got, []string{"ca", "db"}) {
+		t.Errorf("orient(sub-image, 6) = %q, want [ca db]", got)
+	}
+}
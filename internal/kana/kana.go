@@ -0,0 +1,120 @@
+// Package kana converts between romaji, hiragana and katakana and
+// normalizes readings for matching.
+package kana
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Unicode layout of the kana blocks: each katakana is its hiragana + 0x60
+const (
+	hiraganaFirst = 'ぁ'
+	hiraganaLast  = 'ゖ'
+	katakanaFirst = 'ァ'
+	katakanaLast  = 'ヶ'
+	kanaOffset    = katakanaFirst - hiraganaFirst
+	prolonged     = 'ー'
+)
+
+// IsHiragana reports whether r is a hiragana letter
+func IsHiragana(r rune) bool {
+	return r >= hiraganaFirst && r <= hiraganaLast
+}
+
+// IsKatakana reports whether r is a katakana letter
+func IsKatakana(r rune) bool {
+	return r >= katakanaFirst && r <= katakanaLast
+}
+
+// IsKana reports whether r is hiragana, katakana or the prolonged sound mark
+func IsKana(r rune) bool {
+	return IsHiragana(r) || IsKatakana(r) || r == prolonged
+}
+
+// IsKanji reports whether r is a Han character
+func IsKanji(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+// ToHiragana converts katakana to hiragana, leaving everything else as is
+func ToHiragana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if IsKatakana(r) {
+			return r - kanaOffset
+		}
+		return r
+	}, s)
+}
+
+// vowelRows lists hiragana by the vowel they end in
+var vowelRows = map[rune]string{
+	'あ': "あかさたなはまやらわがざだばぱぁゃゎ",
+	'い': "いきしちにひみりゐぎじぢびぴぃ",
+	'う': "うくすつぬふむゆるぐずづぶぷゔぅゅ",
+	'え': "えけせてねへめれゑげぜでべぺぇ",
+	'お': "おこそとのほもよろをごぞどぼぽぉょ",
+}
+
+// vowelOf maps a hiragana to the vowel it ends in
+var vowelOf = func() map[rune]rune {
+	m := make(map[rune]rune)
+	for vowel, row := range vowelRows {
+		for _, r := range row {
+			m[r] = vowel
+		}
+	}
+	return m
+}()
+
+// Normalize converts s to hiragana and spells out the prolonged sound mark,
+// so that "トーキョー" and "とうきょう" compare equal after FoldLongVowels
+func Normalize(s string) string {
+	s = ToHiragana(s)
+	if !strings.ContainsRune(s, prolonged) {
+		return s
+	}
+
+	var b strings.Builder
+	var prev rune
+	for _, r := range s {
+		if r == prolonged {
+			if v, ok := vowelOf[prev]; ok {
+				r = v
+			}
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+// FoldLongVowels drops vowels that only lengthen the previous syllable
+// (おう, おお, えい, ああ ...), so that romaji typed without long vowels
+// still matches: "tokyo" and "toukyou" both fold to ときょ. The result is
+// only meant as a comparison key.
+func FoldLongVowels(s string) string {
+	var b strings.Builder
+	var prevVowel rune
+	for _, r := range s {
+		if prevVowel != 0 && isLengthening(prevVowel, r) {
+			continue
+		}
+		b.WriteRune(r)
+		prevVowel = vowelOf[r]
+	}
+	return b.String()
+}
+
+// isLengthening reports whether vowel r lengthens a syllable ending in prev
+func isLengthening(prev, r rune) bool {
+	switch r {
+	case prev:
+		return true
+	case 'う':
+		return prev == 'お'
+	case 'い':
+		return prev == 'え'
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package kana
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// romajiTable maps romaji syllables to hiragana. It accepts Hepburn spellings
+// along with the common Kunrei/Nihon-shiki and IME ("x"/"l" small kana)
+// variants.
+var romajiTable = map[string]string{
+	"a": "あ", "i": "い", "u": "う", "e": "え", "o": "お",
+
+	"ka": "か", "ki": "き", "ku": "く", "ke": "け", "ko": "こ",
+	"kya": "きゃ", "kyu": "きゅ", "kyo": "きょ",
+	"ga": "が", "gi": "ぎ", "gu": "ぐ", "ge": "げ", "go": "ご",
+	"gya": "ぎゃ", "gyu": "ぎゅ", "gyo": "ぎょ",
+
+	"sa": "さ", "shi": "し", "si": "し", "su": "す", "se": "せ", "so": "そ",
+	"sha": "しゃ", "shu": "しゅ", "sho": "しょ", "she": "しぇ",
+	"sya": "しゃ", "syu": "しゅ", "syo": "しょ", "sye": "しぇ",
+	"za": "ざ", "ji": "じ", "zi": "じ", "zu": "ず", "ze": "ぜ", "zo": "ぞ",
+	"ja": "じゃ", "ju": "じゅ", "jo": "じょ", "je": "じぇ",
+	"zya": "じゃ", "zyu": "じゅ", "zyo": "じょ", "zye": "じぇ",
+	"jya": "じゃ", "jyu": "じゅ", "jyo": "じょ",
+
+	"ta": "た", "chi": "ち", "ti": "ち", "tsu": "つ", "tu": "つ", "te": "て", "to": "と",
+	"cha": "ちゃ", "chu": "ちゅ", "cho": "ちょ", "che": "ちぇ",
+	"tya": "ちゃ", "tyu": "ちゅ", "tyo": "ちょ", "tye": "ちぇ",
+	"da": "だ", "di": "ぢ", "du": "づ", "de": "で", "do": "ど",
+	"dya": "ぢゃ", "dyu": "ぢゅ", "dyo": "ぢょ",
+
+	"na": "な", "ni": "に", "nu": "ぬ", "ne": "ね", "no": "の",
+	"nya": "にゃ", "nyu": "にゅ", "nyo": "にょ",
+
+	"ha": "は", "hi": "ひ", "fu": "ふ", "hu": "ふ", "he": "へ", "ho": "ほ",
+	"hya": "ひゃ", "hyu": "ひゅ", "hyo": "ひょ",
+	"fa": "ふぁ", "fi": "ふぃ", "fe": "ふぇ", "fo": "ふぉ",
+	"ba": "ば", "bi": "び", "bu": "ぶ", "be": "べ", "bo": "ぼ",
+	"bya": "びゃ", "byu": "びゅ", "byo": "びょ",
+	"pa": "ぱ", "pi": "ぴ", "pu": "ぷ", "pe": "ぺ", "po": "ぽ",
+	"pya": "ぴゃ", "pyu": "ぴゅ", "pyo": "ぴょ",
+
+	"ma": "ま", "mi": "み", "mu": "む", "me": "め", "mo": "も",
+	"mya": "みゃ", "myu": "みゅ", "myo": "みょ",
+
+	"ya": "や", "yu": "ゆ", "yo": "よ",
+
+	"ra": "ら", "ri": "り", "ru": "る", "re": "れ", "ro": "ろ",
+	"rya": "りゃ", "ryu": "りゅ", "ryo": "りょ",
+
+	"wa": "わ", "wi": "ゐ", "we": "ゑ", "wo": "を",
+	"vu": "ゔ",
+
+	"xa": "ぁ", "xi": "ぃ", "xu": "ぅ", "xe": "ぇ", "xo": "ぉ",
+	"la": "ぁ", "li": "ぃ", "lu": "ぅ", "le": "ぇ", "lo": "ぉ",
+	"xya": "ゃ", "xyu": "ゅ", "xyo": "ょ", "xwa": "ゎ",
+	"lya": "ゃ", "lyu": "ゅ", "lyo": "ょ", "lwa": "ゎ",
+	"xtu": "っ", "xtsu": "っ", "ltu": "っ", "ltsu": "っ",
+	"xn": "ん",
+}
+
+// longestSyllable is the length of the longest key in romajiTable
+const longestSyllable = 4
+
+// macrons maps long-vowel marks to the kana they stand for. Hepburn ō is
+// usually おう (東京 とうきょう), so that is what it becomes.
+var macrons = map[rune]string{
+	'ā': "aa", 'ī': "ii", 'ū': "uu", 'ē': "ee", 'ō': "ou",
+	'â': "aa", 'î': "ii", 'û': "uu", 'ê': "ee", 'ô': "ou",
+}
+
+// FromRomaji converts romaji to hiragana. Spaces, commas, the "." okurigana
+// marker and "-" affix markers are kept; ok is false if anything else could
+// not be read as romaji, and the unconverted text is kept in the result.
+func FromRomaji(s string) (string, bool) {
+	s = strings.ToLower(s)
+	if strings.ContainsFunc(s, func(r rune) bool { _, ok := macrons[r]; return ok }) {
+		var b strings.Builder
+		for _, r := range s {
+			if m, ok := macrons[r]; ok {
+				b.WriteString(m)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		s = b.String()
+	}
+
+	var out strings.Builder
+	ok := true
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		// ん: "nn", "n'" and "n" not starting a syllable
+		if c == 'n' {
+			if i+1 == len(s) {
+				out.WriteString("ん")
+				i++
+				continue
+			}
+			next := s[i+1]
+			if next == '\'' {
+				out.WriteString("ん")
+				i += 2
+				continue
+			}
+			// "nn" is ん, unless the second n starts a syllable (konnichiha)
+			if next == 'n' {
+				out.WriteString("ん")
+				i++
+				if i+1 == len(s) || (!isVowel(s[i+1]) && s[i+1] != 'y') {
+					i++
+				}
+				continue
+			}
+			if !isVowel(next) && next != 'y' {
+				out.WriteString("ん")
+				i++
+				continue
+			}
+		}
+
+		// Hepburn writes ん as "m" before b, m and p (shimbun)
+		if c == 'm' && i+1 < len(s) && (s[i+1] == 'b' || s[i+1] == 'm' || s[i+1] == 'p') {
+			out.WriteString("ん")
+			i++
+			continue
+		}
+
+		// Sokuon: a doubled consonant, or "tch" (matcha)
+		if i+1 < len(s) && isConsonant(c) && (s[i+1] == c || (c == 't' && s[i+1] == 'c')) {
+			out.WriteString("っ")
+			i++
+			continue
+		}
+
+		// A dash starting or ending a word is a KANJIDIC affix marker
+		// ("-bi", "o-"); inside a word it is the IME spelling of ー
+		if c == '-' {
+			if atWordEdge(s, i) {
+				out.WriteByte('-')
+			} else {
+				out.WriteRune(prolonged)
+			}
+			i++
+			continue
+		}
+
+		// An apostrophe not used by ん is a っ with no consonant to double
+		// ("a'" あっ), as ToRomaji writes it
+		if c == '\'' {
+			out.WriteString("っ")
+			i++
+			continue
+		}
+
+		matched := false
+		for n := min(longestSyllable, len(s)-i); n > 0; n-- {
+			if k, found := romajiTable[s[i:i+n]]; found {
+				out.WriteString(k)
+				i += n
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		// Keep whatever we couldn't convert
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		out.WriteRune(r)
+		if r != ' ' && r != '.' && r != ',' {
+			ok = false
+		}
+	}
+
+	return out.String(), ok
+}
+
+// atWordEdge reports whether s[i] starts or ends a word
+func atWordEdge(s string, i int) bool {
+	isSep := func(c byte) bool { return c == ' ' || c == ',' }
+	return i == 0 || isSep(s[i-1]) || i+1 == len(s) || isSep(s[i+1])
+}
+
+// isVowel reports whether c is a romaji vowel
+func isVowel(c byte) bool {
+	return c == 'a' || c == 'i' || c == 'u' || c == 'e' || c == 'o'
+}
+
+// isConsonant reports whether c is a consonant that can be doubled for っ
+func isConsonant(c byte) bool {
+	return c >= 'a' && c <= 'z' && !isVowel(c) && c != 'n'
+}
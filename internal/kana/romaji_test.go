@@ -0,0 +1,88 @@
+package kana
+
+import "testing"
+
+func TestFromRomaji(t *testing.T) {
+	tests := []struct {
+		romaji string
+		want   string
+		ok     bool
+	}{
+		// Long vowels, with and without macrons
+		{"toukyou", "とうきょう", true},
+		{"tōkyō", "とうきょう", true},
+		{"Tôkyô", "とうきょう", true},
+		{"ookii", "おおきい", true},
+		{"ka-do", "かーど", true},
+
+		// Sokuon
+		{"gakkou", "がっこう", true},
+		{"matcha", "まっちゃ", true},
+		{"mattya", "まっちゃ", true},
+		{"a'", "あっ", true},
+		{"xtsu", "っ", true},
+		{"ltu", "っ", true},
+
+		// ん before vowels and y needs n' (or nn)
+		{"kan'i", "かんい", true},
+		{"kani", "かに", true},
+		{"kin'en", "きんえん", true},
+		{"kinen", "きねん", true},
+		{"hon'ya", "ほんや", true},
+		{"honnya", "ほんにゃ", true},
+		{"konya", "こにゃ", true},
+		{"konnichiha", "こんにちは", true},
+		{"shimbun", "しんぶん", true},
+		{"shinbun", "しんぶん", true},
+		{"nn", "ん", true},
+		{"n", "ん", true},
+
+		// Kunrei and Nihon-shiki spellings
+		{"huzisan", "ふじさん", true},
+		{"tuduku", "つづく", true},
+		{"sya", "しゃ", true},
+		{"wo", "を", true},
+
+		// Affix dashes at a word edge are kept; inside a word they are ー
+		{"-bi", "-び", true},
+		{"o-", "お-", true},
+		{"-gui, o-", "-ぐい, お-", true},
+		{"ko-hi", "こーひ", true},
+
+		// Okurigana marker and separators
+		{"ta.beru", "た.べる", true},
+		{"nichi, jitsu", "にち, じつ", true},
+
+		// Anything else is kept but reported
+		{"qa", "qあ", false},
+		{"eat!", "えあt!", false},
+	}
+	for _, tt := range tests {
+		got, ok := FromRomaji(tt.romaji)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("FromRomaji(%q) = %q, %v; want %q, %v", tt.romaji, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, normalized, folded string
+	}{
+		{"トーキョー", "とおきょお", "ときょ"},
+		{"とうきょう", "とうきょう", "ときょ"},
+		{"ときょ", "ときょ", "ときょ"},
+		{"せんせい", "せんせい", "せんせ"},
+		{"おかあさん", "おかあさん", "おかさん"},
+		{"た.べる", "た.べる", "た.べる"},
+	}
+	for _, tt := range tests {
+		n := Normalize(tt.in)
+		if n != tt.normalized {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, n, tt.normalized)
+		}
+		if f := FoldLongVowels(n); f != tt.folded {
+			t.Errorf("FoldLongVowels(%q) = %q, want %q", n, f, tt.folded)
+		}
+	}
+}
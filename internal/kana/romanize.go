@@ -0,0 +1,294 @@
+package kana
+
+import (
+	"strings"
+)
+
+// System is a romanization system
+type System int
+
+// Supported romanization systems
+const (
+	Hepburn    System = iota // し shi, ち chi, つ tsu, ふ fu, じ ji
+	Kunrei                   // し si, ち ti, つ tu, ふ hu, じ zi
+	NihonShiki               // like Kunrei, but ぢ di, づ du, を wo
+)
+
+// String returns the system's name
+func (s System) String() string {
+	switch s {
+	case Kunrei:
+		return "kunrei"
+	case NihonShiki:
+		return "nihon-shiki"
+	}
+	return "hepburn"
+}
+
+// ParseSystem parses a system name as returned by System.String
+func ParseSystem(name string) (System, bool) {
+	switch strings.ToLower(name) {
+	case "hepburn", "":
+		return Hepburn, true
+	case "kunrei", "kunrei-shiki":
+		return Kunrei, true
+	case "nihon", "nihon-shiki", "nippon", "nippon-shiki":
+		return NihonShiki, true
+	}
+	return Hepburn, false
+}
+
+// spelling is a syllable's romanization in each System
+type spelling [3]string
+
+// syllables maps single hiragana to their romanization
+var syllables = func() map[rune]spelling {
+	m := make(map[rune]spelling)
+	same := func(r rune, s string) { m[r] = spelling{s, s, s} }
+
+	for i, r := range []rune("あいうえお") {
+		same(r, string("aiueo"[i]))
+	}
+	rows := map[string]string{
+		"かきくけこ": "k", "がぎぐげご": "g", "さしすせそ": "s", "ざじずぜぞ": "z",
+		"たちつてと": "t", "だぢづでど": "d", "なにぬねの": "n", "はひふへほ": "h",
+		"ばびぶべぼ": "b", "ぱぴぷぺぽ": "p", "まみむめも": "m", "らりるれろ": "r",
+	}
+	for row, consonant := range rows {
+		for i, r := range []rune(row) {
+			same(r, consonant+string("aiueo"[i]))
+		}
+	}
+	same('や', "ya")
+	same('ゆ', "yu")
+	same('よ', "yo")
+	same('わ', "wa")
+	same('ゔ', "vu")
+
+	// Where the systems disagree
+	m['し'] = spelling{"shi", "si", "si"}
+	m['ち'] = spelling{"chi", "ti", "ti"}
+	m['つ'] = spelling{"tsu", "tu", "tu"}
+	m['ふ'] = spelling{"fu", "hu", "hu"}
+	m['じ'] = spelling{"ji", "zi", "zi"}
+	m['ぢ'] = spelling{"ji", "zi", "di"}
+	m['づ'] = spelling{"zu", "zu", "du"}
+	m['を'] = spelling{"o", "o", "wo"}
+	m['ゐ'] = spelling{"i", "i", "wi"}
+	m['ゑ'] = spelling{"e", "e", "we"}
+
+	// Small kana on their own
+	for i, r := range []rune("ぁぃぅぇぉ") {
+		same(r, "x"+string("aiueo"[i]))
+	}
+	same('ゃ', "xya")
+	same('ゅ', "xyu")
+	same('ょ', "xyo")
+	same('ゎ', "xwa")
+	return m
+}()
+
+// smallVowels maps small kana that combine with the previous syllable to
+// the vowel they contribute
+var smallVowels = map[rune]string{
+	'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+}
+
+// combine spells a syllable followed by a small kana (きゃ, ふぁ, ちぇ)
+func combine(base string, small rune, sys System) (string, bool) {
+	vowel, ok := smallVowels[small]
+	if !ok || base == "" {
+		return "", false
+	}
+	stem := base[:len(base)-1]
+
+	if strings.HasPrefix(vowel, "y") {
+		// Yōon only follows i-column syllables
+		if !strings.HasSuffix(base, "i") || len(base) < 2 {
+			return "", false
+		}
+		// Hepburn sh/ch/j absorb the y: sha, cha, ja
+		if sys == Hepburn && (stem == "sh" || stem == "ch" || stem == "j") {
+			return stem + vowel[1:], true
+		}
+		return stem + vowel, true
+	}
+
+	// Extended katakana sounds: ふぁ fa, ちぇ che, きぇ kye, うぃ wi
+	if strings.HasSuffix(base, "i") && len(base) >= 2 {
+		if sys == Hepburn && (stem == "sh" || stem == "ch" || stem == "j") {
+			return stem + vowel, true
+		}
+		return stem + "y" + vowel, true
+	}
+	switch base {
+	case "u":
+		stem = "w"
+	case "hu":
+		stem = "f"
+	}
+	if stem == "" {
+		return "", false
+	}
+	return stem + vowel, true
+}
+
+// ToRomaji converts hiragana or katakana to romaji in the given system.
+// Long vowels are spelled out (とうきょう → toukyou), っ doubles the next
+// consonant or is written ' where there is none (あっ → a'), and ん is
+// written n' before a vowel or y. Anything that is not kana, such as the "."
+// okurigana marker, is kept as is.
+func ToRomaji(s string, sys System) string {
+	runes := []rune(ToHiragana(s))
+
+	var out strings.Builder
+	sokuon := false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch r {
+		case 'っ':
+			if sokuon {
+				out.WriteString(sokuonMark)
+			}
+			sokuon = true
+			continue
+		case 'ん':
+			flushSokuon(&out, &sokuon)
+			out.WriteString("n")
+			if next, ok := nextSyllable(runes, i+1, sys); ok && startsWithVowelOrY(next) {
+				out.WriteString("'")
+			}
+			continue
+		case prolonged:
+			flushSokuon(&out, &sokuon)
+			if v := lastVowel(out.String()); v != 0 {
+				out.WriteByte(v)
+			}
+			continue
+		}
+
+		syl, n, ok := syllableAt(runes, i, sys)
+		if !ok {
+			flushSokuon(&out, &sokuon)
+			out.WriteRune(r)
+			continue
+		}
+		i += n - 1
+
+		if sokuon {
+			switch {
+			case sys == Hepburn && strings.HasPrefix(syl, "ch"):
+				out.WriteString("t")
+			case !startsWithVowelOrY(syl) && syl[0] != 'x':
+				out.WriteByte(syl[0])
+			default:
+				out.WriteString(sokuonMark)
+			}
+			sokuon = false
+		}
+		out.WriteString(syl)
+	}
+	flushSokuon(&out, &sokuon)
+
+	return out.String()
+}
+
+// syllableAt spells the syllable starting at runes[i], returning how many
+// runes it used
+func syllableAt(runes []rune, i int, sys System) (string, int, bool) {
+	sp, ok := syllables[runes[i]]
+	if !ok {
+		return "", 0, false
+	}
+	if i+1 < len(runes) {
+		if combined, ok := combine(sp[sys], runes[i+1], sys); ok {
+			return combined, 2, true
+		}
+	}
+	return sp[sys], 1, true
+}
+
+// nextSyllable spells the syllable at runes[i], if there is one
+func nextSyllable(runes []rune, i int, sys System) (string, bool) {
+	if i >= len(runes) {
+		return "", false
+	}
+	syl, _, ok := syllableAt(runes, i, sys)
+	return syl, ok
+}
+
+// flushSokuon writes a っ that has no consonant to double
+func flushSokuon(out *strings.Builder, pending *bool) {
+	if *pending {
+		out.WriteString(sokuonMark)
+		*pending = false
+	}
+}
+
+// sokuonMark spells a っ that has no consonant to double (あっ a'), the
+// way FromRomaji reads it back
+const sokuonMark = "'"
+
+// startsWithVowelOrY reports whether a romaji syllable starts with a vowel or y
+func startsWithVowelOrY(s string) bool {
+	return s != "" && (isVowel(s[0]) || s[0] == 'y')
+}
+
+// lastVowel returns the last vowel written to s, or 0
+func lastVowel(s string) byte {
+	for i := len(s) - 1; i >= 0; i-- {
+		if isVowel(s[i]) {
+			return s[i]
+		}
+		if s[i] < 'a' || s[i] > 'z' {
+			return 0
+		}
+	}
+	return 0
+}
+
+// ToKatakana converts hiragana to katakana, leaving everything else as is
+func ToKatakana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if IsHiragana(r) {
+			return r + kanaOffset
+		}
+		return r
+	}, s)
+}
+
+// RomajiToKatakana converts romaji to katakana; see FromRomaji
+func RomajiToKatakana(s string) (string, bool) {
+	h, ok := FromRomaji(s)
+	return ToKatakana(h), ok
+}
+
+// SameReading reports whether answer, typed in romaji (any system) or kana,
+// spells the stored reading. Okurigana and affix markers ("た.べる",
+// "-び") are ignored, as are hiragana/katakana and the spelling differences
+// romaji can't express (ぢ/じ, づ/ず, を/お).
+func SameReading(answer, reading string) bool {
+	answer = strings.Trim(strings.ReplaceAll(strings.TrimSpace(answer), ".", ""), "-")
+	if strings.ContainsFunc(answer, func(r rune) bool { return r < 0x80 && r != '-' && r != ' ' }) {
+		converted, ok := FromRomaji(answer)
+		if !ok {
+			return false
+		}
+		answer = converted
+	}
+	key := readingKey(answer)
+	return key != "" && key == readingKey(reading)
+}
+
+// readingKeyReplacer removes markers and merges kana that sound the same
+var readingKeyReplacer = strings.NewReplacer(
+	".", "", "-", "", " ", "", "　", "",
+	"ぢ", "じ", "づ", "ず", "を", "お",
+)
+
+// readingKey normalizes a reading for comparison
+func readingKey(s string) string {
+	return readingKeyReplacer.Replace(Normalize(s))
+}
@@ -0,0 +1,106 @@
+package kana
+
+import "testing"
+
+func TestToRomaji(t *testing.T) {
+	tests := []struct {
+		kana                   string
+		hepburn, kunrei, nihon string
+	}{
+		// Long vowels are spelled out, including the prolonged sound mark
+		{"とうきょう", "toukyou", "toukyou", "toukyou"},
+		{"おおきい", "ookii", "ookii", "ookii"},
+		{"コーヒー", "koohii", "koohii", "koohii"},
+
+		// Sokuon doubles the next consonant, or is ' with nothing to double
+		{"がっこう", "gakkou", "gakkou", "gakkou"},
+		{"まっちゃ", "matcha", "mattya", "mattya"},
+		{"しゅっちょう", "shutchou", "syuttyou", "syuttyou"},
+		{"あっ", "a'", "a'", "a'"},
+		{"じっ", "ji'", "zi'", "zi'"},
+
+		// ん before a vowel or y
+		{"かんい", "kan'i", "kan'i", "kan'i"},
+		{"ほんや", "hon'ya", "hon'ya", "hon'ya"},
+		{"しんぶん", "shinbun", "sinbun", "sinbun"},
+		{"こんにちは", "konnichiha", "konnitiha", "konnitiha"},
+
+		// Where the systems differ
+		{"ふじさん", "fujisan", "huzisan", "huzisan"},
+		{"つづく", "tsuzuku", "tuzuku", "tuduku"},
+		{"ぢゃ", "ja", "zya", "dya"},
+		{"を", "o", "o", "wo"},
+		{"ファイル", "fairu", "fairu", "fairu"},
+		{"ちぇっく", "chekku", "tyekku", "tyekku"},
+
+		// KANJIDIC markers are kept
+		{"た.べる", "ta.beru", "ta.beru", "ta.beru"},
+		{"-び", "-bi", "-bi", "-bi"},
+		{"お-", "o-", "o-", "o-"},
+		{"にち, じつ", "nichi, jitsu", "niti, zitu", "niti, zitu"},
+	}
+	for _, tt := range tests {
+		for sys, want := range map[System]string{Hepburn: tt.hepburn, Kunrei: tt.kunrei, NihonShiki: tt.nihon} {
+			if got := ToRomaji(tt.kana, sys); got != want {
+				t.Errorf("ToRomaji(%q, %v) = %q, want %q", tt.kana, sys, got, want)
+			}
+		}
+	}
+}
+
+func TestRomajiRoundTrip(t *testing.T) {
+	readings := []string{
+		"とうきょう", "おおきい", "がっこう", "まっちゃ", "きっぷ", "しゅっちょう",
+		"あっ", "じっ", "かんい", "きんえん", "ほんや", "しんぶん", "こんにちは",
+		"ふじさん", "つづく", "ぢゃ", "を", "ふぁいる", "ちぇっく", "じゃ",
+		"た.べる", "-び", "お-", "-ぐい", "にち, じつ",
+	}
+	for _, reading := range readings {
+		for _, sys := range []System{Hepburn, Kunrei, NihonShiki} {
+			romaji := ToRomaji(reading, sys)
+			back, ok := FromRomaji(romaji)
+			if !ok {
+				t.Errorf("FromRomaji(%q) not ok (from %q, %v)", romaji, reading, sys)
+				continue
+			}
+			// Hepburn and Kunrei can't tell ぢ/じ, づ/ず or を/お apart;
+			// Nihon-shiki round-trips exactly
+			if sys == NihonShiki && back != reading {
+				t.Errorf("%q → %q → %q (%v)", reading, romaji, back, sys)
+			}
+			if readingKey(back) != readingKey(reading) {
+				t.Errorf("%q → %q → %q (%v)", reading, romaji, back, sys)
+			}
+		}
+	}
+}
+
+func TestSameReading(t *testing.T) {
+	tests := []struct {
+		answer, reading string
+		want            bool
+	}{
+		{"taberu", "た.べる", true},
+		{"TABERU", "た.べる", true},
+		{"たべる", "た.べる", true},
+		{"タベル", "た.べる", true},
+		{"tabéru", "た.べる", false},
+		{"bi", "-び", true},
+		{"-bi", "-び", true},
+		{"o", "お-", true},
+		{"tōkyō", "とうきょう", true},
+		{"tookyoo", "トーキョー", true},
+		{"tsuzuku", "つづく", true},
+		{"tuduku", "つづく", true},
+		{"ji'", "じっ", true},
+		{"ji", "じっ", false},
+		{"kan'i", "かんい", true},
+		{"kani", "かんい", false},
+		{"", "た.べる", false},
+	}
+	for _, tt := range tests {
+		if got := SameReading(tt.answer, tt.reading); got != tt.want {
+			t.Errorf("SameReading(%q, %q) = %v, want %v", tt.answer, tt.reading, got, tt.want)
+		}
+	}
+}
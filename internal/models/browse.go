@@ -0,0 +1,196 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Browse limits
+const (
+	DefaultBrowseLimit = 60
+	MaxBrowseLimit     = 200
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed or belongs to
+// another sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// kanjiSorts maps each browse sort to its integer keyset expression. The
+// COALESCEs push NULLs to the end and match the 000007 indexes.
+var kanjiSorts = map[string]string{
+	"id":        `k.kanji_char_id`,
+	"frequency": `COALESCE(k.frequency, 2147483647)`,
+	"strokes":   `COALESCE(k.stroke_count, 32767)`,
+	"grade":     `COALESCE(k.grade, 32767)`,
+	"jlpt":      `CASE k.jlpt_level WHEN 'n5' THEN 1 WHEN 'n4' THEN 2 WHEN 'n3' THEN 3 WHEN 'n2' THEN 4 WHEN 'n1' THEN 5 ELSE 6 END`,
+}
+
+// KanjiSorts lists the supported browse sort orders
+var KanjiSorts = []string{"id", "frequency", "strokes", "grade", "jlpt"}
+
+// KanjiCursor marks the last row of a browse page
+type KanjiCursor struct {
+	Sort string
+	Desc bool
+	Key  int
+	ID   int
+}
+
+// String encodes the cursor for use in a URL
+func (c KanjiCursor) String() string {
+	dir := "asc"
+	if c.Desc {
+		dir = "desc"
+	}
+	raw := fmt.Sprintf("%s:%s:%d:%d", c.Sort, dir, c.Key, c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseKanjiCursor decodes a cursor produced by KanjiCursor.String
+func ParseKanjiCursor(s string) (*KanjiCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 {
+		return nil, ErrInvalidCursor
+	}
+	if _, ok := kanjiSorts[parts[0]]; !ok || (parts[1] != "asc" && parts[1] != "desc") {
+		return nil, ErrInvalidCursor
+	}
+	key, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &KanjiCursor{Sort: parts[0], Desc: parts[1] == "desc", Key: key, ID: id}, nil
+}
+
+// BrowseOptions filters and orders a kanji browse
+type BrowseOptions struct {
+	JLPTLevel  string // "" for all levels
+	Sort       string // one of KanjiSorts, default "id"
+	Desc       bool
+	After      *KanjiCursor // nil for the first page
+	Limit      int
+	List       string   // ListStarred or ListSaved to browse a user's list
+	UserID     int      // owner of List
+	Components []string // only kanji containing every one of these components
+}
+
+// KanjiPage is one page of browse results
+type KanjiPage struct {
+	Kanji []Kanji
+	Next  *KanjiCursor // nil on the last page
+	Total int          // rows matching the filter across all pages
+}
+
+// BrowseKanji returns one page of kanji using keyset pagination
+func BrowseKanji(db *sql.DB, opts BrowseOptions) (*KanjiPage, error) {
+	if opts.Sort == "" {
+		opts.Sort = "id"
+	}
+	sortExpr, ok := kanjiSorts[opts.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", opts.Sort)
+	}
+	if opts.Limit <= 0 || opts.Limit > MaxBrowseLimit {
+		opts.Limit = DefaultBrowseLimit
+	}
+	if opts.After != nil && (opts.After.Sort != opts.Sort || opts.After.Desc != opts.Desc) {
+		return nil, ErrInvalidCursor
+	}
+
+	var where []string
+	var args []any
+	if opts.JLPTLevel != "" {
+		args = append(args, opts.JLPTLevel)
+		where = append(where, fmt.Sprintf("k.jlpt_level = $%d", len(args)))
+	}
+	if opts.List != "" {
+		table, ok := listTables[opts.List]
+		if !ok {
+			return nil, fmt.Errorf("unknown kanji list %q", opts.List)
+		}
+		args = append(args, opts.UserID)
+		where = append(where, fmt.Sprintf("EXISTS (SELECT 1 FROM %s l WHERE l.user_id = $%d AND l.kanji_char_id = k.kanji_char_id)", table[0], len(args)))
+	}
+
+	if len(opts.Components) > 0 {
+		args = append(args, opts.Components)
+		where = append(where, "k.kanji_char_id IN ("+componentMatch(len(args))+")")
+	}
+
+	// Count before adding the cursor condition so Total covers every page
+	filter := ""
+	if len(where) > 0 {
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM kanji_go.kanji k`+filter, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count kanji: %w", err)
+	}
+
+	op, dir := ">", "ASC"
+	if opts.Desc {
+		op, dir = "<", "DESC"
+	}
+	if opts.After != nil {
+		args = append(args, opts.After.Key, opts.After.ID)
+		where = append(where, fmt.Sprintf("(%s, k.kanji_char_id) %s ($%d, $%d)", sortExpr, op, len(args)-1, len(args)))
+	}
+	if len(where) > 0 {
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	args = append(args, opts.Limit+1)
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM kanji_go.kanji k%s
+		ORDER BY %s %s, k.kanji_char_id %s
+		LIMIT $%d`, kanjiColumns, sortExpr, filter, sortExpr, dir, dir, len(args))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to browse kanji: %w", err)
+	}
+	defer rows.Close()
+
+	page := &KanjiPage{Total: total}
+	var keys []int
+	for rows.Next() {
+		var k Kanji
+		var key int
+		if err := rows.Scan(append(kanjiScanDest(&k), &key)...); err != nil {
+			return nil, fmt.Errorf("failed to scan kanji: %w", err)
+		}
+		page.Kanji = append(page.Kanji, k)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate kanji: %w", err)
+	}
+
+	if len(page.Kanji) > opts.Limit {
+		page.Kanji = page.Kanji[:opts.Limit]
+		last := opts.Limit - 1
+		page.Next = &KanjiCursor{Sort: opts.Sort, Desc: opts.Desc, Key: keys[last], ID: page.Kanji[last].KanjiCharID}
+	}
+
+	if err := attachReadings(db, page.Kanji, false); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
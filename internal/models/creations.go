@@ -0,0 +1,128 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrCreationNotFound is returned when no creation matches the lookup
+// (or the user doesn't own it, for owner-scoped operations)
+var ErrCreationNotFound = errors.New("kanji creation not found")
+
+// creationColumns selects a kanji_creations row; created_by is NULL once
+// the author's account has been deleted
+const creationColumns = `
+	c.kanji_creation_id, c.kanji_char_id, COALESCE(c.created_by, ''), c.created_date,
+	c.image_url, c.mapping_url, c.explanation, COALESCE(c.is_public, FALSE),
+	COALESCE(c.stars, 0), COALESCE(c.flags, 0), c.updated_at`
+
+// creationScanDest returns the Scan destinations matching creationColumns
+func creationScanDest(c *KanjiCreation) []any {
+	return []any{&c.KanjiCreationID, &c.KanjiCharID, &c.CreatedBy, &c.CreatedDate,
+		&c.ImageURL, &c.MappingURL, &c.Explanation, &c.IsPublic,
+		&c.Stars, &c.Flags, &c.UpdatedAt}
+}
+
+// CreateCreation inserts a new kanji creation
+func CreateCreation(db *sql.DB, c *KanjiCreation) error {
+	query := `
+		INSERT INTO kanji_go.kanji_creations
+		(kanji_char_id, created_by, image_url, mapping_url, explanation, is_public)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING kanji_creation_id, created_date, stars, flags, updated_at
+	`
+
+	err := db.QueryRow(query, c.KanjiCharID, c.CreatedBy, c.ImageURL, c.MappingURL,
+		c.Explanation, c.IsPublic).Scan(&c.KanjiCreationID, &c.CreatedDate, &c.Stars, &c.Flags, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert kanji creation: %w", err)
+	}
+
+	return nil
+}
+
+// GetCreation loads a kanji creation by ID
+func GetCreation(db *sql.DB, id int) (*KanjiCreation, error) {
+	query := `SELECT ` + creationColumns + ` FROM kanji_go.kanji_creations c WHERE c.kanji_creation_id = $1`
+
+	var c KanjiCreation
+	err := db.QueryRow(query, id).Scan(creationScanDest(&c)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCreationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kanji creation: %w", err)
+	}
+
+	return &c, nil
+}
+
+// ListCreationsForKanji returns a kanji's public creations plus the
+// viewer's own private ones, most-starred first
+func ListCreationsForKanji(db *sql.DB, kanjiCharID int, viewer string) ([]KanjiCreation, error) {
+	query := `
+		SELECT ` + creationColumns + `
+		FROM kanji_go.kanji_creations c
+		WHERE c.kanji_char_id = $1
+		  AND (c.is_public OR ($2 <> '' AND c.created_by = $2))
+		ORDER BY c.stars DESC, c.created_date DESC
+	`
+
+	rows, err := db.Query(query, kanjiCharID, viewer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kanji creations: %w", err)
+	}
+	defer rows.Close()
+
+	var creations []KanjiCreation
+	for rows.Next() {
+		var c KanjiCreation
+		if err := rows.Scan(creationScanDest(&c)...); err != nil {
+			return nil, fmt.Errorf("failed to scan kanji creation: %w", err)
+		}
+		creations = append(creations, c)
+	}
+
+	return creations, rows.Err()
+}
+
+// UpdateCreation saves the editable fields of a creation owned by c.CreatedBy
+func UpdateCreation(db *sql.DB, c *KanjiCreation) error {
+	query := `
+		UPDATE kanji_go.kanji_creations
+		SET image_url = $3, mapping_url = $4, explanation = $5, is_public = $6, updated_at = NOW()
+		WHERE kanji_creation_id = $1 AND created_by = $2
+		RETURNING updated_at
+	`
+
+	err := db.QueryRow(query, c.KanjiCreationID, c.CreatedBy, c.ImageURL, c.MappingURL,
+		c.Explanation, c.IsPublic).Scan(&c.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCreationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update kanji creation: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCreation deletes a creation owned by the given user
+func DeleteCreation(db *sql.DB, id int, owner string) error {
+	result, err := db.Exec(`DELETE FROM kanji_go.kanji_creations WHERE kanji_creation_id = $1 AND created_by = $2`,
+		id, owner)
+	if err != nil {
+		return fmt.Errorf("failed to delete kanji creation: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete kanji creation: %w", err)
+	}
+	if n == 0 {
+		return ErrCreationNotFound
+	}
+
+	return nil
+}
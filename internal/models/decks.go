@@ -0,0 +1,196 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// PackExportItem is one kanji of a pack with what an export includes: the
+// user's SRS card (nil if none) and the mnemonics they can see
+type PackExportItem struct {
+	Kanji     Kanji
+	Card      *SRSCard
+	Creations []KanjiCreation
+}
+
+// GetPackExport loads a pack's kanji in study order with their meanings,
+// readings, the user's progress and the mnemonics visible to them. userID
+// 0 (anonymous) exports no progress and only public mnemonics.
+func GetPackExport(db *sql.DB, packID, userID int, username string) ([]PackExportItem, error) {
+	list, err := GetPackKanji(db, packID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	if err := attachMeanings(db, list); err != nil {
+		return nil, err
+	}
+	if err := attachReadings(db, list, false); err != nil {
+		return nil, err
+	}
+
+	items := make([]PackExportItem, len(list))
+	ids := make([]int, len(list))
+	byID := make(map[int]int, len(list))
+	for i, k := range list {
+		items[i].Kanji = k
+		ids[i] = k.KanjiCharID
+		byID[k.KanjiCharID] = i
+	}
+
+	rows, err := db.Query(`
+		SELECT kanji_char_id, state, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at
+		FROM kanji_go.srs_cards
+		WHERE user_id = $1 AND kanji_char_id = ANY($2)
+	`, userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query srs cards: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		card := SRSCard{UserID: userID}
+		if err := rows.Scan(&card.KanjiCharID, &card.State, &card.EaseFactor, &card.IntervalDays,
+			&card.Repetitions, &card.Lapses, &card.DueAt, &card.LastReviewedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan srs card: %w", err)
+		}
+		items[byID[card.KanjiCharID]].Card = &card
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate srs cards: %w", err)
+	}
+
+	creationRows, err := db.Query(`
+		SELECT `+creationColumns+`
+		FROM kanji_go.kanji_creations c
+		WHERE c.kanji_char_id = ANY($1)
+		  AND (c.is_public OR ($2 <> '' AND c.created_by = $2))
+		ORDER BY c.kanji_char_id, c.created_by = $2 DESC, c.stars DESC, c.created_date
+	`, ids, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kanji creations: %w", err)
+	}
+	defer creationRows.Close()
+	for creationRows.Next() {
+		var c KanjiCreation
+		if err := creationRows.Scan(creationScanDest(&c)...); err != nil {
+			return nil, fmt.Errorf("failed to scan kanji creation: %w", err)
+		}
+		i := byID[c.KanjiCharID]
+		items[i].Creations = append(items[i].Creations, c)
+	}
+	if err := creationRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate kanji creations: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetKanjiIDsByChar maps each character that is in kanji_go.kanji to its ID
+func GetKanjiIDsByChar(db *sql.DB, chars []string) (map[string]int, error) {
+	ids := make(map[string]int, len(chars))
+	if len(chars) == 0 {
+		return ids, nil
+	}
+
+	rows, err := db.Query(`SELECT kanji_char, kanji_char_id FROM kanji_go.kanji WHERE kanji_char = ANY($1)`, chars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kanji: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var char string
+		var id int
+		if err := rows.Scan(&char, &id); err != nil {
+			return nil, fmt.Errorf("failed to scan kanji: %w", err)
+		}
+		ids[char] = id
+	}
+	return ids, rows.Err()
+}
+
+// ImportSRSCards stores imported progress. An existing card is only
+// replaced when the imported one was reviewed more recently, so importing
+// an old export can't roll progress back. New cards are dated from their
+// last review so they don't use up today's new-card allowance. Returns how
+// many cards changed.
+func ImportSRSCards(db *sql.DB, cards []SRSCard) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	changed := 0
+	for _, card := range cards {
+		result, err := tx.Exec(`
+			INSERT INTO kanji_go.srs_cards
+			(user_id, kanji_char_id, state, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($9, NOW()))
+			ON CONFLICT (user_id, kanji_char_id) DO UPDATE SET
+				state = EXCLUDED.state,
+				ease_factor = EXCLUDED.ease_factor,
+				interval_days = EXCLUDED.interval_days,
+				repetitions = EXCLUDED.repetitions,
+				lapses = EXCLUDED.lapses,
+				due_at = EXCLUDED.due_at,
+				last_reviewed_at = EXCLUDED.last_reviewed_at,
+				updated_at = NOW()
+			WHERE EXCLUDED.last_reviewed_at IS NOT NULL
+			  AND (kanji_go.srs_cards.last_reviewed_at IS NULL
+			       OR EXCLUDED.last_reviewed_at > kanji_go.srs_cards.last_reviewed_at)
+		`, card.UserID, card.KanjiCharID, card.State, card.EaseFactor, card.IntervalDays,
+			card.Repetitions, card.Lapses, card.DueAt, card.LastReviewedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import srs card: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to import srs card: %w", err)
+		}
+		changed += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return changed, nil
+}
+
+// ImportCreations inserts imported mnemonics, skipping any the author
+// already has with the same text for that kanji. Returns how many were added.
+func ImportCreations(db *sql.DB, creations []KanjiCreation) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	added := 0
+	for _, c := range creations {
+		result, err := tx.Exec(`
+			INSERT INTO kanji_go.kanji_creations
+			(kanji_char_id, created_by, image_url, mapping_url, explanation, is_public)
+			SELECT $1::INT, $2::VARCHAR, $3::VARCHAR, $4::VARCHAR, $5::TEXT, $6::BOOLEAN
+			WHERE NOT EXISTS (
+				SELECT 1 FROM kanji_go.kanji_creations
+				WHERE kanji_char_id = $1 AND created_by = $2 AND explanation = $5
+			)
+		`, c.KanjiCharID, c.CreatedBy, c.ImageURL, c.MappingURL, c.Explanation, c.IsPublic)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import kanji creation: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to import kanji creation: %w", err)
+		}
+		added += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return added, nil
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Errors returned by the draft functions
+var (
+	ErrDraftNotFound   = errors.New("draft not found")
+	ErrDraftIncomplete = errors.New("draft has no explanation")
+)
+
+// draftTTL is how long an untouched draft is kept
+const draftTTL = "30 days"
+
+// draftColumns selects a temp_creation row; owner is NULL for drafts that
+// predate draft ownership
+const draftColumns = `
+	d.temp_id, d.kanji_char_id, COALESCE(d.owner, ''), d.image_url, d.mapping_url, d.explanation,
+	COALESCE(d.is_public, FALSE), d.created_at, d.updated_at, d.expires_at`
+
+// draftScanDest returns the Scan destinations matching draftColumns
+func draftScanDest(d *TempCreation) []any {
+	return []any{&d.TempID, &d.KanjiCharID, &d.Owner, &d.ImageURL, &d.MappingURL,
+		&d.Explanation, &d.IsPublic, &d.CreatedAt, &d.UpdatedAt, &d.ExpiresAt}
+}
+
+// GetDraftForKanji loads the user's draft for a kanji
+func GetDraftForKanji(db *sql.DB, owner string, kanjiCharID int) (*TempCreation, error) {
+	query := `SELECT ` + draftColumns + ` FROM kanji_go.temp_creation d WHERE d.owner = $1 AND d.kanji_char_id = $2`
+
+	var d TempCreation
+	err := db.QueryRow(query, owner, kanjiCharID).Scan(draftScanDest(&d)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDraftNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query draft: %w", err)
+	}
+
+	return &d, nil
+}
+
+// SaveDraft creates or updates the owner's draft for d.KanjiCharID and
+// pushes back its expiry
+func SaveDraft(db *sql.DB, d *TempCreation) error {
+	query := `
+		INSERT INTO kanji_go.temp_creation
+		(kanji_char_id, owner, image_url, mapping_url, explanation, is_public, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW() + INTERVAL '` + draftTTL + `')
+		ON CONFLICT (owner, kanji_char_id) DO UPDATE SET
+			image_url = EXCLUDED.image_url,
+			mapping_url = EXCLUDED.mapping_url,
+			explanation = EXCLUDED.explanation,
+			is_public = EXCLUDED.is_public,
+			updated_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		RETURNING temp_id, created_at, updated_at, expires_at
+	`
+
+	err := db.QueryRow(query, d.KanjiCharID, d.Owner, d.ImageURL, d.MappingURL, d.Explanation, d.IsPublic).
+		Scan(&d.TempID, &d.CreatedAt, &d.UpdatedAt, &d.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save draft: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDraft deletes the owner's draft for a kanji
+func DeleteDraft(db *sql.DB, owner string, kanjiCharID int) error {
+	result, err := db.Exec(`DELETE FROM kanji_go.temp_creation WHERE owner = $1 AND kanji_char_id = $2`,
+		owner, kanjiCharID)
+	if err != nil {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+	if n == 0 {
+		return ErrDraftNotFound
+	}
+
+	return nil
+}
+
+// PublishDraft atomically turns the owner's draft for a kanji into a
+// kanji creation and deletes the draft
+func PublishDraft(db *sql.DB, owner string, kanjiCharID int) (*KanjiCreation, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the draft so a concurrent autosave or publish can't interleave
+	var d TempCreation
+	err = tx.QueryRow(`SELECT `+draftColumns+` FROM kanji_go.temp_creation d
+		WHERE d.owner = $1 AND d.kanji_char_id = $2 FOR UPDATE`, owner, kanjiCharID).Scan(draftScanDest(&d)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDraftNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query draft: %w", err)
+	}
+
+	if strings.TrimSpace(d.Explanation) == "" {
+		return nil, ErrDraftIncomplete
+	}
+
+	c := &KanjiCreation{
+		KanjiCharID: d.KanjiCharID,
+		CreatedBy:   d.Owner,
+		ImageURL:    d.ImageURL,
+		MappingURL:  d.MappingURL,
+		Explanation: d.Explanation,
+		IsPublic:    d.IsPublic,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO kanji_go.kanji_creations
+		(kanji_char_id, created_by, image_url, mapping_url, explanation, is_public)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING kanji_creation_id, created_date, stars, flags, updated_at
+	`, c.KanjiCharID, c.CreatedBy, c.ImageURL, c.MappingURL, c.Explanation, c.IsPublic).
+		Scan(&c.KanjiCreationID, &c.CreatedDate, &c.Stars, &c.Flags, &c.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert kanji creation: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM kanji_go.temp_creation WHERE temp_id = $1`, d.TempID); err != nil {
+		return nil, fmt.Errorf("failed to delete draft: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return c, nil
+}
+
+// DeleteExpiredDrafts deletes drafts that expired before now and returns them
+func DeleteExpiredDrafts(db *sql.DB, now time.Time) ([]TempCreation, error) {
+	rows, err := db.Query(`DELETE FROM kanji_go.temp_creation d WHERE d.expires_at < $1 RETURNING `+draftColumns, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired drafts: %w", err)
+	}
+	defer rows.Close()
+
+	var drafts []TempCreation
+	for rows.Next() {
+		var d TempCreation
+		if err := rows.Scan(draftScanDest(&d)...); err != nil {
+			return nil, fmt.Errorf("failed to scan draft: %w", err)
+		}
+		drafts = append(drafts, d)
+	}
+
+	return drafts, rows.Err()
+}
+
+// ImageURLInUse reports whether any creation or draft references the URL
+func ImageURLInUse(db *sql.DB, url string) (bool, error) {
+	var inUse bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM kanji_go.kanji_creations WHERE image_url = $1 OR mapping_url = $1)
+		    OR EXISTS (SELECT 1 FROM kanji_go.temp_creation WHERE image_url = $1 OR mapping_url = $1)
+	`, url).Scan(&inUse)
+	if err != nil {
+		return false, fmt.Errorf("failed to check image references: %w", err)
+	}
+	return inUse, nil
+}
+
+// ImageURLsInUse returns which of the URLs a creation or draft references
+func ImageURLsInUse(db *sql.DB, urls []string) (map[string]bool, error) {
+	rows, err := db.Query(`
+		SELECT url FROM unnest($1::text[]) AS url
+		WHERE EXISTS (SELECT 1 FROM kanji_go.kanji_creations WHERE image_url = url OR mapping_url = url)
+		   OR EXISTS (SELECT 1 FROM kanji_go.temp_creation WHERE image_url = url OR mapping_url = url)
+	`, urls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check image references: %w", err)
+	}
+	defer rows.Close()
+
+	inUse := make(map[string]bool)
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, fmt.Errorf("failed to scan image reference: %w", err)
+		}
+		inUse[url] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate image references: %w", err)
+	}
+	return inUse, nil
+}
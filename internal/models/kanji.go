@@ -0,0 +1,166 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrKanjiNotFound is returned when no kanji matches the lookup
+var ErrKanjiNotFound = errors.New("kanji not found")
+
+// kanjiColumns selects a kanji row with NULL readings as empty strings
+const kanjiColumns = `
+	k.kanji_char_id, k.kanji_char,
+	COALESCE(k.romaji_onyomi, ''), COALESCE(k.romaji_kunyomi, ''),
+	COALESCE(k.hiragana_onyomi, ''), COALESCE(k.hiragana_kunyomi, ''),
+	COALESCE(k.jlpt_level, ''), k.stroke_count, k.grade, k.frequency, k.radical,
+	COALESCE(k.nanori, ''), k.created_at, k.updated_at`
+
+// kanjiScanDest returns the Scan destinations matching kanjiColumns
+func kanjiScanDest(k *Kanji) []any {
+	return []any{&k.KanjiCharID, &k.KanjiChar, &k.RomajiOnyomi, &k.RomajiKunyomi,
+		&k.HiraganaOnyomi, &k.HiraganaKunyomi, &k.JLPTLevel, &k.StrokeCount, &k.Grade,
+		&k.Frequency, &k.Radical, &k.Nanori, &k.CreatedAt, &k.UpdatedAt}
+}
+
+// GetKanjiByID loads a single kanji
+func GetKanjiByID(db *sql.DB, kanjiCharID int) (*Kanji, error) {
+	query := `SELECT ` + kanjiColumns + ` FROM kanji_go.kanji k WHERE k.kanji_char_id = $1`
+
+	var k Kanji
+	err := db.QueryRow(query, kanjiCharID).Scan(kanjiScanDest(&k)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrKanjiNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kanji: %w", err)
+	}
+
+	return &k, nil
+}
+
+// GetKanjiByChar loads a single kanji by its character
+func GetKanjiByChar(db *sql.DB, char string) (*Kanji, error) {
+	query := `SELECT ` + kanjiColumns + ` FROM kanji_go.kanji k WHERE k.kanji_char = $1`
+
+	var k Kanji
+	err := db.QueryRow(query, char).Scan(kanjiScanDest(&k)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrKanjiNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kanji: %w", err)
+	}
+
+	return &k, nil
+}
+
+// GetKanjiMeanings returns a kanji's English meanings in dictionary order
+func GetKanjiMeanings(db *sql.DB, kanjiCharID int) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT meaning FROM kanji_go.kanji_meanings
+		WHERE kanji_char_id = $1
+		ORDER BY position
+	`, kanjiCharID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query meanings: %w", err)
+	}
+	defer rows.Close()
+
+	var meanings []string
+	for rows.Next() {
+		var m string
+		if err := rows.Scan(&m); err != nil {
+			return nil, fmt.Errorf("failed to scan meaning: %w", err)
+		}
+		meanings = append(meanings, m)
+	}
+
+	return meanings, rows.Err()
+}
+
+// attachMeanings loads the meanings for a list of kanji in one query
+func attachMeanings(db *sql.DB, list []Kanji) error {
+	ids := make([]int, len(list))
+	byID := make(map[int]int, len(list))
+	for i, k := range list {
+		ids[i] = k.KanjiCharID
+		byID[k.KanjiCharID] = i
+	}
+
+	rows, err := db.Query(`
+		SELECT kanji_char_id, meaning FROM kanji_go.kanji_meanings
+		WHERE kanji_char_id = ANY($1)
+		ORDER BY kanji_char_id, position
+	`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to query meanings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var m string
+		if err := rows.Scan(&id, &m); err != nil {
+			return fmt.Errorf("failed to scan meaning: %w", err)
+		}
+		if i, ok := byID[id]; ok {
+			list[i].Meanings = append(list[i].Meanings, m)
+		}
+	}
+	return rows.Err()
+}
+
+// ListAllKanji returns every kanji with its meanings and readings, for
+// building the in-memory search index
+func ListAllKanji(db *sql.DB) ([]Kanji, error) {
+	rows, err := db.Query(`SELECT ` + kanjiColumns + ` FROM kanji_go.kanji k ORDER BY k.kanji_char_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query kanji: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Kanji
+	byID := make(map[int]int)
+	for rows.Next() {
+		var k Kanji
+		if err := rows.Scan(kanjiScanDest(&k)...); err != nil {
+			return nil, fmt.Errorf("failed to scan kanji: %w", err)
+		}
+		byID[k.KanjiCharID] = len(list)
+		list = append(list, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate kanji: %w", err)
+	}
+
+	meaningRows, err := db.Query(`
+		SELECT kanji_char_id, meaning FROM kanji_go.kanji_meanings
+		ORDER BY kanji_char_id, position
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query meanings: %w", err)
+	}
+	defer meaningRows.Close()
+
+	for meaningRows.Next() {
+		var id int
+		var m string
+		if err := meaningRows.Scan(&id, &m); err != nil {
+			return nil, fmt.Errorf("failed to scan meaning: %w", err)
+		}
+		if i, ok := byID[id]; ok {
+			list[i].Meanings = append(list[i].Meanings, m)
+		}
+	}
+
+	if err := meaningRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate meanings: %w", err)
+	}
+
+	if err := attachReadings(db, list, true); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
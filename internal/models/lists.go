@@ -0,0 +1,120 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// KanjiListState is whether a user has starred and/or saved a kanji
+type KanjiListState struct {
+	Starred bool `json:"starred"`
+	Saved   bool `json:"saved"`
+}
+
+// GetKanjiListState returns the user's starred/saved state for a kanji
+func GetKanjiListState(db *sql.DB, userID, kanjiCharID int) (KanjiListState, error) {
+	var state KanjiListState
+	err := db.QueryRow(`
+		SELECT
+			EXISTS (SELECT 1 FROM kanji_go.user_starred_kanji WHERE user_id = $1 AND kanji_char_id = $2),
+			EXISTS (SELECT 1 FROM kanji_go.user_saved_kanji WHERE user_id = $1 AND kanji_char_id = $2)
+	`, userID, kanjiCharID).Scan(&state.Starred, &state.Saved)
+	if err != nil {
+		return state, fmt.Errorf("failed to query kanji list state: %w", err)
+	}
+	return state, nil
+}
+
+// Kanji lists a user can add kanji to
+const (
+	ListStarred = "starred"
+	ListSaved   = "saved"
+)
+
+// listTables maps each list to its junction table and timestamp column
+var listTables = map[string][2]string{
+	ListStarred: {"kanji_go.user_starred_kanji", "starred_at"},
+	ListSaved:   {"kanji_go.user_saved_kanji", "saved_at"},
+}
+
+// ToggleKanjiInList adds the kanji to the user's list, or removes it if it
+// was already there, and returns whether it is now in the list
+func ToggleKanjiInList(db *sql.DB, list string, userID, kanjiCharID int) (bool, error) {
+	table, ok := listTables[list]
+	if !ok {
+		return false, fmt.Errorf("unknown kanji list %q", list)
+	}
+
+	// One statement, so two quick clicks can't both insert
+	var added bool
+	err := db.QueryRow(`
+		WITH removed AS (
+			DELETE FROM `+table[0]+` WHERE user_id = $1 AND kanji_char_id = $2
+			RETURNING 1
+		), added AS (
+			INSERT INTO `+table[0]+` (user_id, kanji_char_id)
+			SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM removed)
+			ON CONFLICT DO NOTHING
+			RETURNING 1
+		)
+		SELECT EXISTS (SELECT 1 FROM added)
+	`, userID, kanjiCharID).Scan(&added)
+	if err != nil {
+		return false, fmt.Errorf("failed to toggle %s kanji: %w", list, err)
+	}
+	return added, nil
+}
+
+// LoadUserKanjiLists fills the user's StarredKanji and SavedKanji, most
+// recently added first, and KanjiPacks with the names of subscribed packs
+func LoadUserKanjiLists(db *sql.DB, user *User) error {
+	var err error
+	if user.StarredKanji, err = listKanjiIDs(db, ListStarred, user.ID); err != nil {
+		return err
+	}
+	if user.SavedKanji, err = listKanjiIDs(db, ListSaved, user.ID); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT p.name FROM kanji_go.pack_subscriptions s
+		JOIN kanji_go.packs p ON p.pack_id = s.pack_id
+		WHERE s.user_id = $1
+		  AND (p.owner_id IS NULL OR p.is_public OR p.owner_id = $1)
+		ORDER BY s.subscribed_at, p.pack_id
+	`, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query kanji packs: %w", err)
+	}
+	defer rows.Close()
+
+	user.KanjiPacks = nil
+	for rows.Next() {
+		var pack string
+		if err := rows.Scan(&pack); err != nil {
+			return fmt.Errorf("failed to scan kanji pack: %w", err)
+		}
+		user.KanjiPacks = append(user.KanjiPacks, pack)
+	}
+	return rows.Err()
+}
+
+// listKanjiIDs returns the kanji IDs in one of the user's lists
+func listKanjiIDs(db *sql.DB, list string, userID int) ([]int, error) {
+	table := listTables[list]
+	rows, err := db.Query(`SELECT kanji_char_id FROM `+table[0]+` WHERE user_id = $1 ORDER BY `+table[1]+` DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s kanji: %w", list, err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan %s kanji: %w", list, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
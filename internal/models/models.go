@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
 )
 
 // User represents a user of the application
@@ -15,6 +17,7 @@ type User struct {
 	KanjiPacks   []string  `json:"kanji_packs"`
 	StarredKanji []int     `json:"starred_kanji"`
 	SavedKanji   []int     `json:"saved_kanji"`
+	IsAdmin      bool      `json:"is_admin"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -43,6 +46,13 @@ type Kanji struct {
 	HiraganaOnyomi   string    `json:"hiragana_onyomi"`
 	HiraganaKunyomi  string    `json:"hiragana_kunyomi"`
 	JLPTLevel        string    `json:"jlpt_level"`
+	StrokeCount      *int      `json:"stroke_count"` // Pointers to allow NULL
+	Grade            *int      `json:"grade"`
+	Frequency        *int      `json:"frequency"`
+	Radical          *int      `json:"radical"`
+	Nanori           string    `json:"nanori"`
+	Meanings         []string  `json:"meanings,omitempty"`
+	Readings         []Reading `json:"readings,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
@@ -67,10 +77,14 @@ type KanjiCreation struct {
 type TempCreation struct {
 	TempID      int       `json:"temp_id"`
 	KanjiCharID int       `json:"kanji_char_id"`
+	Owner       string    `json:"owner"`
 	ImageURL    *string   `json:"image_url"` // Pointer to allow NULL
 	MappingURL  *string   `json:"mapping_url"` // Pointer to allow NULL
 	Explanation string    `json:"explanation"`
+	IsPublic    bool      `json:"is_public"`
 	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
 	Kanji       *Kanji    `json:"kanji,omitempty"` // For joins
 }
 
@@ -92,6 +106,9 @@ func AddKanji(db *sql.DB, kanji *Kanji) error {
         }
     }()
 
+    // Fill in whichever of romaji / hiragana readings were left out
+    deriveReadings(kanji)
+
     // Insert the kanji
     query := `
         INSERT INTO kanji_go.kanji 
@@ -114,6 +131,13 @@ func AddKanji(db *sql.DB, kanji *Kanji) error {
         return fmt.Errorf("failed to insert kanji: %w", err)
     }
 
+    // One row per reading alongside the summary columns
+    readings := append(ParseReadings(kanji.HiraganaOnyomi, ReadingOn), ParseReadings(kanji.HiraganaKunyomi, ReadingKun)...)
+    if _, err = ReplaceReadings(tx, kanji.KanjiCharID, readings); err != nil {
+        return err
+    }
+    kanji.Readings = readings
+
     // Commit the transaction
 	err = tx.Commit()
 	if err != nil {
@@ -122,3 +146,22 @@ func AddKanji(db *sql.DB, kanji *Kanji) error {
 
     return nil
 }
+
+// deriveReadings fills empty romaji readings from their hiragana, or empty
+// hiragana readings from their romaji, so the pairs can't disagree
+func deriveReadings(kanji *Kanji) {
+	pairs := []struct{ romaji, hiragana *string }{
+		{&kanji.RomajiOnyomi, &kanji.HiraganaOnyomi},
+		{&kanji.RomajiKunyomi, &kanji.HiraganaKunyomi},
+	}
+	for _, p := range pairs {
+		switch {
+		case *p.romaji == "" && *p.hiragana != "":
+			*p.romaji = kana.ToRomaji(*p.hiragana, kana.Hepburn)
+		case *p.hiragana == "" && *p.romaji != "":
+			if h, ok := kana.FromRomaji(*p.romaji); ok {
+				*p.hiragana = h
+			}
+		}
+	}
+}
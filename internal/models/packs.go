@@ -0,0 +1,375 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPackNotFound is returned when no pack matches the lookup, the viewer
+// can't see it, or (for owner-scoped operations) the user doesn't own it
+var ErrPackNotFound = errors.New("kanji pack not found")
+
+// Pack is a deck of kanji: a built-in JLPT pack or a user's custom deck
+type Pack struct {
+	PackID      int       `json:"pack_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	OwnerID     *int      `json:"owner_id"`   // nil for built-in packs
+	Owner       string    `json:"owner"`      // owner's username, "" for built-in packs
+	JLPTLevel   *string   `json:"jlpt_level"` // set for built-in packs only
+	IsPublic    bool      `json:"is_public"`
+	KanjiCount  int       `json:"kanji_count"`
+	Subscribed  bool      `json:"subscribed"` // whether the viewing user subscribes
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IsBuiltin reports whether the pack is one of the JLPT packs
+func (p Pack) IsBuiltin() bool {
+	return p.OwnerID == nil
+}
+
+// OwnedBy reports whether the user owns the pack
+func (p Pack) OwnedBy(userID int) bool {
+	return p.OwnerID != nil && *p.OwnerID == userID
+}
+
+// packQuery selects packs visible to the viewer ($1, 0 when anonymous)
+const packQuery = `
+	SELECT p.pack_id, p.name, p.description, p.owner_id, COALESCE(u.username, ''),
+	       p.jlpt_level, p.is_public,
+	       (SELECT COUNT(*) FROM kanji_go.pack_members m WHERE m.pack_id = p.pack_id),
+	       EXISTS (SELECT 1 FROM kanji_go.pack_subscriptions s WHERE s.pack_id = p.pack_id AND s.user_id = $1),
+	       p.created_at, p.updated_at
+	FROM kanji_go.packs p
+	LEFT JOIN kanji_go.users u ON u.id = p.owner_id
+	WHERE (p.owner_id IS NULL OR p.is_public OR p.owner_id = $1)`
+
+// packScanDest returns the Scan destinations matching packQuery
+func packScanDest(p *Pack) []any {
+	return []any{&p.PackID, &p.Name, &p.Description, &p.OwnerID, &p.Owner,
+		&p.JLPTLevel, &p.IsPublic, &p.KanjiCount, &p.Subscribed,
+		&p.CreatedAt, &p.UpdatedAt}
+}
+
+// ListPacks returns the packs the viewer can see: built-in packs (N5
+// first), then the viewer's own decks, then other users' public decks
+func ListPacks(db *sql.DB, viewerID int) ([]Pack, error) {
+	rows, err := db.Query(packQuery+`
+		ORDER BY p.owner_id IS NOT NULL, p.jlpt_level DESC, p.owner_id = $1 DESC, lower(p.name), p.pack_id`,
+		viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query packs: %w", err)
+	}
+	defer rows.Close()
+
+	var packs []Pack
+	for rows.Next() {
+		var p Pack
+		if err := rows.Scan(packScanDest(&p)...); err != nil {
+			return nil, fmt.Errorf("failed to scan pack: %w", err)
+		}
+		packs = append(packs, p)
+	}
+
+	return packs, rows.Err()
+}
+
+// GetPack loads a pack the viewer can see
+func GetPack(db *sql.DB, packID, viewerID int) (*Pack, error) {
+	var p Pack
+	err := db.QueryRow(packQuery+` AND p.pack_id = $2`, viewerID, packID).Scan(packScanDest(&p)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPackNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pack: %w", err)
+	}
+
+	return &p, nil
+}
+
+// CreatePack inserts a custom deck owned by *p.OwnerID and subscribes the
+// owner to it
+func CreatePack(db *sql.DB, p *Pack) error {
+	if p.OwnerID == nil {
+		return errors.New("custom pack needs an owner")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO kanji_go.packs (name, description, owner_id, is_public)
+		VALUES ($1, $2, $3, $4)
+		RETURNING pack_id, created_at, updated_at
+	`, p.Name, p.Description, *p.OwnerID, p.IsPublic).Scan(&p.PackID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert pack: %w", err)
+	}
+
+	if _, err := tx.Exec(`INSERT INTO kanji_go.pack_subscriptions (user_id, pack_id) VALUES ($1, $2)`,
+		*p.OwnerID, p.PackID); err != nil {
+		return fmt.Errorf("failed to subscribe to pack: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	p.Subscribed = true
+	return nil
+}
+
+// UpdatePack saves the name, description and visibility of a custom deck
+// owned by *p.OwnerID
+func UpdatePack(db *sql.DB, p *Pack) error {
+	if p.OwnerID == nil {
+		return ErrPackNotFound
+	}
+
+	err := db.QueryRow(`
+		UPDATE kanji_go.packs
+		SET name = $3, description = $4, is_public = $5, updated_at = NOW()
+		WHERE pack_id = $1 AND owner_id = $2
+		RETURNING updated_at
+	`, p.PackID, *p.OwnerID, p.Name, p.Description, p.IsPublic).Scan(&p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPackNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update pack: %w", err)
+	}
+
+	return nil
+}
+
+// DeletePack deletes a custom deck owned by the given user
+func DeletePack(db *sql.DB, packID, ownerID int) error {
+	result, err := db.Exec(`DELETE FROM kanji_go.packs WHERE pack_id = $1 AND owner_id = $2`, packID, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete pack: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete pack: %w", err)
+	}
+	if n == 0 {
+		return ErrPackNotFound
+	}
+
+	return nil
+}
+
+// GetPackKanji returns a pack's kanji in study order
+func GetPackKanji(db *sql.DB, packID int) ([]Kanji, error) {
+	rows, err := db.Query(`
+		SELECT `+kanjiColumns+`
+		FROM kanji_go.pack_members m
+		JOIN kanji_go.kanji k ON k.kanji_char_id = m.kanji_char_id
+		WHERE m.pack_id = $1
+		ORDER BY m.position, k.kanji_char_id
+	`, packID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pack kanji: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Kanji
+	for rows.Next() {
+		var k Kanji
+		if err := rows.Scan(kanjiScanDest(&k)...); err != nil {
+			return nil, fmt.Errorf("failed to scan pack kanji: %w", err)
+		}
+		list = append(list, k)
+	}
+
+	return list, rows.Err()
+}
+
+// lockOwnedPack locks a custom deck's row for a membership change and bumps
+// its updated_at, or returns ErrPackNotFound if the user doesn't own it
+func lockOwnedPack(tx *sql.Tx, packID, ownerID int) error {
+	var id int
+	err := tx.QueryRow(`
+		UPDATE kanji_go.packs SET updated_at = NOW()
+		WHERE pack_id = $1 AND owner_id = $2
+		RETURNING pack_id
+	`, packID, ownerID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPackNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock pack: %w", err)
+	}
+	return nil
+}
+
+// AddKanjiToPack appends kanji to the end of the owner's deck, in the order
+// given, and returns how many were added. Kanji already in the deck are
+// skipped.
+func AddKanjiToPack(db *sql.DB, packID, ownerID int, kanjiCharIDs []int) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOwnedPack(tx, packID, ownerID); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO kanji_go.pack_kanji (pack_id, kanji_char_id, position)
+		SELECT $1, t.id,
+		       (SELECT COALESCE(MAX(position), -1) FROM kanji_go.pack_kanji WHERE pack_id = $1) + t.ord::INT
+		FROM unnest($2::INT[]) WITH ORDINALITY AS t(id, ord)
+		JOIN kanji_go.kanji k ON k.kanji_char_id = t.id
+		ON CONFLICT DO NOTHING
+	`, packID, kanjiCharIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add pack kanji: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to add pack kanji: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(n), nil
+}
+
+// RemoveKanjiFromPack removes a kanji from the owner's deck
+func RemoveKanjiFromPack(db *sql.DB, packID, ownerID, kanjiCharID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOwnedPack(tx, packID, ownerID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM kanji_go.pack_kanji WHERE pack_id = $1 AND kanji_char_id = $2`,
+		packID, kanjiCharID); err != nil {
+		return fmt.Errorf("failed to remove pack kanji: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// MoveKanjiInPack swaps a kanji with its neighbour in the owner's deck,
+// towards the front when up is true. Moving past either end does nothing.
+func MoveKanjiInPack(db *sql.DB, packID, ownerID, kanjiCharID int, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOwnedPack(tx, packID, ownerID); err != nil {
+		return err
+	}
+
+	var pos int
+	err = tx.QueryRow(`SELECT position FROM kanji_go.pack_kanji WHERE pack_id = $1 AND kanji_char_id = $2`,
+		packID, kanjiCharID).Scan(&pos)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrKanjiNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query pack kanji: %w", err)
+	}
+
+	neighbour := `SELECT kanji_char_id, position FROM kanji_go.pack_kanji
+		WHERE pack_id = $1 AND (position, kanji_char_id) > ($2, $3)
+		ORDER BY position, kanji_char_id LIMIT 1`
+	if up {
+		neighbour = `SELECT kanji_char_id, position FROM kanji_go.pack_kanji
+			WHERE pack_id = $1 AND (position, kanji_char_id) < ($2, $3)
+			ORDER BY position DESC, kanji_char_id DESC LIMIT 1`
+	}
+
+	var otherID, otherPos int
+	err = tx.QueryRow(neighbour, packID, pos, kanjiCharID).Scan(&otherID, &otherPos)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query pack kanji: %w", err)
+	}
+
+	// Positions can tie after concurrent adds; nudge so the swap changes order
+	if otherPos == pos {
+		if up {
+			pos++
+		} else {
+			otherPos++
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE kanji_go.pack_kanji
+		SET position = CASE kanji_char_id WHEN $2 THEN $3 ELSE $4 END
+		WHERE pack_id = $1 AND kanji_char_id IN ($2, $5)
+	`, packID, kanjiCharID, otherPos, pos, otherID)
+	if err != nil {
+		return fmt.Errorf("failed to reorder pack kanji: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemovePackSubscription unsubscribes the user from the pack, whether or
+// not they can still see it, and reports whether they were subscribed
+func RemovePackSubscription(db *sql.DB, userID, packID int) (bool, error) {
+	result, err := db.Exec(`DELETE FROM kanji_go.pack_subscriptions WHERE user_id = $1 AND pack_id = $2`, userID, packID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove pack subscription: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove pack subscription: %w", err)
+	}
+	return n > 0, nil
+}
+
+// TogglePackSubscription subscribes the user to the pack, or unsubscribes
+// if they already were, and returns whether they are now subscribed
+func TogglePackSubscription(db *sql.DB, userID, packID int) (bool, error) {
+	var subscribed bool
+	err := db.QueryRow(`
+		WITH removed AS (
+			DELETE FROM kanji_go.pack_subscriptions WHERE user_id = $1 AND pack_id = $2
+			RETURNING 1
+		), added AS (
+			INSERT INTO kanji_go.pack_subscriptions (user_id, pack_id)
+			SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM removed)
+			ON CONFLICT DO NOTHING
+			RETURNING 1
+		)
+		SELECT EXISTS (SELECT 1 FROM added)
+	`, userID, packID).Scan(&subscribed)
+	if err != nil {
+		return false, fmt.Errorf("failed to toggle pack subscription: %w", err)
+	}
+	return subscribed, nil
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// QuizResult is one answered quiz question
+type QuizResult struct {
+	ResultID       int64     `json:"result_id"`
+	UserID         int       `json:"user_id"`
+	KanjiCharID    int       `json:"kanji_char_id"`
+	PackID         *int      `json:"pack_id"` // Pointer to allow NULL
+	Mode           string    `json:"mode"`    // reading, meaning or kanji
+	MultipleChoice bool      `json:"multiple_choice"`
+	Prompt         string    `json:"prompt"`
+	Answer         string    `json:"answer"`
+	IsCorrect      bool      `json:"is_correct"`
+	AnsweredAt     time.Time `json:"answered_at"`
+}
+
+// PackHasKanji reports whether the kanji is a member of the pack
+func PackHasKanji(db *sql.DB, packID, kanjiCharID int) (bool, error) {
+	var member bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM kanji_go.pack_members
+		WHERE pack_id = $1 AND kanji_char_id = $2)`, packID, kanjiCharID).Scan(&member)
+	if err != nil {
+		return false, fmt.Errorf("failed to check pack member: %w", err)
+	}
+	return member, nil
+}
+
+// GetQuizKanji picks up to limit random kanji from a pack, with meanings
+// and readings loaded. The kanji with ID avoid (the previous question) is
+// only picked when nothing else is left.
+func GetQuizKanji(db *sql.DB, packID, avoid, limit int) ([]Kanji, error) {
+	rows, err := db.Query(`
+		SELECT `+kanjiColumns+`
+		FROM kanji_go.kanji k
+		JOIN kanji_go.pack_members m ON m.kanji_char_id = k.kanji_char_id
+		WHERE m.pack_id = $1
+		ORDER BY k.kanji_char_id = $2, random()
+		LIMIT $3
+	`, packID, avoid, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quiz kanji: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Kanji
+	for rows.Next() {
+		var k Kanji
+		if err := rows.Scan(kanjiScanDest(&k)...); err != nil {
+			return nil, fmt.Errorf("failed to scan quiz kanji: %w", err)
+		}
+		list = append(list, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate quiz kanji: %w", err)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := attachMeanings(db, list); err != nil {
+		return nil, err
+	}
+	if err := attachReadings(db, list, false); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetDistractorPool returns up to limit kanji that look or sound like k,
+// with meanings and readings loaded: ones sharing its radical, a reading,
+// a similar stroke count or its JLPT level, most similar first. The order
+// is stable so a seeded pick from the pool is repeatable.
+func GetDistractorPool(db *sql.DB, k *Kanji, limit int) ([]Kanji, error) {
+	rows, err := db.Query(`
+		SELECT `+kanjiColumns+`
+		FROM kanji_go.kanji k
+		CROSS JOIN LATERAL (
+			SELECT
+				COALESCE(k.radical = $2::INT, FALSE) AS same_radical,
+				EXISTS (
+					SELECT 1 FROM kanji_go.kanji_readings r
+					JOIN kanji_go.kanji_readings t ON t.kana = r.kana
+					WHERE r.kanji_char_id = k.kanji_char_id AND t.kanji_char_id = $1
+					  AND r.reading_type <> 'nanori' AND t.reading_type <> 'nanori'
+				) AS same_reading,
+				COALESCE(abs(k.stroke_count - $3::INT), 99) AS stroke_diff,
+				COALESCE(k.jlpt_level = $4::VARCHAR, FALSE) AS same_level
+		) s
+		WHERE k.kanji_char_id <> $1
+		  AND (s.same_radical OR s.same_reading OR s.stroke_diff <= 3 OR s.same_level)
+		ORDER BY s.same_radical::INT * 3 + s.same_reading::INT * 3
+		         + CASE WHEN s.stroke_diff <= 1 THEN 2 WHEN s.stroke_diff <= 3 THEN 1 ELSE 0 END
+		         + s.same_level::INT DESC,
+		         k.frequency NULLS LAST, k.kanji_char_id
+		LIMIT $5
+	`, k.KanjiCharID, k.Radical, k.StrokeCount, k.JLPTLevel, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query distractor pool: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Kanji
+	for rows.Next() {
+		var c Kanji
+		if err := rows.Scan(kanjiScanDest(&c)...); err != nil {
+			return nil, fmt.Errorf("failed to scan distractor: %w", err)
+		}
+		list = append(list, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate distractor pool: %w", err)
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	if err := attachMeanings(db, list); err != nil {
+		return nil, err
+	}
+	if err := attachReadings(db, list, false); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetKanjiForQuiz loads one kanji with its meanings and readings, for
+// grading an answer
+func GetKanjiForQuiz(db *sql.DB, kanjiCharID int) (*Kanji, error) {
+	k, err := GetKanjiByID(db, kanjiCharID)
+	if err != nil {
+		return nil, err
+	}
+	list := []Kanji{*k}
+	if err := attachMeanings(db, list); err != nil {
+		return nil, err
+	}
+	if err := attachReadings(db, list, false); err != nil {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// SaveQuizResult records an answered question
+func SaveQuizResult(db *sql.DB, res *QuizResult) error {
+	err := db.QueryRow(`
+		INSERT INTO kanji_go.quiz_results
+		(user_id, kanji_char_id, pack_id, mode, is_multiple_choice, prompt, answer, is_correct)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING result_id, answered_at
+	`, res.UserID, res.KanjiCharID, res.PackID, res.Mode, res.MultipleChoice, res.Prompt, res.Answer, res.IsCorrect).
+		Scan(&res.ResultID, &res.AnsweredAt)
+	if err != nil {
+		return fmt.Errorf("failed to save quiz result: %w", err)
+	}
+	return nil
+}
+
+// GetQuizScore counts the user's correct and total answers since a time
+func GetQuizScore(db *sql.DB, userID int, since time.Time) (correct, total int, err error) {
+	err = db.QueryRow(`
+		SELECT COUNT(*) FILTER (WHERE is_correct), COUNT(*)
+		FROM kanji_go.quiz_results
+		WHERE user_id = $1 AND answered_at >= $2
+	`, userID, since).Scan(&correct, &total)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count quiz results: %w", err)
+	}
+	return correct, total, nil
+}
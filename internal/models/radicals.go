@@ -0,0 +1,180 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrRadicalNotFound is returned when a radical number has no row
+var ErrRadicalNotFound = errors.New("radical not found")
+
+// Radical is one of the 214 Kangxi radicals
+type Radical struct {
+	Number      int    `json:"number"`
+	Char        string `json:"char"`
+	StrokeCount int    `json:"stroke_count"`
+	Meaning     string `json:"meaning"`
+}
+
+// Component is a part kanji can be searched by (from RADKFILE/KRADFILE).
+// Key is the character stored in kanji_components; Display is how it is
+// drawn, which differs for radical forms like 亻 that RADKFILE writes as 化.
+type Component struct {
+	Key           string `json:"component"`
+	Display       string `json:"display"`
+	StrokeCount   *int   `json:"stroke_count"` // Pointers to allow NULL
+	RadicalNumber *int   `json:"radical_number"`
+	Meaning       string `json:"meaning,omitempty"` // the radical's meaning, if it is one
+	KanjiCount    int    `json:"kanji_count"`
+}
+
+// ComponentSearch is the result of a search by components
+type ComponentSearch struct {
+	Kanji []Kanji
+	Total int // kanji containing every component, beyond the limit too
+	// Possible holds the components that appear in at least one of the
+	// matching kanji; any other component would leave no results
+	Possible map[string]bool
+}
+
+// componentColumns is the column list scanned by componentScanDest
+const componentColumns = `c.component, c.display, c.stroke_count, c.radical_number,
+	COALESCE(r.meaning, ''),
+	(SELECT COUNT(*) FROM kanji_go.kanji_components kc WHERE kc.component = c.component)`
+
+func componentScanDest(c *Component) []any {
+	return []any{&c.Key, &c.Display, &c.StrokeCount, &c.RadicalNumber, &c.Meaning, &c.KanjiCount}
+}
+
+// componentMatch selects the IDs of kanji containing every component in
+// the TEXT[] parameter $n
+func componentMatch(n int) string {
+	return fmt.Sprintf(`SELECT kanji_char_id FROM kanji_go.kanji_components
+		WHERE component = ANY($%d)
+		GROUP BY kanji_char_id
+		HAVING COUNT(*) = cardinality($%d::TEXT[])`, n, n)
+}
+
+// GetRadical returns a Kangxi radical by number
+func GetRadical(db *sql.DB, number int) (*Radical, error) {
+	var r Radical
+	err := db.QueryRow(`
+		SELECT radical_number, radical_char, stroke_count, meaning
+		FROM kanji_go.radicals
+		WHERE radical_number = $1
+	`, number).Scan(&r.Number, &r.Char, &r.StrokeCount, &r.Meaning)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRadicalNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get radical: %w", err)
+	}
+	return &r, nil
+}
+
+// ListComponents returns every searchable component, by stroke count
+func ListComponents(db *sql.DB) ([]Component, error) {
+	return queryComponents(db, `
+		SELECT `+componentColumns+`
+		FROM kanji_go.components c
+		LEFT JOIN kanji_go.radicals r ON r.radical_number = c.radical_number
+		ORDER BY c.stroke_count NULLS LAST, c.radical_number NULLS LAST, c.component
+	`)
+}
+
+// GetKanjiComponents returns the components a kanji is made of
+func GetKanjiComponents(db *sql.DB, kanjiCharID int) ([]Component, error) {
+	return queryComponents(db, `
+		SELECT `+componentColumns+`
+		FROM kanji_go.kanji_components k
+		JOIN kanji_go.components c ON c.component = k.component
+		LEFT JOIN kanji_go.radicals r ON r.radical_number = c.radical_number
+		WHERE k.kanji_char_id = $1
+		ORDER BY c.stroke_count NULLS LAST, c.component
+	`, kanjiCharID)
+}
+
+func queryComponents(db *sql.DB, query string, args ...any) ([]Component, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query components: %w", err)
+	}
+	defer rows.Close()
+
+	var list []Component
+	for rows.Next() {
+		var c Component
+		if err := rows.Scan(componentScanDest(&c)...); err != nil {
+			return nil, fmt.Errorf("failed to scan component: %w", err)
+		}
+		list = append(list, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate components: %w", err)
+	}
+	return list, nil
+}
+
+// SearchByComponents finds the kanji containing every one of comps, fewest
+// strokes first, and which further components could still narrow them down
+func SearchByComponents(db *sql.DB, comps []string, limit int) (*ComponentSearch, error) {
+	res := &ComponentSearch{Possible: make(map[string]bool)}
+	if len(comps) == 0 {
+		return res, nil
+	}
+
+	if err := db.QueryRow(`SELECT COUNT(*) FROM (`+componentMatch(1)+`) m`, comps).Scan(&res.Total); err != nil {
+		return nil, fmt.Errorf("failed to count component matches: %w", err)
+	}
+	if res.Total == 0 {
+		return res, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT `+kanjiColumns+`
+		FROM kanji_go.kanji k
+		WHERE k.kanji_char_id IN (`+componentMatch(1)+`)
+		ORDER BY COALESCE(k.stroke_count, 32767), k.frequency NULLS LAST, k.kanji_char_id
+		LIMIT $2
+	`, comps, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search by components: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k Kanji
+		if err := rows.Scan(kanjiScanDest(&k)...); err != nil {
+			return nil, fmt.Errorf("failed to scan kanji: %w", err)
+		}
+		res.Kanji = append(res.Kanji, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate kanji: %w", err)
+	}
+
+	rows, err = db.Query(`
+		SELECT DISTINCT kc.component
+		FROM kanji_go.kanji_components kc
+		WHERE kc.kanji_char_id IN (`+componentMatch(1)+`)
+	`, comps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query possible components: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, fmt.Errorf("failed to scan component: %w", err)
+		}
+		res.Possible[c] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate possible components: %w", err)
+	}
+
+	if err := attachMeanings(db, res.Kanji); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,264 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
+)
+
+// Reading types
+const (
+	ReadingOn     = "on"
+	ReadingKun    = "kun"
+	ReadingNanori = "nanori"
+)
+
+// Reading is one on'yomi, kun'yomi or nanori of a kanji
+type Reading struct {
+	ReadingID   int    `json:"-"`
+	KanjiCharID int    `json:"-"`
+	Type        string `json:"type"`
+	Position    int    `json:"position"`
+	Kana        string `json:"kana"`      // full reading in hiragana
+	Stem        string `json:"stem"`      // the part the kanji spells
+	Okurigana   string `json:"okurigana"` // the kana that follow it
+	IsPrefix    bool   `json:"is_prefix"`
+	IsSuffix    bool   `json:"is_suffix"`
+	IsCommon    bool   `json:"is_common"`
+}
+
+// Romaji returns the reading in Hepburn romaji
+func (r Reading) Romaji() string {
+	return kana.ToRomaji(r.Kana, kana.Hepburn)
+}
+
+// String formats the reading in KANJIDIC notation: "た.べる", "-び", "お-"
+func (r Reading) String() string {
+	s := r.Stem
+	if r.Okurigana != "" {
+		s += "." + r.Okurigana
+	}
+	if r.IsSuffix {
+		s = "-" + s
+	}
+	if r.IsPrefix {
+		s += "-"
+	}
+	return s
+}
+
+// ParseReadings splits a comma-joined reading list in KANJIDIC notation
+// ("た.べる, く.う, -ぐい") into readings of the given type. Commas, 、 and
+// whitespace all separate readings, as in migration 000008's backfill.
+func ParseReadings(list, readingType string) []Reading {
+	fields := strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == '、' || unicode.IsSpace(r)
+	})
+
+	var readings []Reading
+	for _, raw := range fields {
+		raw = kana.ToHiragana(raw)
+		trimmed := strings.Trim(raw, "-")
+		if trimmed == "" {
+			continue
+		}
+		stem, okurigana, _ := strings.Cut(trimmed, ".")
+		readings = append(readings, Reading{
+			Type:      readingType,
+			Position:  len(readings),
+			Kana:      stem + okurigana,
+			Stem:      stem,
+			Okurigana: okurigana,
+			IsPrefix:  strings.HasSuffix(raw, "-"),
+			IsSuffix:  strings.HasPrefix(raw, "-"),
+		})
+	}
+	return readings
+}
+
+// FilterReadings returns the readings of one type, in order
+func FilterReadings(readings []Reading, readingType string) []Reading {
+	var out []Reading
+	for _, r := range readings {
+		if r.Type == readingType {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// readingColumns selects a kanji_readings row
+const readingColumns = `
+	r.reading_id, r.kanji_char_id, r.reading_type, r.position, r.kana, r.stem,
+	r.okurigana, r.is_prefix, r.is_suffix, r.is_common`
+
+// scanReadings reads rows selected with readingColumns
+func scanReadings(rows *sql.Rows) ([]Reading, error) {
+	defer rows.Close()
+
+	var readings []Reading
+	for rows.Next() {
+		var r Reading
+		if err := rows.Scan(&r.ReadingID, &r.KanjiCharID, &r.Type, &r.Position, &r.Kana, &r.Stem,
+			&r.Okurigana, &r.IsPrefix, &r.IsSuffix, &r.IsCommon); err != nil {
+			return nil, fmt.Errorf("failed to scan reading: %w", err)
+		}
+		readings = append(readings, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate readings: %w", err)
+	}
+	return readings, nil
+}
+
+// readingOrder sorts on'yomi, then kun'yomi, then nanori
+const readingOrder = `
+	ORDER BY r.kanji_char_id,
+	         CASE r.reading_type WHEN 'on' THEN 0 WHEN 'kun' THEN 1 ELSE 2 END,
+	         r.position`
+
+// GetKanjiReadings returns a kanji's readings: on'yomi, kun'yomi, then nanori
+func GetKanjiReadings(db *sql.DB, kanjiCharID int) ([]Reading, error) {
+	rows, err := db.Query(`SELECT `+readingColumns+` FROM kanji_go.kanji_readings r
+		WHERE r.kanji_char_id = $1`+readingOrder, kanjiCharID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query readings: %w", err)
+	}
+	return scanReadings(rows)
+}
+
+// attachReadings loads the readings for a list of kanji in one query; all
+// reads the whole table instead of filtering by id
+func attachReadings(db *sql.DB, list []Kanji, all bool) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	var rows *sql.Rows
+	var err error
+	if all {
+		rows, err = db.Query(`SELECT ` + readingColumns + ` FROM kanji_go.kanji_readings r` + readingOrder)
+	} else {
+		ids := make([]int, len(list))
+		for i, k := range list {
+			ids[i] = k.KanjiCharID
+		}
+		rows, err = db.Query(`SELECT `+readingColumns+` FROM kanji_go.kanji_readings r
+			WHERE r.kanji_char_id = ANY($1)`+readingOrder, ids)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query readings: %w", err)
+	}
+
+	readings, err := scanReadings(rows)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int]int, len(list))
+	for i, k := range list {
+		byID[k.KanjiCharID] = i
+	}
+	for _, r := range readings {
+		if i, ok := byID[r.KanjiCharID]; ok {
+			list[i].Readings = append(list[i].Readings, r)
+		}
+	}
+	return nil
+}
+
+// ReplaceReadings rewrites a kanji's readings inside tx if they differ from
+// the stored ones, and reports whether they did. is_common is kept for
+// readings that are still present.
+func ReplaceReadings(tx *sql.Tx, kanjiCharID int, readings []Reading) (bool, error) {
+	rows, err := tx.Query(`SELECT reading_type, position, kana, stem, okurigana, is_prefix, is_suffix, is_common
+		FROM kanji_go.kanji_readings WHERE kanji_char_id = $1`, kanjiCharID)
+	if err != nil {
+		return false, fmt.Errorf("failed to query readings: %w", err)
+	}
+	current := make(map[string]Reading)
+	common := make(map[string]bool)
+	for rows.Next() {
+		var r Reading
+		if err := rows.Scan(&r.Type, &r.Position, &r.Kana, &r.Stem, &r.Okurigana,
+			&r.IsPrefix, &r.IsSuffix, &r.IsCommon); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan reading: %w", err)
+		}
+		current[fmt.Sprintf("%s:%d", r.Type, r.Position)] = r
+		if r.IsCommon {
+			common[r.Type+":"+r.Kana] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to iterate readings: %w", err)
+	}
+
+	if sameReadings(current, readings) {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM kanji_go.kanji_readings WHERE kanji_char_id = $1`, kanjiCharID); err != nil {
+		return false, fmt.Errorf("failed to clear readings: %w", err)
+	}
+	for _, r := range readings {
+		_, err := tx.Exec(`
+			INSERT INTO kanji_go.kanji_readings
+			(kanji_char_id, reading_type, position, kana, stem, okurigana, is_prefix, is_suffix, is_common)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, kanjiCharID, r.Type, r.Position, r.Kana, r.Stem, r.Okurigana, r.IsPrefix, r.IsSuffix,
+			r.IsCommon || common[r.Type+":"+r.Kana])
+		if err != nil {
+			return false, fmt.Errorf("failed to insert reading: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// sameReadings reports whether the stored readings, keyed by type and
+// position, spell the same readings. Commonness is ignored since
+// ReplaceReadings keeps it anyway.
+func sameReadings(current map[string]Reading, readings []Reading) bool {
+	if len(current) != len(readings) {
+		return false
+	}
+	for _, r := range readings {
+		c, ok := current[fmt.Sprintf("%s:%d", r.Type, r.Position)]
+		if !ok || c.Kana != r.Kana || c.Stem != r.Stem || c.Okurigana != r.Okurigana ||
+			c.IsPrefix != r.IsPrefix || c.IsSuffix != r.IsSuffix {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateReadingCommonness marks readings used by common vocabulary. Kun'yomi
+// must spell a whole word with its okurigana (食べる たべる); on'yomi only
+// need to appear in the reading of a compound containing the kanji.
+func UpdateReadingCommonness(db *sql.DB) error {
+	_, err := db.Exec(`
+		UPDATE kanji_go.kanji_readings r
+		SET is_common = EXISTS (
+			SELECT 1
+			FROM kanji_go.kanji_vocabulary kv
+			JOIN kanji_go.vocabulary v ON v.vocab_id = kv.vocab_id
+			WHERE kv.kanji_char_id = r.kanji_char_id
+			  AND v.is_common
+			  AND CASE r.reading_type
+			        WHEN 'kun' THEN v.written = k.kanji_char || r.okurigana AND v.reading = r.kana
+			        ELSE strpos(v.reading, r.kana) > 0
+			      END
+		)
+		FROM kanji_go.kanji k
+		WHERE k.kanji_char_id = r.kanji_char_id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to update reading commonness: %w", err)
+	}
+	return nil
+}
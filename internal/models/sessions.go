@@ -0,0 +1,130 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSessionNotFound is returned when a session ID has no matching row
+var ErrSessionNotFound = errors.New("session not found")
+
+// NewSessionID returns a random, URL-safe session identifier
+func NewSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateSession inserts a new session for the given user (nil for anonymous)
+func CreateSession(db *sql.DB, username *string) (*Session, error) {
+	id, err := NewSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO kanji_go.sessions (session_id, curr_user)
+		VALUES ($1, $2)
+		RETURNING session_id, curr_user, curr_jlpt_level, curr_page,
+		          contact_popup_active, login_popup_active, payment_popup_active,
+		          left_sidebar_active, dark_mode_active, created_at, updated_at
+	`
+
+	session, err := scanSession(db.QueryRow(query, id, username))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSession loads a session by ID
+func GetSession(db *sql.DB, sessionID string) (*Session, error) {
+	query := `
+		SELECT session_id, curr_user, curr_jlpt_level, curr_page,
+		       contact_popup_active, login_popup_active, payment_popup_active,
+		       left_sidebar_active, dark_mode_active, created_at, updated_at
+		FROM kanji_go.sessions
+		WHERE session_id = $1
+	`
+
+	session, err := scanSession(db.QueryRow(query, sessionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session: %w", err)
+	}
+
+	return session, nil
+}
+
+// DeleteSession removes a session row
+func DeleteSession(db *sql.DB, sessionID string) error {
+	_, err := db.Exec(`DELETE FROM kanji_go.sessions WHERE session_id = $1`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// scanSession scans a single sessions row
+func scanSession(row *sql.Row) (*Session, error) {
+	var s Session
+	err := row.Scan(&s.SessionID, &s.CurrentUser, &s.CurrentJLPTLevel, &s.CurrentPage,
+		&s.ContactPopupActive, &s.LoginPopupActive, &s.PaymentPopupActive,
+		&s.LeftSidebarActive, &s.DarkModeActive, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UpdateSession saves the mutable session fields and bumps updated_at
+func UpdateSession(db *sql.DB, s *Session) error {
+	query := `
+		UPDATE kanji_go.sessions
+		SET curr_user = $2, curr_jlpt_level = $3, curr_page = $4,
+		    contact_popup_active = $5, login_popup_active = $6, payment_popup_active = $7,
+		    left_sidebar_active = $8, dark_mode_active = $9, updated_at = NOW()
+		WHERE session_id = $1
+		RETURNING updated_at
+	`
+
+	err := db.QueryRow(query, s.SessionID, s.CurrentUser, s.CurrentJLPTLevel, s.CurrentPage,
+		s.ContactPopupActive, s.LoginPopupActive, s.PaymentPopupActive,
+		s.LeftSidebarActive, s.DarkModeActive).Scan(&s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return nil
+}
+
+// TouchSession bumps updated_at so an active session doesn't expire
+func TouchSession(db *sql.DB, sessionID string) error {
+	_, err := db.Exec(`UPDATE kanji_go.sessions SET updated_at = NOW() WHERE session_id = $1`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions not used since the given time
+// and returns how many were deleted
+func DeleteExpiredSessions(db *sql.DB, before time.Time) (int64, error) {
+	result, err := db.Exec(`DELETE FROM kanji_go.sessions WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
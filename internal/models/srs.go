@@ -0,0 +1,200 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SRSCard is a user's spaced repetition state for one kanji
+type SRSCard struct {
+	UserID         int        `json:"user_id"`
+	KanjiCharID    int        `json:"kanji_char_id"`
+	State          string     `json:"state"` // learning, review or relearning
+	EaseFactor     float64    `json:"ease_factor"`
+	IntervalDays   int        `json:"interval_days"`
+	Repetitions    int        `json:"repetitions"`
+	Lapses         int        `json:"lapses"`
+	DueAt          time.Time  `json:"due_at"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at"` // Pointer to allow NULL
+}
+
+// ReviewItem is a kanji in the review queue. Card is nil for a new kanji.
+type ReviewItem struct {
+	Kanji Kanji
+	Card  *SRSCard
+}
+
+// ErrCardNotFound is returned when a user has no SRS card for a kanji
+var ErrCardNotFound = errors.New("srs card not found")
+
+// packScope restricts a query on alias c to the kanji of one pack; packID 0
+// matches everything
+const packScope = `($%[1]d = 0 OR EXISTS (
+	SELECT 1 FROM kanji_go.pack_members m WHERE m.pack_id = $%[1]d AND m.kanji_char_id = c.kanji_char_id))`
+
+// GetDueReviews returns the user's cards due at or before now, oldest first,
+// optionally limited to one pack (packID 0 for all cards)
+func GetDueReviews(db *sql.DB, userID, packID int, now time.Time, limit int) ([]ReviewItem, error) {
+	query := `
+		SELECT ` + kanjiColumns + `,
+		       c.state, c.ease_factor, c.interval_days, c.repetitions, c.lapses,
+		       c.due_at, c.last_reviewed_at
+		FROM kanji_go.srs_cards c
+		JOIN kanji_go.kanji k ON k.kanji_char_id = c.kanji_char_id
+		WHERE c.user_id = $1 AND c.due_at <= $2 AND ` + fmt.Sprintf(packScope, 4) + `
+		ORDER BY c.due_at, c.kanji_char_id
+		LIMIT $3
+	`
+
+	rows, err := db.Query(query, userID, now, limit, packID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var items []ReviewItem
+	for rows.Next() {
+		item := ReviewItem{Card: &SRSCard{UserID: userID}}
+		dest := append(kanjiScanDest(&item.Kanji),
+			&item.Card.State, &item.Card.EaseFactor, &item.Card.IntervalDays,
+			&item.Card.Repetitions, &item.Card.Lapses, &item.Card.DueAt, &item.Card.LastReviewedAt)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan due review: %w", err)
+		}
+		item.Card.KanjiCharID = item.Kanji.KanjiCharID
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// GetNewKanji returns kanji that have no SRS card yet, in pack order, from
+// one pack or (packID 0) from every pack the user subscribes to and can
+// still see
+func GetNewKanji(db *sql.DB, userID, packID int, limit int) ([]ReviewItem, error) {
+	query := `
+		SELECT ` + kanjiColumns + `
+		FROM kanji_go.kanji k
+		JOIN (
+			SELECT m.kanji_char_id, MIN(m.position) AS position
+			FROM kanji_go.pack_members m
+			WHERE m.pack_id = $3 OR ($3 = 0 AND EXISTS (
+				SELECT 1 FROM kanji_go.pack_subscriptions s
+				JOIN kanji_go.packs p ON p.pack_id = s.pack_id
+				WHERE s.user_id = $1 AND s.pack_id = m.pack_id
+				  AND (p.owner_id IS NULL OR p.is_public OR p.owner_id = $1)))
+			GROUP BY m.kanji_char_id
+		) m ON m.kanji_char_id = k.kanji_char_id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM kanji_go.srs_cards c
+			WHERE c.user_id = $1 AND c.kanji_char_id = k.kanji_char_id
+		)
+		ORDER BY m.position, k.kanji_char_id
+		LIMIT $2
+	`
+
+	rows, err := db.Query(query, userID, limit, packID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query new kanji: %w", err)
+	}
+	defer rows.Close()
+
+	var items []ReviewItem
+	for rows.Next() {
+		var item ReviewItem
+		if err := rows.Scan(kanjiScanDest(&item.Kanji)...); err != nil {
+			return nil, fmt.Errorf("failed to scan new kanji: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// CountDueReviews returns how many of the user's cards are due at or before
+// now, optionally limited to one pack (packID 0 for all cards)
+func CountDueReviews(db *sql.DB, userID, packID int, now time.Time) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM kanji_go.srs_cards c WHERE c.user_id = $1 AND c.due_at <= $2 AND `+
+		fmt.Sprintf(packScope, 3), userID, now, packID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count due reviews: %w", err)
+	}
+	return n, nil
+}
+
+// CountNewCardsSince returns how many cards the user started since the given time
+func CountNewCardsSince(db *sql.DB, userID int, since time.Time) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM kanji_go.srs_cards WHERE user_id = $1 AND created_at >= $2`,
+		userID, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count new cards: %w", err)
+	}
+	return n, nil
+}
+
+// GetSRSCard loads a user's card for one kanji
+func GetSRSCard(db *sql.DB, userID, kanjiCharID int) (*SRSCard, error) {
+	query := `
+		SELECT state, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at
+		FROM kanji_go.srs_cards
+		WHERE user_id = $1 AND kanji_char_id = $2
+	`
+
+	card := SRSCard{UserID: userID, KanjiCharID: kanjiCharID}
+	err := db.QueryRow(query, userID, kanjiCharID).Scan(&card.State, &card.EaseFactor,
+		&card.IntervalDays, &card.Repetitions, &card.Lapses, &card.DueAt, &card.LastReviewedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCardNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query srs card: %w", err)
+	}
+
+	return &card, nil
+}
+
+// SaveReview stores the rescheduled card and logs the grade in one transaction
+func SaveReview(db *sql.DB, card *SRSCard, grade int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO kanji_go.srs_cards
+		(user_id, kanji_char_id, state, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, kanji_char_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			ease_factor = EXCLUDED.ease_factor,
+			interval_days = EXCLUDED.interval_days,
+			repetitions = EXCLUDED.repetitions,
+			lapses = EXCLUDED.lapses,
+			due_at = EXCLUDED.due_at,
+			last_reviewed_at = EXCLUDED.last_reviewed_at,
+			updated_at = NOW()
+	`, card.UserID, card.KanjiCharID, card.State, card.EaseFactor, card.IntervalDays,
+		card.Repetitions, card.Lapses, card.DueAt, card.LastReviewedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save srs card: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO kanji_go.srs_reviews (user_id, kanji_char_id, grade, interval_days, ease_factor)
+		VALUES ($1, $2, $3, $4, $5)
+	`, card.UserID, card.KanjiCharID, grade, card.IntervalDays, card.EaseFactor)
+	if err != nil {
+		return fmt.Errorf("failed to log review: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
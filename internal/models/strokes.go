@@ -0,0 +1,43 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Stroke is one stroke of a KanjiVG stroke order diagram
+type Stroke struct {
+	Number int      `json:"number"`
+	Type   string   `json:"type"`              // CJK stroke character, e.g. ㇐
+	Path   string   `json:"path"`              // SVG path data on a 109x109 canvas
+	LabelX *float64 `json:"label_x,omitempty"` // Pointers to allow NULL
+	LabelY *float64 `json:"label_y,omitempty"`
+}
+
+// GetKanjiStrokes returns a kanji's strokes in writing order; none if no
+// diagram has been imported
+func GetKanjiStrokes(db *sql.DB, kanjiCharID int) ([]Stroke, error) {
+	rows, err := db.Query(`
+		SELECT stroke_number, stroke_type, path, label_x, label_y
+		FROM kanji_go.kanji_strokes
+		WHERE kanji_char_id = $1
+		ORDER BY stroke_number
+	`, kanjiCharID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query strokes: %w", err)
+	}
+	defer rows.Close()
+
+	var strokes []Stroke
+	for rows.Next() {
+		var s Stroke
+		if err := rows.Scan(&s.Number, &s.Type, &s.Path, &s.LabelX, &s.LabelY); err != nil {
+			return nil, fmt.Errorf("failed to scan stroke: %w", err)
+		}
+		strokes = append(strokes, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate strokes: %w", err)
+	}
+	return strokes, nil
+}
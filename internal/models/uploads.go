@@ -0,0 +1,574 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Errors returned by the upload functions
+var (
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadInProgress is returned by CreateUpload when the owner
+	// already has a live upload of the same blob, typically one still
+	// pending from a double submit
+	ErrUploadInProgress = errors.New("upload already in progress")
+)
+
+// Upload statuses: rows are pending while their objects are being written,
+// and deleted until the purge job removes them
+const (
+	UploadPending = "pending"
+	UploadStored  = "stored"
+	UploadDeleted = "deleted"
+)
+
+// SystemActor is the actor name audited for deletions the application makes
+// itself, such as releasing an image a draft no longer uses
+const SystemActor = "system"
+
+// Upload is one user's upload of an image, recorded in kanji_go.uploads.
+// Identical images share a blob.
+type Upload struct {
+	UploadID    int64
+	ObjectKey   string // the full-size object of its blob
+	OwnerID     *int   // Pointers to allow NULL
+	KanjiCharID *int
+	ContentHash string // hex SHA-256 of the file as uploaded
+	SizeBytes   int64
+	Width       int
+	Height      int
+	Status      string
+	CreatedAt   time.Time
+	DeletedAt   *time.Time
+	DeletedBy   *int
+
+	// Filled in by ListUploads
+	OwnerName *string
+	KanjiChar *string
+}
+
+// UploadBlob is a stored image shared by every upload of the same content.
+// Its status is UploadPending until one of those uploads has written every
+// size, then UploadStored.
+type UploadBlob struct {
+	ObjectKey   string
+	ContentHash string
+	SizeBytes   int64
+	Width       int
+	Height      int
+	RefCount    int
+	Status      string
+	CreatedAt   time.Time
+}
+
+// uploadColumns and uploadScanDest keep the upload SELECTs in step
+const uploadColumns = `
+	u.upload_id, u.object_key, u.owner_id, u.kanji_char_id, u.content_hash,
+	u.size_bytes, u.width, u.height, u.status, u.created_at, u.deleted_at, u.deleted_by`
+
+func uploadScanDest(u *Upload) []any {
+	return []any{&u.UploadID, &u.ObjectKey, &u.OwnerID, &u.KanjiCharID, &u.ContentHash,
+		&u.SizeBytes, &u.Width, &u.Height, &u.Status, &u.CreatedAt, &u.DeletedAt, &u.DeletedBy}
+}
+
+// CreateUpload records a new upload as pending, before its objects are
+// written, and takes a reference to its blob, recording the blob as pending
+// if it is new. It fills in the upload's ID and creation time. Returns
+// ErrUploadInProgress if the owner already has a live upload of the blob.
+func CreateUpload(db *sql.DB, u *Upload) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Concurrent uploads of the same content write the same objects, so
+	// they simply share the blob
+	if _, err := tx.Exec(`
+		INSERT INTO kanji_go.upload_blobs (object_key, content_hash, size_bytes, width, height, ref_count, status)
+		VALUES ($1, $2, $3, $4, $5, 1, $6)
+		ON CONFLICT (object_key) DO UPDATE SET ref_count = kanji_go.upload_blobs.ref_count + 1
+	`, u.ObjectKey, u.ContentHash, u.SizeBytes, u.Width, u.Height, UploadPending); err != nil {
+		return fmt.Errorf("failed to record blob: %w", err)
+	}
+
+	u.Status = UploadPending
+	if err := insertUpload(tx, u); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == "idx_uploads_owner_object" {
+			return ErrUploadInProgress
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AddUploadReference records an upload of content that is already stored,
+// pointing it at the existing blob u.ObjectKey. Returns ErrUploadNotFound if
+// that blob has been released in the meantime, or isn't stored yet.
+func AddUploadReference(db *sql.DB, u *Upload) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE kanji_go.upload_blobs SET ref_count = ref_count + 1
+		WHERE object_key = $1 AND ref_count > 0 AND status = $2`, u.ObjectKey, UploadStored)
+	if err != nil {
+		return fmt.Errorf("failed to reference blob: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to reference blob: %w", err)
+	} else if n == 0 {
+		return ErrUploadNotFound
+	}
+
+	u.Status = UploadStored
+	if err := insertUpload(tx, u); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertUpload writes the uploads row for u
+func insertUpload(tx *sql.Tx, u *Upload) error {
+	err := tx.QueryRow(`
+		INSERT INTO kanji_go.uploads
+		(object_key, owner_id, kanji_char_id, content_hash, size_bytes, width, height, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING upload_id, created_at
+	`, u.ObjectKey, u.OwnerID, u.KanjiCharID, u.ContentHash, u.SizeBytes, u.Width, u.Height, u.Status).
+		Scan(&u.UploadID, &u.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert upload: %w", err)
+	}
+	return nil
+}
+
+// GetUploadBlob returns the stored blob with the given content hash. Blobs
+// whose objects are still being written are skipped.
+func GetUploadBlob(db *sql.DB, contentHash string) (*UploadBlob, error) {
+	var b UploadBlob
+	err := db.QueryRow(`
+		SELECT object_key, content_hash, size_bytes, width, height, ref_count, status, created_at
+		FROM kanji_go.upload_blobs
+		WHERE content_hash = $1 AND ref_count > 0 AND status = $2
+		ORDER BY created_at
+		LIMIT 1
+	`, contentHash, UploadStored).Scan(&b.ObjectKey, &b.ContentHash, &b.SizeBytes, &b.Width, &b.Height, &b.RefCount, &b.Status, &b.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blob: %w", err)
+	}
+	return &b, nil
+}
+
+// GetOwnerUpload returns the user's live (not deleted) upload of a blob
+func GetOwnerUpload(db *sql.DB, ownerID int, objectKey string) (*Upload, error) {
+	var u Upload
+	err := db.QueryRow(`SELECT `+uploadColumns+` FROM kanji_go.uploads u
+		WHERE u.owner_id = $1 AND u.object_key = $2 AND u.status <> $3`,
+		ownerID, objectKey, UploadDeleted).Scan(uploadScanDest(&u)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query upload: %w", err)
+	}
+	return &u, nil
+}
+
+// SetUploadKanji links an upload to a kanji
+func SetUploadKanji(db *sql.DB, uploadID int64, kanjiCharID int) error {
+	result, err := db.Exec(`UPDATE kanji_go.uploads SET kanji_char_id = $1 WHERE upload_id = $2`, kanjiCharID, uploadID)
+	if err != nil {
+		return fmt.Errorf("failed to update upload: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update upload: %w", err)
+	}
+	if n == 0 {
+		return ErrUploadNotFound
+	}
+	return nil
+}
+
+// MarkUploadStored flags an upload, and its blob, as stored once all its
+// objects are in the bucket, so identical uploads may reuse them
+func MarkUploadStored(db *sql.DB, uploadID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var key string
+	err = tx.QueryRow(`UPDATE kanji_go.uploads SET status = $1 WHERE upload_id = $2 RETURNING object_key`,
+		UploadStored, uploadID).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("upload %d no longer exists", uploadID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update upload: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE kanji_go.upload_blobs SET status = $1 WHERE object_key = $2`, UploadStored, key); err != nil {
+		return fmt.Errorf("failed to update blob: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DiscardUpload removes an upload that failed part way and releases its
+// blob. gone reports whether that was the last reference, so the objects
+// should be deleted.
+func DiscardUpload(db *sql.DB, uploadID int64) (gone bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var key string
+	err = tx.QueryRow(`DELETE FROM kanji_go.uploads WHERE upload_id = $1 RETURNING object_key`, uploadID).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrUploadNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to delete upload: %w", err)
+	}
+	if gone, err = releaseBlob(tx, key); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return gone, nil
+}
+
+// ReleaseOwnerUploads soft-deletes the owner's stored uploads of the blob
+// stored under any of the keys, recording each in the audit log as done by
+// SystemActor. Like any deleted upload they are purged, and the objects
+// deleted once nothing else references them, after the retention window.
+// Keys the owner has no upload of are left alone. Returns how many uploads
+// were released.
+func ReleaseOwnerUploads(db *sql.DB, owner string, keys []string) (int, error) {
+	result, err := db.Exec(`
+		WITH released AS (
+			UPDATE kanji_go.uploads SET status = $1, deleted_at = NOW()
+			WHERE object_key = ANY($2) AND status = $3
+			  AND owner_id = (SELECT id FROM kanji_go.users WHERE username = $4)
+			RETURNING upload_id, object_key
+		)
+		INSERT INTO kanji_go.upload_audit (upload_id, object_key, action, actor_name)
+		SELECT upload_id, object_key, 'delete', $5 FROM released
+	`, UploadDeleted, keys, UploadStored, owner, SystemActor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release uploads: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to release uploads: %w", err)
+	}
+	return int(n), nil
+}
+
+// releaseBlob drops one reference to a blob, removing its row when it was
+// the last. It reports whether the blob's objects are now unreferenced.
+func releaseBlob(tx *sql.Tx, objectKey string) (bool, error) {
+	var refs int
+	err := tx.QueryRow(`UPDATE kanji_go.upload_blobs SET ref_count = ref_count - 1
+		WHERE object_key = $1 AND ref_count > 0
+		RETURNING ref_count`, objectKey).Scan(&refs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to release blob: %w", err)
+	}
+	if refs > 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(`DELETE FROM kanji_go.upload_blobs WHERE object_key = $1 AND ref_count = 0`, objectKey); err != nil {
+		return false, fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return true, nil
+}
+
+// LatestUploadTimes returns when each of the object keys was last
+// uploaded. Reusing a blob doesn't touch its objects, so this is the only
+// record of a recent reference to an old blob.
+func LatestUploadTimes(db *sql.DB, keys []string) (map[string]time.Time, error) {
+	rows, err := db.Query(`SELECT object_key, MAX(created_at) FROM kanji_go.uploads
+		WHERE object_key = ANY($1)
+		GROUP BY object_key`, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query uploads: %w", err)
+	}
+	defer rows.Close()
+
+	latest := make(map[string]time.Time)
+	for rows.Next() {
+		var key string
+		var t time.Time
+		if err := rows.Scan(&key, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan upload: %w", err)
+		}
+		latest[key] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate uploads: %w", err)
+	}
+	return latest, nil
+}
+
+// DeleteOrphanedUpload removes every upload row, and the blob, recorded
+// under any of the given object keys, so their objects can be deleted. The
+// blob is locked while its uploads are checked, so a concurrent reference
+// either lands first, and is seen here, or finds the blob gone. Nothing is
+// removed if an upload was made after cutoff; removed reports whether the
+// rows went.
+func DeleteOrphanedUpload(db *sql.DB, keys []string, cutoff time.Time) (removed bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT 1 FROM kanji_go.upload_blobs WHERE object_key = ANY($1) FOR UPDATE`, keys); err != nil {
+		return false, fmt.Errorf("failed to lock blob: %w", err)
+	}
+	var recent bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM kanji_go.uploads
+		WHERE object_key = ANY($1) AND created_at > $2)`, keys, cutoff).Scan(&recent)
+	if err != nil {
+		return false, fmt.Errorf("failed to check uploads: %w", err)
+	}
+	if recent {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM kanji_go.uploads WHERE object_key = ANY($1)`, keys); err != nil {
+		return false, fmt.Errorf("failed to delete uploads: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM kanji_go.upload_blobs WHERE object_key = ANY($1)`, keys); err != nil {
+		return false, fmt.Errorf("failed to delete blobs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// ListUploads returns stored uploads newest first, limit at a time. before
+// is the ID of the last upload on the previous page, or 0 for the first;
+// next is 0 on the last page.
+func ListUploads(db *sql.DB, before int64, limit int) (uploads []Upload, next int64, err error) {
+	args := []any{UploadStored, limit + 1}
+	filter := ""
+	if before > 0 {
+		args = append(args, before)
+		filter = " AND u.upload_id < $3"
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	rows, err := db.Query(`
+		SELECT `+uploadColumns+`, usr.username, k.kanji_char
+		FROM kanji_go.uploads u
+		LEFT JOIN kanji_go.users usr ON usr.id = u.owner_id
+		LEFT JOIN kanji_go.kanji k ON k.kanji_char_id = u.kanji_char_id
+		WHERE u.status = $1`+filter+`
+		ORDER BY u.upload_id DESC
+		LIMIT $2`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query uploads: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u Upload
+		if err := rows.Scan(append(uploadScanDest(&u), &u.OwnerName, &u.KanjiChar)...); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan upload: %w", err)
+		}
+		uploads = append(uploads, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate uploads: %w", err)
+	}
+
+	if len(uploads) > limit {
+		uploads = uploads[:limit]
+		next = uploads[limit-1].UploadID
+	}
+	return uploads, next, nil
+}
+
+// GetUpload returns an upload by ID
+func GetUpload(db *sql.DB, uploadID int64) (*Upload, error) {
+	var u Upload
+	err := db.QueryRow(`SELECT `+uploadColumns+` FROM kanji_go.uploads u
+		WHERE u.upload_id = $1`, uploadID).Scan(uploadScanDest(&u)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query upload: %w", err)
+	}
+	return &u, nil
+}
+
+// SoftDeleteUpload marks a stored upload deleted by actor and records it in
+// the audit log. asAdmin notes that the upload wasn't the actor's own.
+// Returns ErrUploadNotFound if it isn't stored (already deleted, or gone).
+func SoftDeleteUpload(db *sql.DB, uploadID int64, actor *User, asAdmin bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var key string
+	err = tx.QueryRow(`
+		UPDATE kanji_go.uploads SET status = $1, deleted_at = NOW(), deleted_by = $2
+		WHERE upload_id = $3 AND status = $4
+		RETURNING object_key
+	`, UploadDeleted, actor.ID, uploadID, UploadStored).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUploadNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO kanji_go.upload_audit (upload_id, object_key, action, actor_id, actor_name, as_admin)
+		VALUES ($1, $2, 'delete', $3, $4, $5)
+	`, uploadID, key, actor.ID, actor.Username, asAdmin); err != nil {
+		return fmt.Errorf("failed to record deletion: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UploadIsDeleted reports whether the blob stored under any of the given
+// object keys has been deleted by every user who uploaded it
+func UploadIsDeleted(db *sql.DB, keys []string) (bool, error) {
+	var deleted bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM kanji_go.uploads WHERE object_key = ANY($1) AND status = $2)
+		   AND NOT EXISTS (SELECT 1 FROM kanji_go.uploads WHERE object_key = ANY($1) AND status <> $2)
+	`, keys, UploadDeleted).Scan(&deleted)
+	if err != nil {
+		return false, fmt.Errorf("failed to check upload status: %w", err)
+	}
+	return deleted, nil
+}
+
+// ListPurgeableUploads returns up to limit uploads deleted before cutoff,
+// oldest first
+func ListPurgeableUploads(db *sql.DB, cutoff time.Time, limit int) ([]Upload, error) {
+	rows, err := db.Query(`SELECT `+uploadColumns+` FROM kanji_go.uploads u
+		WHERE u.status = $1 AND u.deleted_at < $2
+		ORDER BY u.deleted_at
+		LIMIT $3`, UploadDeleted, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted uploads: %w", err)
+	}
+	defer rows.Close()
+
+	var uploads []Upload
+	for rows.Next() {
+		var u Upload
+		if err := rows.Scan(uploadScanDest(&u)...); err != nil {
+			return nil, fmt.Errorf("failed to scan upload: %w", err)
+		}
+		uploads = append(uploads, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate deleted uploads: %w", err)
+	}
+	return uploads, nil
+}
+
+// PurgeUpload removes a deleted upload's row and releases its blob,
+// recording the purge in the audit log. gone reports whether that was the
+// blob's last reference, so its objects should be deleted.
+func PurgeUpload(db *sql.DB, u *Upload) (gone bool, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO kanji_go.upload_audit (upload_id, object_key, action)
+		VALUES ($1, $2, 'purge')
+	`, u.UploadID, u.ObjectKey); err != nil {
+		return false, fmt.Errorf("failed to record purge: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM kanji_go.uploads WHERE upload_id = $1 AND status = $2`,
+		u.UploadID, UploadDeleted)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete upload: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return false, fmt.Errorf("failed to delete upload: %w", err)
+	} else if n == 0 {
+		return false, ErrUploadNotFound
+	}
+	if gone, err = releaseBlob(tx, u.ObjectKey); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return gone, nil
+}
+
+// DeletedUploadKeys returns which of the object keys belong to a
+// soft-deleted upload
+func DeletedUploadKeys(db *sql.DB, keys []string) (map[string]bool, error) {
+	rows, err := db.Query(`SELECT DISTINCT object_key FROM kanji_go.uploads
+		WHERE object_key = ANY($1) AND status = $2`, keys, UploadDeleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted uploads: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted upload: %w", err)
+		}
+		deleted[key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate deleted uploads: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Errors returned by the user functions
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrDuplicateEmail    = errors.New("email already registered")
+	ErrDuplicateUsername = errors.New("username already taken")
+)
+
+// uniqueViolation is the Postgres error code for a unique constraint violation
+const uniqueViolation = "23505"
+
+// CreateUser inserts a new user. PasswordHash must already be set.
+// Returns ErrDuplicateEmail or ErrDuplicateUsername if either is taken.
+func CreateUser(db *sql.DB, user *User) error {
+	query := `
+		INSERT INTO kanji_go.users (email, username, password_hash)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := db.QueryRow(query, user.Email, user.Username, user.PasswordHash).
+		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			switch pgErr.ConstraintName {
+			case "users_email_key", "users_email_lower_key":
+				return ErrDuplicateEmail
+			case "users_username_key", "users_username_lower_key":
+				return ErrDuplicateUsername
+			}
+		}
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserByLogin looks up a user by email or username (case-insensitive)
+func GetUserByLogin(db *sql.DB, login string) (*User, error) {
+	query := `
+		SELECT id, email, username, password_hash, is_admin, created_at, updated_at
+		FROM kanji_go.users
+		WHERE LOWER(email) = LOWER($1) OR LOWER(username) = LOWER($1)
+		LIMIT 1
+	`
+
+	var user User
+	err := db.QueryRow(query, login).Scan(&user.ID, &user.Email, &user.Username,
+		&user.PasswordHash, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// GetUserByUsername looks up a user by exact username
+func GetUserByUsername(db *sql.DB, username string) (*User, error) {
+	query := `
+		SELECT id, email, username, password_hash, is_admin, created_at, updated_at
+		FROM kanji_go.users
+		WHERE username = $1
+	`
+
+	var user User
+	err := db.QueryRow(query, username).Scan(&user.ID, &user.Email, &user.Username,
+		&user.PasswordHash, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	return &user, nil
+}
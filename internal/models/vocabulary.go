@@ -0,0 +1,44 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Vocabulary is a JMdict word
+type Vocabulary struct {
+	VocabID   int    `json:"vocab_id"`
+	JMdictSeq int    `json:"jmdict_seq"`
+	Written   string `json:"written"`
+	Reading   string `json:"reading"`
+	Meanings  string `json:"meanings"`
+	IsCommon  bool   `json:"is_common"`
+}
+
+// GetVocabularyForKanji returns words containing a kanji, common and short
+// words first
+func GetVocabularyForKanji(db *sql.DB, kanjiCharID int, limit int) ([]Vocabulary, error) {
+	rows, err := db.Query(`
+		SELECT v.vocab_id, v.jmdict_seq, v.written, v.reading, v.meanings, COALESCE(v.is_common, FALSE)
+		FROM kanji_go.kanji_vocabulary kv
+		JOIN kanji_go.vocabulary v ON v.vocab_id = kv.vocab_id
+		WHERE kv.kanji_char_id = $1
+		ORDER BY COALESCE(v.is_common, FALSE) DESC, char_length(v.written), v.jmdict_seq
+		LIMIT $2
+	`, kanjiCharID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vocabulary: %w", err)
+	}
+	defer rows.Close()
+
+	var words []Vocabulary
+	for rows.Next() {
+		var v Vocabulary
+		if err := rows.Scan(&v.VocabID, &v.JMdictSeq, &v.Written, &v.Reading, &v.Meanings, &v.IsCommon); err != nil {
+			return nil, fmt.Errorf("failed to scan vocabulary: %w", err)
+		}
+		words = append(words, v)
+	}
+
+	return words, rows.Err()
+}
@@ -0,0 +1,153 @@
+package quiz
+
+import (
+	"math/rand/v2"
+	"sort"
+
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+// DefaultChoices is how many options a multiple-choice question offers
+const DefaultChoices = 4
+
+// Similarity weights: how much each shared trait makes a kanji easy to
+// mistake for another
+const (
+	weightRadical     = 3 // same Kangxi radical
+	weightReading     = 3 // an on'yomi or kun'yomi in common
+	weightStrokesNear = 2 // stroke count within 1
+	weightStrokesSome = 1 // stroke count within 3
+	weightJLPT        = 1 // same JLPT level
+)
+
+// Choices returns n options for a multiple-choice question in shuffled
+// order: the correct answer plus distractors from the kanji in pool most
+// easily confused with it. pool kanji need Meanings and Readings loaded. The same
+// seed, question and pool always give the same options. Fewer than n
+// options are returned if the pool runs out.
+func Choices(q Question, pool []models.Kanji, n int, seed uint64) []string {
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+
+	answer, ok := option(q.Mode, q.Kanji, nil)
+	if !ok {
+		return nil
+	}
+	options := []string{answer}
+	seen := map[string]bool{answer: true}
+
+	// Rank the pool by similarity, breaking ties at random, then draw from
+	// the most similar few so the same distractors don't always appear.
+	// Kanji with nothing in common are only used if the rest run out.
+	type candidate struct {
+		kanji models.Kanji
+		score int
+		tie   uint64
+	}
+	var cands []candidate
+	for _, c := range pool {
+		if c.KanjiCharID == q.Kanji.KanjiCharID {
+			continue
+		}
+		cands = append(cands, candidate{c, Similarity(q.Kanji, c), rng.Uint64()})
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].score != cands[j].score {
+			return cands[i].score > cands[j].score
+		}
+		return cands[i].tie < cands[j].tie
+	})
+	window := 0
+	for window < len(cands) && window < max(2*(n-1), 6) && cands[window].score > 0 {
+		window++
+	}
+	rng.Shuffle(window, func(i, j int) { cands[i], cands[j] = cands[j], cands[i] })
+
+	for _, c := range cands {
+		if len(options) == n {
+			break
+		}
+		opt, ok := option(q.Mode, c.kanji, func(s string) bool { return seen[s] || Grade(q, s).Correct })
+		if !ok {
+			continue
+		}
+		seen[opt] = true
+		options = append(options, opt)
+	}
+
+	rng.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+	return options
+}
+
+// Similarity scores how easily c could be mistaken for k; 0 means nothing
+// in common
+func Similarity(k, c models.Kanji) int {
+	score := 0
+	if k.Radical != nil && c.Radical != nil && *k.Radical == *c.Radical {
+		score += weightRadical
+	}
+	if sharesReading(k, c) {
+		score += weightReading
+	}
+	if k.StrokeCount != nil && c.StrokeCount != nil {
+		switch d := *k.StrokeCount - *c.StrokeCount; {
+		case d >= -1 && d <= 1:
+			score += weightStrokesNear
+		case d >= -3 && d <= 3:
+			score += weightStrokesSome
+		}
+	}
+	if k.JLPTLevel != "" && k.JLPTLevel == c.JLPTLevel {
+		score += weightJLPT
+	}
+	return score
+}
+
+// sharesReading reports whether two kanji have an on'yomi or kun'yomi in
+// common
+func sharesReading(k, c models.Kanji) bool {
+	for _, a := range answerReadings(k) {
+		for _, b := range answerReadings(c) {
+			if a.Kana == b.Kana {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// option returns what k contributes as an option in the given mode. skip,
+// if set, rejects options that are taken or would also be correct; the
+// next reading or meaning is tried instead.
+func option(mode Mode, k models.Kanji, skip func(string) bool) (string, bool) {
+	var opts []string
+	switch mode {
+	case ModeReading:
+		if r, ok := promptReading(k); ok {
+			opts = append(opts, displayReading(r))
+		}
+		for _, r := range answerReadings(k) {
+			opts = append(opts, displayReading(r))
+		}
+	case ModeMeaning:
+		opts = k.Meanings
+	case ModeKanji:
+		opts = []string{k.KanjiChar}
+	}
+
+	for _, o := range opts {
+		if skip == nil || !skip(o) {
+			return o, true
+		}
+	}
+	return "", false
+}
+
+// displayReading shows a reading in KANJIDIC notation, with on'yomi in
+// katakana as dictionaries print them
+func displayReading(r models.Reading) string {
+	if r.Type == models.ReadingOn {
+		return kana.ToKatakana(r.String())
+	}
+	return r.String()
+}
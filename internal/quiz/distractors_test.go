@@ -0,0 +1,116 @@
+package quiz
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+// testKanji builds a kanji with KANJIDIC-style readings
+func testKanji(id int, char, on, kun string, radical, strokes int, level string, meanings ...string) models.Kanji {
+	return models.Kanji{
+		KanjiCharID: id,
+		KanjiChar:   char,
+		Radical:     &radical,
+		StrokeCount: &strokes,
+		JLPTLevel:   level,
+		Meanings:    meanings,
+		Readings:    append(models.ParseReadings(on, models.ReadingOn), models.ParseReadings(kun, models.ReadingKun)...),
+	}
+}
+
+var (
+	rest = testKanji(1, "休", "キュウ", "やす.む", 9, 6, "n5", "rest", "day off")
+	pool = []models.Kanji{
+		rest,
+		testKanji(2, "体", "タイ, テイ", "からだ", 9, 7, "n5", "body"),
+		testKanji(3, "木", "ボク, モク", "き", 75, 4, "n5", "tree", "wood"),
+		testKanji(4, "急", "キュウ", "いそ.ぐ", 61, 9, "n3", "hurry"),
+		testKanji(5, "安", "アン", "やす.い", 40, 6, "n5", "cheap", "rest"),
+		testKanji(6, "何", "カ", "なに", 9, 7, "n5", "what"),
+		testKanji(7, "鬱", "ウツ", "", 192, 29, "n1", "gloom"),
+		testKanji(8, "位", "イ", "くらい", 9, 7, "n3", "rank"),
+		testKanji(9, "休", "キュウ", "", 1, 1, "", "a second 休"),
+	}
+)
+
+func TestChoices(t *testing.T) {
+	for _, mode := range Modes {
+		t.Run(string(mode), func(t *testing.T) {
+			q, ok := NewQuestion(mode, rest)
+			if !ok {
+				t.Fatal("NewQuestion failed")
+			}
+			answer, _ := option(mode, rest, nil)
+
+			for seed := range uint64(50) {
+				opts := Choices(q, pool, DefaultChoices, seed)
+				if len(opts) != DefaultChoices {
+					t.Fatalf("seed %d: %d options, want %d: %v", seed, len(opts), DefaultChoices, opts)
+				}
+				if !slices.Contains(opts, answer) {
+					t.Errorf("seed %d: answer %q missing from %v", seed, answer, opts)
+				}
+
+				correct := 0
+				for _, o := range opts {
+					if Grade(q, o).Correct {
+						correct++
+					}
+				}
+				if correct != 1 {
+					t.Errorf("seed %d: %d options grade as correct in %v", seed, correct, opts)
+				}
+
+				seen := make(map[string]bool)
+				for _, o := range opts {
+					if seen[o] {
+						t.Errorf("seed %d: %q offered twice in %v", seed, o, opts)
+					}
+					seen[o] = true
+				}
+
+				if again := Choices(q, pool, DefaultChoices, seed); !slices.Equal(opts, again) {
+					t.Errorf("seed %d: %v, then %v", seed, opts, again)
+				}
+			}
+		})
+	}
+}
+
+func TestChoicesVaryBySeed(t *testing.T) {
+	q, _ := NewQuestion(ModeKanji, rest)
+	first := Choices(q, pool, DefaultChoices, 0)
+	for seed := uint64(1); seed < 20; seed++ {
+		if !slices.Equal(Choices(q, pool, DefaultChoices, seed), first) {
+			return
+		}
+	}
+	t.Errorf("20 seeds all gave %v", first)
+}
+
+func TestChoicesSmallPool(t *testing.T) {
+	q, _ := NewQuestion(ModeMeaning, rest)
+	opts := Choices(q, pool[:3], DefaultChoices, 7)
+	if len(opts) != 3 {
+		t.Errorf("Choices from two other kanji = %v, want 3 options", opts)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		other models.Kanji
+		want  int
+	}{
+		{pool[1], weightRadical + weightStrokesNear + weightJLPT}, // 体
+		{pool[3], weightReading + weightStrokesSome},              // 急
+		{pool[4], weightStrokesNear + weightJLPT},                 // 安: やす.い is not やす.む
+		{pool[6], 0}, // 鬱
+	}
+	for _, tt := range tests {
+		if got := Similarity(rest, tt.other); got != tt.want {
+			t.Errorf("Similarity(休, %s) = %d, want %d", tt.other.KanjiChar, got, tt.want)
+		}
+	}
+}
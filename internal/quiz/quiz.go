@@ -0,0 +1,244 @@
+// Package quiz builds typed-answer questions from kanji and grades the
+// answers: kanji to reading, kanji to meaning, and reading to kanji.
+package quiz
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+// Mode is the kind of question asked
+type Mode string
+
+// Quiz modes
+const (
+	ModeReading Mode = "reading" // show the kanji, type a reading
+	ModeMeaning Mode = "meaning" // show the kanji, type a meaning
+	ModeKanji   Mode = "kanji"   // show a reading and meanings, type the kanji
+)
+
+// Modes lists every mode in the order they are offered
+var Modes = []Mode{ModeReading, ModeMeaning, ModeKanji}
+
+// Label returns the mode's name for display
+func (m Mode) Label() string {
+	switch m {
+	case ModeMeaning:
+		return "Kanji → meaning"
+	case ModeKanji:
+		return "Reading → kanji"
+	}
+	return "Kanji → reading"
+}
+
+// ParseMode parses a mode form value; "" means ModeReading
+func ParseMode(s string) (Mode, bool) {
+	switch Mode(s) {
+	case ModeReading, "":
+		return ModeReading, true
+	case ModeMeaning:
+		return ModeMeaning, true
+	case ModeKanji:
+		return ModeKanji, true
+	}
+	return ModeReading, false
+}
+
+// Question is one quiz question about a kanji
+type Question struct {
+	Mode   Mode
+	Kanji  models.Kanji
+	Prompt string   // the kanji, or for ModeKanji a reading in kana
+	Hints  []string // meanings shown with a reading prompt
+}
+
+// Result is a graded answer
+type Result struct {
+	Correct  bool
+	Answer   string   // the answer as understood: romaji is shown in kana
+	Expected []string // every accepted answer
+}
+
+// NewQuestion builds a question about k, or returns false if k lacks the
+// readings or meanings the mode needs. k must have Meanings and Readings
+// loaded. The question depends only on the mode and kanji, so it can be
+// rebuilt when the answer comes back.
+func NewQuestion(mode Mode, k models.Kanji) (Question, bool) {
+	q := Question{Mode: mode, Kanji: k, Prompt: k.KanjiChar}
+	switch mode {
+	case ModeReading:
+		return q, len(answerReadings(k)) > 0
+	case ModeMeaning:
+		return q, len(k.Meanings) > 0
+	case ModeKanji:
+		r, ok := promptReading(k)
+		if !ok || len(k.Meanings) == 0 {
+			return q, false
+		}
+		q.Prompt = r.Kana
+		if r.Type == models.ReadingOn {
+			q.Prompt = kana.ToKatakana(r.Kana)
+		}
+		q.Hints = k.Meanings
+		return q, true
+	}
+	return q, false
+}
+
+// Grade checks an answer. Readings may be typed in romaji (any system) or
+// kana and any on'yomi or kun'yomi is accepted, with or without its
+// okurigana. Meanings ignore case, articles, a leading "to" and one typo in
+// longer words.
+func Grade(q Question, answer string) Result {
+	answer = strings.TrimSpace(answer)
+	res := Result{Answer: answer}
+
+	switch q.Mode {
+	case ModeReading:
+		if strings.ContainsFunc(answer, func(r rune) bool { return r < 0x80 && unicode.IsLetter(r) }) {
+			if converted, ok := kana.FromRomaji(answer); ok {
+				res.Answer = converted
+			}
+		}
+		for _, r := range answerReadings(q.Kanji) {
+			res.Expected = append(res.Expected, displayReading(r))
+			if kana.SameReading(answer, r.Kana) || (r.Okurigana != "" && kana.SameReading(answer, r.Stem)) {
+				res.Correct = true
+			}
+		}
+	case ModeMeaning:
+		res.Expected = q.Kanji.Meanings
+		key := meaningKey(answer)
+		for _, m := range q.Kanji.Meanings {
+			if closeEnough(key, meaningKey(m)) {
+				res.Correct = true
+			}
+		}
+	case ModeKanji:
+		// The prompt spells out any okurigana, so accept it typed too (食べる)
+		res.Expected = []string{q.Kanji.KanjiChar}
+		res.Correct = answer == q.Kanji.KanjiChar
+		if r, ok := promptReading(q.Kanji); ok && r.Okurigana != "" {
+			res.Expected = append(res.Expected, q.Kanji.KanjiChar+r.Okurigana)
+			res.Correct = res.Correct || kana.ToHiragana(answer) == q.Kanji.KanjiChar+r.Okurigana
+		}
+	}
+	return res
+}
+
+// answerReadings returns the on'yomi and kun'yomi a reading answer may give
+func answerReadings(k models.Kanji) []models.Reading {
+	var out []models.Reading
+	for _, r := range k.Readings {
+		if r.Type != models.ReadingNanori {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// promptReading picks the reading to show for ModeKanji: the first common
+// one, preferring whole words over prefixes and suffixes
+func promptReading(k models.Kanji) (models.Reading, bool) {
+	var best models.Reading
+	bestScore := -1
+	for _, r := range answerReadings(k) {
+		score := 0
+		if r.IsCommon {
+			score += 2
+		}
+		if !r.IsPrefix && !r.IsSuffix {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// meaningKey normalizes a meaning for comparison: lower case, without
+// parenthesized notes, punctuation, articles or a leading "to"
+func meaningKey(s string) string {
+	s = strings.ToLower(s)
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth = max(0, depth-1)
+		case depth > 0:
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(b.String())
+	for len(words) > 1 {
+		switch words[0] {
+		case "to", "a", "an", "the":
+			words = words[1:]
+			continue
+		}
+		break
+	}
+	return strings.Join(words, " ")
+}
+
+// closeEnough reports whether answer matches want, allowing one typo (a
+// wrong, missing, extra or swapped letter) when the meaning is at least 5
+// letters long
+func closeEnough(answer, want string) bool {
+	if answer == "" || want == "" {
+		return false
+	}
+	if answer == want {
+		return true
+	}
+	a, b := []rune(answer), []rune(want)
+	if len(b) < 5 {
+		return false
+	}
+	return editDistance(a, b, 1) <= 1
+}
+
+// editDistance is the edit distance between a and b counting a swap of
+// adjacent letters as one edit, or limit+1 if it is more than limit
+func editDistance(a, b []rune, limit int) int {
+	if d := len(a) - len(b); d > limit || -d > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
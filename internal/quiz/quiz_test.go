@@ -0,0 +1,117 @@
+package quiz
+
+import (
+	"testing"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+// eat is 食 with KANJIDIC readings
+var eat = models.Kanji{
+	KanjiCharID: 1,
+	KanjiChar:   "食",
+	Meanings:    []string{"eat", "food"},
+	Readings: append(models.ParseReadings("ショク, ジキ", models.ReadingOn),
+		models.ParseReadings("く.う, く.らう, た.べる, -ぐい", models.ReadingKun)...),
+}
+
+func TestGradeReading(t *testing.T) {
+	q, ok := NewQuestion(ModeReading, eat)
+	if !ok {
+		t.Fatal("NewQuestion failed")
+	}
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		// Any on'yomi or kun'yomi, in romaji of any system or in kana
+		{"shoku", true},
+		{"syoku", true},
+		{"ショク", true},
+		{"しょく", true},
+		{"jiki", true},
+		{"ziki", true},
+		{"gui", true},
+
+		// Okurigana may be included or left off
+		{"taberu", true},
+		{"た.べる", true},
+		{"tabe", false},
+		{"ta", true},
+		{"kurau", true},
+		{"ku", true},
+
+		{"TABERU", true},
+		{"  taberu ", true},
+		{"tabero", false},
+		{"shokku", false},
+		{"eat", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Grade(q, tt.answer); got.Correct != tt.want {
+			t.Errorf("Grade(%q).Correct = %v, want %v", tt.answer, got.Correct, tt.want)
+		}
+	}
+
+	if got := Grade(q, "taberu").Answer; got != "たべる" {
+		t.Errorf("Answer = %q, want romaji shown as kana", got)
+	}
+}
+
+func TestGradeMeaning(t *testing.T) {
+	k := models.Kanji{KanjiCharID: 2, KanjiChar: "受", Meanings: []string{"accept", "undergo", "receive (an award)", "cat"}}
+	q, _ := NewQuestion(ModeMeaning, k)
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{"accept", true},
+		{"Accept", true},
+		{"to accept", true},
+		{"receive", true},
+		{"to receive!", true},
+		{"the cat", true},
+
+		// One typo in words of at least 5 letters
+		{"recieve", true},
+		{"acept", true},
+		{"undergoo", true},
+		{"recievd", false},
+		{"car", false},
+
+		{"to", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Grade(q, tt.answer); got.Correct != tt.want {
+			t.Errorf("Grade(%q).Correct = %v, want %v", tt.answer, got.Correct, tt.want)
+		}
+	}
+}
+
+func TestGradeKanji(t *testing.T) {
+	k := eat
+	k.Readings = append([]models.Reading(nil), eat.Readings...)
+	for i := range k.Readings {
+		k.Readings[i].IsCommon = k.Readings[i].Kana == "たべる"
+	}
+	q, ok := NewQuestion(ModeKanji, k)
+	if !ok || q.Prompt != "たべる" {
+		t.Fatalf("NewQuestion = %+v, %v; want the common reading as prompt", q, ok)
+	}
+	for answer, want := range map[string]bool{"食": true, "食べる": true, "食ベル": true, "飲": false, "たべる": false} {
+		if got := Grade(q, answer); got.Correct != want {
+			t.Errorf("Grade(%q).Correct = %v, want %v", answer, got.Correct, want)
+		}
+	}
+}
+
+func TestNewQuestionNeedsData(t *testing.T) {
+	bare := models.Kanji{KanjiCharID: 3, KanjiChar: "丶"}
+	for _, mode := range Modes {
+		if _, ok := NewQuestion(mode, bare); ok {
+			t.Errorf("NewQuestion(%s) ok for a kanji without readings or meanings", mode)
+		}
+	}
+}
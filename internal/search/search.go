@@ -0,0 +1,280 @@
+// Package search finds kanji by character, reading (kana or romaji) or
+// English meaning using an in-memory index of kanji_go.kanji.
+package search
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/UreshiiPanda/kanji_go/internal/kana"
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+// Match kinds reported in Result.Match
+const (
+	MatchCharacter = "character"
+	MatchReading   = "reading"
+	MatchMeaning   = "meaning"
+)
+
+// Scores for each kind of match; higher ranks first
+const (
+	scoreCharacter     = 1000
+	scoreReadingExact  = 900
+	scoreMeaningExact  = 850
+	scoreReadingFolded = 800
+	scoreReadingStem   = 700
+	scoreMeaningWord   = 650
+	scoreReadingPrefix = 500
+	scoreMeaningPrefix = 450
+	scoreFoldedPrefix  = 400
+	scoreMeaningSubstr = 200
+)
+
+// Result is one ranked search hit
+type Result struct {
+	Kanji models.Kanji `json:"kanji"`
+	Score int          `json:"score"`
+	Match string       `json:"match"`
+}
+
+// entry is a kanji with its precomputed match keys
+type entry struct {
+	kanji    models.Kanji
+	readings []string // normalized hiragana, okurigana marks removed
+	folded   []string // readings with long vowels folded
+	stems    []string // kun'yomi stems before the okurigana
+	meanings []string // lower case
+}
+
+// Index is a concurrency-safe kanji search index
+type Index struct {
+	mu      sync.RWMutex
+	entries []entry
+}
+
+// NewIndex returns an empty index; call Reload to fill it
+func NewIndex() *Index {
+	return &Index{}
+}
+
+// Reload rebuilds the index from the database
+func (ix *Index) Reload(db *sql.DB) error {
+	list, err := models.ListAllKanji(db)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]entry, 0, len(list))
+	for _, k := range list {
+		entries = append(entries, newEntry(k))
+	}
+
+	ix.mu.Lock()
+	ix.entries = entries
+	ix.mu.Unlock()
+	return nil
+}
+
+// StartRefresh reloads the index every interval until ctx is cancelled, so
+// kanji added by the importer show up without a restart
+func (ix *Index) StartRefresh(ctx context.Context, db *sql.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := ix.Reload(db); err != nil {
+					log.Printf("Error reloading search index: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Len returns the number of indexed kanji
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.entries)
+}
+
+// newEntry precomputes the match keys for a kanji
+func newEntry(k models.Kanji) entry {
+	e := entry{kanji: k}
+
+	for _, r := range k.Readings {
+		full := kana.Normalize(r.Kana)
+		if full == "" {
+			continue
+		}
+		e.readings = append(e.readings, full)
+		e.folded = append(e.folded, kana.FoldLongVowels(full))
+		if r.Okurigana != "" {
+			e.stems = append(e.stems, kana.Normalize(r.Stem))
+		}
+	}
+
+	for _, m := range k.Meanings {
+		e.meanings = append(e.meanings, strings.ToLower(m))
+	}
+	return e
+}
+
+// Search returns up to limit kanji matching query, best first
+func (ix *Index) Search(query string, limit int) []Result {
+	query = strings.TrimSpace(query)
+	if query == "" || limit <= 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	best := make(map[int]Result)
+	consider := func(e *entry, score int, match string) {
+		if r, ok := best[e.kanji.KanjiCharID]; !ok || score > r.Score {
+			best[e.kanji.KanjiCharID] = Result{Kanji: e.kanji, Score: score, Match: match}
+		}
+	}
+
+	switch {
+	case strings.ContainsFunc(query, kana.IsKanji):
+		// Literal kanji, kept in the order they were typed
+		var chars []rune
+		for _, r := range query {
+			if kana.IsKanji(r) {
+				chars = append(chars, r)
+			}
+		}
+		for i := range ix.entries {
+			e := &ix.entries[i]
+			for pos, r := range chars {
+				if e.kanji.KanjiChar == string(r) {
+					consider(e, scoreCharacter-pos, MatchCharacter)
+				}
+			}
+		}
+
+	case isKanaQuery(query):
+		ix.matchReadings(kana.Normalize(query), consider)
+
+	default:
+		ix.matchMeanings(strings.ToLower(query), consider)
+		if reading, ok := kana.FromRomaji(query); ok {
+			ix.matchReadings(kana.Normalize(reading), consider)
+		}
+	}
+
+	results := make([]Result, 0, len(best))
+	for _, r := range best {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool { return rankBefore(results[i], results[j]) })
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// matchReadings scores every entry against a normalized kana query
+func (ix *Index) matchReadings(q string, consider func(*entry, int, string)) {
+	folded := kana.FoldLongVowels(q)
+	for i := range ix.entries {
+		e := &ix.entries[i]
+		for j, reading := range e.readings {
+			switch {
+			case reading == q:
+				consider(e, scoreReadingExact, MatchReading)
+			case e.folded[j] == folded:
+				consider(e, scoreReadingFolded, MatchReading)
+			case strings.HasPrefix(reading, q):
+				consider(e, scoreReadingPrefix, MatchReading)
+			case strings.HasPrefix(e.folded[j], folded):
+				consider(e, scoreFoldedPrefix, MatchReading)
+			}
+		}
+		for _, stem := range e.stems {
+			if stem == q {
+				consider(e, scoreReadingStem, MatchReading)
+			}
+		}
+	}
+}
+
+// matchMeanings scores every entry against a lower-case English query
+func (ix *Index) matchMeanings(q string, consider func(*entry, int, string)) {
+	for i := range ix.entries {
+		e := &ix.entries[i]
+		for _, m := range e.meanings {
+			switch {
+			case m == q:
+				consider(e, scoreMeaningExact, MatchMeaning)
+			case containsWord(m, q):
+				consider(e, scoreMeaningWord, MatchMeaning)
+			case strings.HasPrefix(m, q):
+				consider(e, scoreMeaningPrefix, MatchMeaning)
+			case len(q) >= 3 && strings.Contains(m, q):
+				consider(e, scoreMeaningSubstr, MatchMeaning)
+			}
+		}
+	}
+}
+
+// containsWord reports whether q appears in s as whole words
+func containsWord(s, q string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], q)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(q)
+		if (start == 0 || !isWordByte(s[start-1])) && (end == len(s) || !isWordByte(s[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// isWordByte reports whether c is part of an English word
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '\''
+}
+
+// isKanaQuery reports whether q is written entirely in kana
+func isKanaQuery(q string) bool {
+	for _, r := range q {
+		if !kana.IsKana(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// rankBefore orders results by score, then by how common the kanji is
+func rankBefore(a, b Result) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	fa, fb := a.Kanji.Frequency, b.Kanji.Frequency
+	switch {
+	case fa != nil && fb != nil && *fa != *fb:
+		return *fa < *fb
+	case fa != nil && fb == nil:
+		return true
+	case fa == nil && fb != nil:
+		return false
+	}
+	return a.Kanji.KanjiCharID < b.Kanji.KanjiCharID
+}
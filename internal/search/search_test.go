@@ -0,0 +1,106 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+// testIndex builds an index from KANJIDIC-style fixtures
+func testIndex() *Index {
+	type fixture struct {
+		char     string
+		on, kun  string
+		freq     int // 0 for none
+		meanings []string
+	}
+	fixtures := []fixture{
+		{"日", "にち, じつ", "ひ, -び, -か", 1, []string{"day", "sun", "Japan"}},
+		{"食", "しょく, じき", "く.う, た.べる", 328, []string{"eat", "food"}},
+		{"東", "とう", "ひがし", 37, []string{"east"}},
+		{"京", "きょう, けい", "みやこ", 74, []string{"capital"}},
+		{"天", "てん", "あまつ, あめ", 0, []string{"heavens", "sky"}},
+		{"十", "じゅう, じっ", "とお, と", 0, []string{"ten"}},
+		{"点", "てん", "つ.ける", 500, []string{"spot", "point", "mark"}},
+	}
+
+	ix := NewIndex()
+	for i, f := range fixtures {
+		k := models.Kanji{KanjiCharID: i + 1, KanjiChar: f.char, Meanings: f.meanings}
+		if f.freq != 0 {
+			k.Frequency = &f.freq
+		}
+		k.Readings = append(models.ParseReadings(f.on, "on"), models.ParseReadings(f.kun, "kun")...)
+		ix.entries = append(ix.entries, newEntry(k))
+	}
+	return ix
+}
+
+func TestSearchRanking(t *testing.T) {
+	ix := testIndex()
+
+	tests := []struct {
+		query string
+		want  []string // kanji in rank order
+		match []string // Match of each result
+	}{
+		// Literal kanji keep the order they were typed; unknown ones are skipped
+		{"京東", []string{"京", "東"}, []string{MatchCharacter, MatchCharacter}},
+		{"日本", []string{"日"}, []string{MatchCharacter}},
+
+		// Readings outrank meanings; equal scores fall back to frequency
+		{"ten", []string{"点", "天", "十"}, []string{MatchReading, MatchReading, MatchMeaning}},
+		{"テン", []string{"点", "天"}, []string{MatchReading, MatchReading}},
+
+		// Okurigana, stems and long vowels
+		{"taberu", []string{"食"}, []string{MatchReading}},
+		{"たべる", []string{"食"}, []string{MatchReading}},
+		{"tabe", []string{"食"}, []string{MatchReading}},
+		{"kyo", []string{"京"}, []string{MatchReading}},
+		{"toukyou", nil, nil},
+
+		// Meanings: exact, whole word, prefix
+		{"sun", []string{"日"}, []string{MatchMeaning}},
+		{"Japan", []string{"日"}, []string{MatchMeaning}},
+		{"heaven", []string{"天"}, []string{MatchMeaning}},
+		{"ea", []string{"東", "食"}, []string{MatchMeaning, MatchMeaning}},
+
+		{"", nil, nil},
+		{"zzz", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results := ix.Search(tt.query, 10)
+			var got, match []string
+			for _, r := range results {
+				got = append(got, r.Kanji.KanjiChar)
+				match = append(match, r.Match)
+			}
+			if !slices.Equal(got, tt.want) || !slices.Equal(match, tt.match) {
+				t.Errorf("Search(%q) = %v %v, want %v %v", tt.query, got, match, tt.want, tt.match)
+			}
+		})
+	}
+}
+
+func TestSearchScoreOrder(t *testing.T) {
+	// An exact kanji beats an exact reading, which beats an exact meaning
+	ix := testIndex()
+	char := ix.Search("十", 1)[0].Score
+	reading := ix.Search("juu", 1)[0].Score
+	meaning := ix.Search("ten", 3)[2].Score
+	if !(char > reading && reading > meaning) {
+		t.Errorf("scores character %d, reading %d, meaning %d; want descending", char, reading, meaning)
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	ix := testIndex()
+	if got := ix.Search("ten", 2); len(got) != 2 {
+		t.Errorf("Search with limit 2 returned %d results", len(got))
+	}
+	if got := ix.Search("ten", 0); got != nil {
+		t.Errorf("Search with limit 0 returned %v", got)
+	}
+}
@@ -0,0 +1,204 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+// CookieName is the name of the cookie holding the session ID
+const CookieName = "kanji_go_session"
+
+const (
+	// defaultTTL is how long a session survives without being used
+	defaultTTL = 30 * 24 * time.Hour
+	// touchInterval limits how often an unchanged session is written back
+	touchInterval = 5 * time.Minute
+)
+
+type contextKey struct{}
+
+// state is what the middleware stores in the request context. The session
+// pointer may be replaced by Renew; original is used to detect changes.
+type state struct {
+	session  *models.Session
+	original models.Session
+}
+
+// Manager issues session cookies and loads/saves sessions in Postgres
+type Manager struct {
+	db     *sql.DB
+	ttl    time.Duration
+	secure bool
+}
+
+// NewManager creates a session manager. secure controls the cookie's
+// Secure flag and should be true in production.
+func NewManager(db *sql.DB, secure bool) *Manager {
+	return &Manager{
+		db:     db,
+		ttl:    defaultTTL,
+		secure: secure,
+	}
+}
+
+// Middleware loads the session for the request (creating one if needed),
+// exposes it via the request context and saves any changes afterwards
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Static assets don't need a session
+		if strings.HasPrefix(r.URL.Path, "/static/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sess := m.load(r)
+		if sess == nil {
+			var err error
+			sess, err = models.CreateSession(m.db, nil)
+			if err != nil {
+				log.Printf("Error creating session: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			m.setCookie(w, sess.SessionID)
+		} else if time.Since(sess.UpdatedAt) > touchInterval {
+			// Slide the cookie expiry along with the row
+			m.setCookie(w, sess.SessionID)
+		}
+
+		st := &state{session: sess, original: *sess}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, st)))
+
+		// Persist changes made by the handler, or keep the session alive
+		if *st.session != st.original {
+			if err := models.UpdateSession(m.db, st.session); err != nil {
+				log.Printf("Error saving session: %v", err)
+			}
+		} else if time.Since(st.session.UpdatedAt) > touchInterval {
+			if err := models.TouchSession(m.db, st.session.SessionID); err != nil {
+				log.Printf("Error touching session: %v", err)
+			}
+		}
+	})
+}
+
+// load returns the request's unexpired session, or nil
+func (m *Manager) load(r *http.Request) *models.Session {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+
+	sess, err := models.GetSession(m.db, cookie.Value)
+	if err != nil {
+		if !errors.Is(err, models.ErrSessionNotFound) {
+			log.Printf("Error loading session: %v", err)
+		}
+		return nil
+	}
+
+	if time.Since(sess.UpdatedAt) > m.ttl {
+		if err := models.DeleteSession(m.db, sess.SessionID); err != nil {
+			log.Printf("Error deleting expired session: %v", err)
+		}
+		return nil
+	}
+
+	return sess
+}
+
+// Renew replaces the current session with a fresh ID for the given user
+// (nil to log out), carrying the UI preferences over. Call it on every
+// login and logout so a session ID is never reused across identities.
+func (m *Manager) Renew(w http.ResponseWriter, r *http.Request, username *string) (*models.Session, error) {
+	st, _ := r.Context().Value(contextKey{}).(*state)
+
+	fresh, err := models.CreateSession(m.db, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if st != nil {
+		old := st.session
+		if err := models.DeleteSession(m.db, old.SessionID); err != nil {
+			log.Printf("Error deleting old session: %v", err)
+		}
+
+		fresh.CurrentJLPTLevel = old.CurrentJLPTLevel
+		fresh.CurrentPage = old.CurrentPage
+		fresh.LeftSidebarActive = old.LeftSidebarActive
+		fresh.DarkModeActive = old.DarkModeActive
+		if err := models.UpdateSession(m.db, fresh); err != nil {
+			return nil, err
+		}
+
+		st.session = fresh
+		st.original = *fresh
+	}
+
+	m.setCookie(w, fresh.SessionID)
+	return fresh, nil
+}
+
+// StartGC deletes expired sessions every interval until ctx is cancelled
+func (m *Manager) StartGC(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n, err := models.DeleteExpiredSessions(m.db, time.Now().Add(-m.ttl))
+				if err != nil {
+					log.Printf("Error garbage-collecting sessions: %v", err)
+					continue
+				}
+				if n > 0 {
+					log.Printf("Garbage-collected %d expired sessions", n)
+				}
+			}
+		}
+	}()
+}
+
+// setCookie writes the session cookie
+func (m *Manager) setCookie(w http.ResponseWriter, id string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(m.ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// FromContext returns the current request's session, or nil if the
+// session middleware did not run
+func FromContext(ctx context.Context) *models.Session {
+	st, ok := ctx.Value(contextKey{}).(*state)
+	if !ok {
+		return nil
+	}
+	return st.session
+}
+
+// CurrentUser returns the logged-in username, or "" for anonymous requests
+func CurrentUser(ctx context.Context) string {
+	sess := FromContext(ctx)
+	if sess == nil || sess.CurrentUser == nil {
+		return ""
+	}
+	return *sess.CurrentUser
+}
@@ -0,0 +1,145 @@
+// Package srs schedules kanji reviews with a variant of the SM-2 algorithm
+// using Anki-style Again/Hard/Good/Easy grades.
+package srs
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+// Grade is the learner's self-assessment of a review
+type Grade int
+
+const (
+	Again Grade = iota + 1
+	Hard
+	Good
+	Easy
+)
+
+// Card states
+const (
+	StateLearning   = "learning"
+	StateReview     = "review"
+	StateRelearning = "relearning"
+)
+
+const (
+	initialEase = 2.5
+	minEase     = 1.3
+
+	// relearnDelay is how soon a forgotten card comes back
+	relearnDelay = 10 * time.Minute
+
+	// maxIntervalDays caps how far out a card can be scheduled
+	maxIntervalDays = 3650
+
+	// NewCardsPerDay is how many unseen kanji a learner is given each day
+	NewCardsPerDay = 20
+)
+
+// ParseGrade converts a form value ("1".."4") into a Grade
+func ParseGrade(s string) (Grade, error) {
+	switch s {
+	case "1":
+		return Again, nil
+	case "2":
+		return Hard, nil
+	case "3":
+		return Good, nil
+	case "4":
+		return Easy, nil
+	}
+	return 0, fmt.Errorf("invalid grade %q", s)
+}
+
+// NewCard returns the initial state for a kanji the user has never reviewed
+func NewCard(userID, kanjiCharID int, now time.Time) models.SRSCard {
+	return models.SRSCard{
+		UserID:      userID,
+		KanjiCharID: kanjiCharID,
+		State:       StateLearning,
+		EaseFactor:  initialEase,
+		DueAt:       now,
+	}
+}
+
+// Schedule returns the card's new state after being graded at time now
+func Schedule(card models.SRSCard, grade Grade, now time.Time) models.SRSCard {
+	next := card
+	next.LastReviewedAt = &now
+
+	if grade == Again {
+		// Forgotten: start over, show again shortly and make it harder
+		if card.State == StateReview {
+			next.Lapses++
+			next.State = StateRelearning
+		}
+		next.Repetitions = 0
+		next.IntervalDays = 0
+		next.EaseFactor = math.Max(minEase, card.EaseFactor-0.20)
+		next.DueAt = now.Add(relearnDelay)
+		return next
+	}
+
+	var interval float64
+	switch {
+	case card.Repetitions == 0:
+		interval = map[Grade]float64{Hard: 1, Good: 1, Easy: 4}[grade]
+	case card.Repetitions == 1 && grade != Hard:
+		interval = map[Grade]float64{Good: 6, Easy: 6 * 1.3}[grade]
+	default:
+		prev := math.Max(1, float64(card.IntervalDays))
+		switch grade {
+		case Hard:
+			interval = prev * 1.2
+		case Good:
+			interval = prev * card.EaseFactor
+		case Easy:
+			interval = prev * card.EaseFactor * 1.3
+		}
+	}
+
+	switch grade {
+	case Hard:
+		next.EaseFactor = math.Max(minEase, card.EaseFactor-0.15)
+	case Easy:
+		next.EaseFactor = card.EaseFactor + 0.15
+	}
+
+	next.IntervalDays = min(maxIntervalDays, max(1, int(math.Round(interval))))
+	next.Repetitions = card.Repetitions + 1
+	next.State = StateReview
+	next.DueAt = now.AddDate(0, 0, next.IntervalDays)
+
+	return next
+}
+
+// maxEase caps the ease of imported cards; Easy grades can raise it past
+// this, but an import shouldn't start a card there
+const maxEase = 5.0
+
+// Sanitize fixes up a card from outside the scheduler (an import) so its
+// fields are in range. It returns false for an unknown state.
+func Sanitize(card models.SRSCard) (models.SRSCard, bool) {
+	switch card.State {
+	case StateLearning, StateReview, StateRelearning:
+	default:
+		return card, false
+	}
+
+	if card.EaseFactor == 0 {
+		card.EaseFactor = initialEase
+	}
+	card.EaseFactor = math.Min(maxEase, math.Max(minEase, card.EaseFactor))
+	card.IntervalDays = min(maxIntervalDays, max(0, card.IntervalDays))
+	card.Repetitions = max(0, card.Repetitions)
+	card.Lapses = max(0, card.Lapses)
+	if card.DueAt.IsZero() {
+		card.DueAt = time.Now()
+	}
+	return card, true
+}
@@ -0,0 +1,193 @@
+package srs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/UreshiiPanda/kanji_go/internal/models"
+)
+
+var now = time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+
+func TestParseGrade(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Grade
+		ok   bool
+	}{
+		{"1", Again, true},
+		{"2", Hard, true},
+		{"3", Good, true},
+		{"4", Easy, true},
+		{"0", 0, false},
+		{"5", 0, false},
+		{"", 0, false},
+		{"good", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseGrade(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseGrade(%q) = %v, %v; want %v, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestScheduleIntervals(t *testing.T) {
+	tests := []struct {
+		name     string
+		card     models.SRSCard
+		grade    Grade
+		interval int
+		ease     float64
+	}{
+		{"new good", NewCard(1, 1, now), Good, 1, 2.5},
+		{"new hard", NewCard(1, 1, now), Hard, 1, 2.35},
+		{"new easy", NewCard(1, 1, now), Easy, 4, 2.65},
+		{"second good", reviewCard(1, 1, 2.5), Good, 6, 2.5},
+		{"second easy", reviewCard(1, 1, 2.5), Easy, 8, 2.65},
+		{"second hard", reviewCard(1, 1, 2.5), Hard, 1, 2.35},
+		{"mature good", reviewCard(3, 10, 2.5), Good, 25, 2.5},
+		{"mature hard", reviewCard(3, 10, 2.5), Hard, 12, 2.35},
+		{"mature easy", reviewCard(3, 10, 2.5), Easy, 33, 2.65},
+		{"interval cap", reviewCard(20, 3000, 2.5), Good, maxIntervalDays, 2.5},
+		{"ease floor on hard", reviewCard(3, 10, minEase), Hard, 12, minEase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Schedule(tt.card, tt.grade, now)
+			if got.IntervalDays != tt.interval {
+				t.Errorf("IntervalDays = %d, want %d", got.IntervalDays, tt.interval)
+			}
+			if !almostEqual(got.EaseFactor, tt.ease) {
+				t.Errorf("EaseFactor = %v, want %v", got.EaseFactor, tt.ease)
+			}
+			if got.State != StateReview {
+				t.Errorf("State = %q, want %q", got.State, StateReview)
+			}
+			if got.Repetitions != tt.card.Repetitions+1 {
+				t.Errorf("Repetitions = %d, want %d", got.Repetitions, tt.card.Repetitions+1)
+			}
+			if want := now.AddDate(0, 0, tt.interval); !got.DueAt.Equal(want) {
+				t.Errorf("DueAt = %v, want %v", got.DueAt, want)
+			}
+			if got.LastReviewedAt == nil || !got.LastReviewedAt.Equal(now) {
+				t.Errorf("LastReviewedAt = %v, want %v", got.LastReviewedAt, now)
+			}
+		})
+	}
+}
+
+func TestScheduleLapse(t *testing.T) {
+	card := reviewCard(4, 30, 2.5)
+	card.Lapses = 2
+
+	got := Schedule(card, Again, now)
+	if got.State != StateRelearning || got.Lapses != 3 {
+		t.Errorf("State, Lapses = %q, %d; want %q, 3", got.State, got.Lapses, StateRelearning)
+	}
+	if got.Repetitions != 0 || got.IntervalDays != 0 {
+		t.Errorf("Repetitions, IntervalDays = %d, %d; want 0, 0", got.Repetitions, got.IntervalDays)
+	}
+	if !almostEqual(got.EaseFactor, 2.3) {
+		t.Errorf("EaseFactor = %v, want 2.3", got.EaseFactor)
+	}
+	if want := now.Add(relearnDelay); !got.DueAt.Equal(want) {
+		t.Errorf("DueAt = %v, want %v", got.DueAt, want)
+	}
+
+	// Failing again while relearning is not another lapse
+	again := Schedule(got, Again, now.Add(relearnDelay))
+	if again.State != StateRelearning || again.Lapses != 3 {
+		t.Errorf("relearning Again: State, Lapses = %q, %d; want %q, 3", again.State, again.Lapses, StateRelearning)
+	}
+
+	// Nor is failing a card that is still being learned
+	learning := Schedule(NewCard(1, 1, now), Again, now)
+	if learning.State != StateLearning || learning.Lapses != 0 {
+		t.Errorf("learning Again: State, Lapses = %q, %d; want %q, 0", learning.State, learning.Lapses, StateLearning)
+	}
+}
+
+func TestScheduleEaseFloor(t *testing.T) {
+	card := reviewCard(3, 10, 2.5)
+	for range 10 {
+		card = Schedule(card, Again, now)
+	}
+	if card.EaseFactor != minEase {
+		t.Errorf("EaseFactor = %v after repeated lapses, want %v", card.EaseFactor, minEase)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	due := now.AddDate(0, 0, 3)
+	tests := []struct {
+		name string
+		in   models.SRSCard
+		want models.SRSCard
+		ok   bool
+	}{
+		{
+			name: "in range",
+			in:   models.SRSCard{State: StateReview, EaseFactor: 2.1, IntervalDays: 12, Repetitions: 3, Lapses: 1, DueAt: due},
+			want: models.SRSCard{State: StateReview, EaseFactor: 2.1, IntervalDays: 12, Repetitions: 3, Lapses: 1, DueAt: due},
+			ok:   true,
+		},
+		{
+			name: "clamped",
+			in:   models.SRSCard{State: StateLearning, EaseFactor: 9, IntervalDays: 99999, Repetitions: -2, Lapses: -1, DueAt: due},
+			want: models.SRSCard{State: StateLearning, EaseFactor: maxEase, IntervalDays: maxIntervalDays, DueAt: due},
+			ok:   true,
+		},
+		{
+			name: "ease floor and negative interval",
+			in:   models.SRSCard{State: StateRelearning, EaseFactor: 0.5, IntervalDays: -4, DueAt: due},
+			want: models.SRSCard{State: StateRelearning, EaseFactor: minEase, DueAt: due},
+			ok:   true,
+		},
+		{
+			name: "missing ease",
+			in:   models.SRSCard{State: StateReview, DueAt: due},
+			want: models.SRSCard{State: StateReview, EaseFactor: initialEase, DueAt: due},
+			ok:   true,
+		},
+		{
+			name: "unknown state",
+			in:   models.SRSCard{State: "suspended"},
+			ok:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Sanitize(tt.in)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && got != tt.want {
+				t.Errorf("Sanitize = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	got, _ := Sanitize(models.SRSCard{State: StateLearning})
+	if got.DueAt.IsZero() {
+		t.Error("Sanitize left DueAt zero")
+	}
+}
+
+// reviewCard returns a card in review after reps successful reviews
+func reviewCard(reps, interval int, ease float64) models.SRSCard {
+	return models.SRSCard{
+		UserID:       1,
+		KanjiCharID:  1,
+		State:        StateReview,
+		EaseFactor:   ease,
+		IntervalDays: interval,
+		Repetitions:  reps,
+		DueAt:        now,
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	const eps = 1e-9
+	return a-b < eps && b-a < eps
+}
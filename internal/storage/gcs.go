@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStore stores objects in a Google Cloud Storage bucket
+type GCSStore struct {
+	client *gcs.Client
+	bucket string
+}
+
+// NewGCSStore creates a GCS-backed store. In production this uses the
+// service account credentials; locally it uses gcloud credentials.
+func NewGCSStore(ctx context.Context, bucket string) (*GCSStore, error) {
+	client, err := gcs.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	return &GCSStore{client: client, bucket: bucket}, nil
+}
+
+// Put uploads an object to the bucket
+func (s *GCSStore) Put(ctx context.Context, key string, r io.Reader, contentType string) (*Object, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	if contentType == "" {
+		contentType = contentTypeFor(key)
+	}
+
+	wc := s.client.Bucket(s.bucket).Object(key).NewWriter(ctx)
+	wc.ContentType = contentType
+
+	if _, err := io.Copy(wc, r); err != nil {
+		wc.Close()
+		return nil, fmt.Errorf("failed to write object: %w", err)
+	}
+	// Close finalizes the upload
+	if err := wc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize object: %w", err)
+	}
+
+	attrs := wc.Attrs()
+	return &Object{Key: key, Size: attrs.Size, ContentType: attrs.ContentType, Created: attrs.Created}, nil
+}
+
+// Get opens an object in the bucket
+func (s *GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.client.Bucket(s.bucket).Object(key).NewReader(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open object: %w", err)
+	}
+
+	obj := &Object{
+		Key:         key,
+		Size:        reader.Attrs.Size,
+		ContentType: reader.Attrs.ContentType,
+		Created:     reader.Attrs.LastModified,
+	}
+	return reader, obj, nil
+}
+
+// Delete removes an object from the bucket
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	err := s.client.Bucket(s.bucket).Object(key).Delete(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+// List returns one page of objects under a prefix
+func (s *GCSStore) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	it := s.client.Bucket(s.bucket).Objects(ctx, &gcs.Query{Prefix: opts.Prefix})
+	pager := iterator.NewPager(it, limit, opts.PageToken)
+
+	var attrs []*gcs.ObjectAttrs
+	token, err := pager.NextPage(&attrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	page := &ListPage{NextPageToken: token}
+	for _, a := range attrs {
+		page.Objects = append(page.Objects, Object{
+			Key:         a.Name,
+			Size:        a.Size,
+			ContentType: a.ContentType,
+			Created:     a.Created,
+		})
+	}
+	return page, nil
+}
+
+// PublicURL returns the object's public storage.googleapis.com URL
+func (s *GCSStore) PublicURL(key string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", s.bucket, key)
+}
+
+// Close closes the storage client
+func (s *GCSStore) Close() error {
+	return s.client.Close()
+}
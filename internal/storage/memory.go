@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps objects in memory. Contents are lost on restart, so it
+// is only meant for development and tests.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	objects   map[string]memoryObject
+	urlPrefix string
+}
+
+type memoryObject struct {
+	info Object
+	data []byte
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore(urlPrefix string) *MemoryStore {
+	return &MemoryStore{objects: map[string]memoryObject{}, urlPrefix: urlPrefix}
+}
+
+// Put stores a copy of the object's contents
+func (s *MemoryStore) Put(ctx context.Context, key string, r io.Reader, contentType string) (*Object, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	if contentType == "" {
+		contentType = contentTypeFor(key)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	info := Object{Key: key, Size: int64(len(data)), ContentType: contentType, Created: time.Now()}
+
+	s.mu.Lock()
+	s.objects[key] = memoryObject{info: info, data: data}
+	s.mu.Unlock()
+
+	return &info, nil
+}
+
+// Get returns a reader over the stored contents
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	s.mu.RLock()
+	obj, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+
+	info := obj.info
+	return io.NopCloser(bytes.NewReader(obj.data)), &info, nil
+}
+
+// Delete removes an object
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.objects[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.objects, key)
+	return nil
+}
+
+// List returns one page of objects under a prefix
+func (s *MemoryStore) List(ctx context.Context, opts ListOptions) (*ListPage, error) {
+	s.mu.RLock()
+	var objects []Object
+	for key, obj := range s.objects {
+		if strings.HasPrefix(key, opts.Prefix) {
+			objects = append(objects, obj.info)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return paginate(objects, opts), nil
+}
+
+// PublicURL returns the app URL the object is served from
+func (s *MemoryStore) PublicURL(key string) string {
+	return s.urlPrefix + key
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,143 @@
+// Package storage abstracts the object store used for uploaded files so the
+// app can run against Google Cloud Storage, a local directory or memory.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ErrInvalidKey is returned for keys that are empty or escape the store
+var ErrInvalidKey = errors.New("invalid object key")
+
+// Object describes a stored object
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	Created     time.Time
+}
+
+// ListOptions controls a List call
+type ListOptions struct {
+	Prefix    string
+	PageToken string // From a previous ListPage; empty for the first page
+	Limit     int    // Objects per page; defaults to DefaultPageSize
+}
+
+// ListPage is one page of List results
+type ListPage struct {
+	Objects       []Object
+	NextPageToken string // Empty when there are no more pages
+}
+
+// DefaultPageSize is used when ListOptions.Limit is not set
+const DefaultPageSize = 100
+
+// Store is an object store
+type Store interface {
+	// Put writes an object, replacing any existing one with the same key
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (*Object, error)
+	// Get opens an object for reading. The caller must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Delete removes an object. Deleting a missing object returns ErrNotFound.
+	Delete(ctx context.Context, key string) error
+	// List returns objects whose keys start with a prefix, in key order
+	List(ctx context.Context, opts ListOptions) (*ListPage, error)
+	// PublicURL returns the URL a browser can load the object from
+	PublicURL(key string) string
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// Config selects and configures a Store backend
+type Config struct {
+	Backend    string // "gcs", "local" or "memory"
+	BucketName string // gcs
+	Dir        string // local
+	URLPrefix  string // local and memory: where ServeFileHandler is mounted
+}
+
+// New creates the Store selected by cfg.Backend
+func New(ctx context.Context, cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "gcs":
+		return NewGCSStore(ctx, cfg.BucketName)
+	case "local":
+		return NewLocalStore(cfg.Dir, cfg.URLPrefix)
+	case "memory":
+		return NewMemoryStore(cfg.URLPrefix), nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
+
+// ValidateKey rejects empty keys and keys that could escape a prefix
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	if path.Clean(key) != key {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." || part == "." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// contentTypeFor guesses a content type from the key's extension
+func contentTypeFor(key string) string {
+	if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// paginate applies ListOptions to a sorted slice of objects. The page token
+// is the key of the last object on the previous page.
+func paginate(objects []Object, opts ListOptions) *ListPage {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	start := 0
+	if opts.PageToken != "" {
+		for start < len(objects) && objects[start].Key <= opts.PageToken {
+			start++
+		}
+	}
+
+	page := &ListPage{}
+	end := min(start+limit, len(objects))
+	page.Objects = objects[start:end]
+	if end < len(objects) {
+		page.NextPageToken = objects[end-1].Key
+	}
+	return page
+}
+
+// KeyFromURL returns the object key for a URL produced by s.PublicURL,
+// or false if the URL doesn't point into the store
+func KeyFromURL(s Store, url string) (string, bool) {
+	prefix := s.PublicURL("")
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	key := strings.TrimPrefix(url, prefix)
+	if ValidateKey(key) != nil {
+		return "", false
+	}
+	return key, true
+}